@@ -12,13 +12,16 @@ import (
 // about a registered account that
 // issues domain certificates.
 type Account struct {
-	ID           uuid.UUID
-	Token        uuid.UUID
-	Key          string
-	DirectoryURL string
-	Owners       []string
-	CreatedAt    time.Time
-	UpdatedAt    time.Time
+	ID                 uuid.UUID
+	Token              uuid.UUID
+	Key                string
+	DirectoryURL       string
+	KeyType            cryptopolis.KeyType // default key type for the account's certificates
+	Owners             []string
+	DNSProvider        string            // provider that resolves the account's dns-01 challenges, instead of the service's
+	DNSProviderOptions map[string]string // credentials and options for the account's DNS provider
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
 }
 
 // Contacts generates a list of mail contacts from the
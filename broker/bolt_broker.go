@@ -0,0 +1,326 @@
+package broker
+
+import (
+	"bytes"
+	"encoding/binary"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/pkg/errors"
+)
+
+var (
+	queueBucket      = []byte("queue")
+	deadLetterBucket = []byte("dead_letters")
+)
+
+// storeRetryDelay is how long the broker waits to read
+// the queue again after failing to read or write it.
+const storeRetryDelay = 5 * time.Second
+
+// BoltBroker implements broker.Broker using BoltDB as a backend.
+// Messages are persisted until the processor handles them successfully,
+// so they survive restarts. It's suitable for single node deployments.
+type BoltBroker struct {
+	retrier
+	codec  Codec
+	db     *bolt.DB
+	notify chan struct{}
+	exit   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewBoltBroker opens the BoltDB database in the given path
+// to persist the messages in the queue.
+func NewBoltBroker(path string) (*BoltBroker, error) {
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(queueBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(deadLetterBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, errors.Wrap(err, "unable to create the queue buckets")
+	}
+
+	return &BoltBroker{
+		codec:  JSONCodec{},
+		db:     db,
+		notify: make(chan struct{}, 1),
+		exit:   make(chan struct{}),
+	}, nil
+}
+
+// Close stops processing messages and closes the database.
+// Messages that have not been processed yet stay in the
+// database to be delivered the next time the broker starts.
+func (b *BoltBroker) Close() error {
+	close(b.exit)
+	b.wg.Wait()
+	return b.db.Close()
+}
+
+// DeadLetters returns the messages that have run out of attempts.
+// Each message's payload is a DeadLetterPayload with the original
+// message and the error history of every attempt.
+func (b *BoltBroker) DeadLetters() ([]*Message, error) {
+	var msgs []*Message
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(deadLetterBucket).ForEach(func(k, v []byte) error {
+			m, err := b.codec.Unmarshal(v)
+			if err != nil {
+				return err
+			}
+			msgs = append(msgs, m)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "error retrieving dead letters")
+	}
+
+	return msgs, nil
+}
+
+// Publish stores a message in the database for a specific job
+// and notifies the subscriber that there is work to do.
+// Messages for the dead letter topic are stored apart,
+// and they are never sent to the processor.
+func (b *BoltBroker) Publish(topic TopicType, payload interface{}) error {
+	return b.PublishAt(topic, payload, time.Time{})
+}
+
+// PublishAfter stores a message in the database for a specific job
+// that is not delivered until the delay has passed.
+func (b *BoltBroker) PublishAfter(topic TopicType, payload interface{}, delay time.Duration) error {
+	return b.PublishAt(topic, payload, time.Now().Add(delay))
+}
+
+// PublishAt stores a message in the database for a specific job
+// that is not delivered until the given time.
+// Scheduled messages survive restarts like any other message.
+func (b *BoltBroker) PublishAt(topic TopicType, payload interface{}, t time.Time) error {
+	m := NewMessage(payload)
+	m.Topic = topic
+	m.NotBefore = t
+
+	bucket := queueBucket
+	if topic == DeadLetter {
+		bucket = deadLetterBucket
+	}
+
+	if err := b.store(bucket, m); err != nil {
+		return errors.Wrapf(err, "error storing message for topic: %s", topic)
+	}
+
+	b.wakeup()
+	return nil
+}
+
+// Subscribe receives messages from the database to process them.
+// Messages left in the database by a previous run are delivered first.
+// A message is removed from the database only after the processor
+// handles it successfully. Failed messages are stored again to be
+// delivered after the backoff set in the topic's retry policy.
+func (b *BoltBroker) Subscribe(processor Processor) error {
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+
+		for {
+			wait := b.deliver(processor)
+
+			timer := time.NewTimer(wait)
+			select {
+			case <-b.notify:
+			case <-timer.C:
+			case <-b.exit:
+				timer.Stop()
+				return
+			}
+			timer.Stop()
+		}
+	}()
+
+	return nil
+}
+
+// deliver sends the messages stored in the queue to the processor.
+// It only goes through the messages stored before it started, so
+// messages stored again after failing wait until the next pass.
+// It returns how long to wait until the next scheduled message is due.
+func (b *BoltBroker) deliver(processor Processor) time.Duration {
+	wait := time.Hour
+
+	tail, err := b.tail()
+	if err != nil {
+		log.Printf("error reading the message queue: %v", err)
+		return storeRetryDelay
+	}
+
+	var last []byte
+	for {
+		key, data, err := b.next(last)
+		if err != nil {
+			log.Printf("error reading the message queue: %v", err)
+			return storeRetryDelay
+		}
+		if key == nil || bytes.Compare(key, tail) > 0 {
+			return wait
+		}
+		last = key
+
+		select {
+		case <-b.exit:
+			return wait
+		default:
+		}
+
+		m, err := b.codec.Unmarshal(data)
+		if err != nil {
+			// The message cannot be processed ever, drop it from the queue.
+			if err := b.remove(queueBucket, key); err != nil {
+				log.Printf("error removing invalid message from the queue: %v", err)
+				return storeRetryDelay
+			}
+			continue
+		}
+
+		if d := time.Until(m.NotBefore); d > 0 {
+			if d < wait {
+				wait = d
+			}
+			continue
+		}
+
+		dl, err := b.retrier.deliver(processor, m)
+		if err == nil {
+			if err := b.remove(queueBucket, key); err != nil {
+				log.Printf("error removing message %s from the queue: %v", m.JobUUID, err)
+				return storeRetryDelay
+			}
+			continue
+		}
+
+		bucket := queueBucket
+		if dl != nil {
+			bucket = deadLetterBucket
+			m = NewMessageWithID(m.JobUUID, dl)
+			m.Topic = DeadLetter
+		} else if d := time.Until(m.NotBefore); d < wait {
+			wait = d
+		}
+
+		if err := b.requeue(key, bucket, m); err != nil {
+			log.Printf("error storing message %s for its next attempt: %v", m.JobUUID, err)
+			return storeRetryDelay
+		}
+	}
+}
+
+// tail returns the key of the last message stored in the queue.
+func (b *BoltBroker) tail() ([]byte, error) {
+	var key []byte
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		k, _ := tx.Bucket(queueBucket).Cursor().Last()
+		key = append([]byte(nil), k...)
+		return nil
+	})
+
+	return key, err
+}
+
+// next returns the first message stored after the given key.
+func (b *BoltBroker) next(last []byte) ([]byte, []byte, error) {
+	var key, data []byte
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(queueBucket).Cursor()
+
+		var k, v []byte
+		if last == nil {
+			k, v = c.First()
+		} else {
+			k, v = c.Seek(last)
+			if k != nil && bytes.Equal(k, last) {
+				k, v = c.Next()
+			}
+		}
+
+		if k != nil {
+			key = append([]byte(nil), k...)
+			data = append([]byte(nil), v...)
+		}
+		return nil
+	})
+
+	return key, data, err
+}
+
+// store appends a message at the end of a bucket.
+func (b *BoltBroker) store(bucket []byte, m *Message) error {
+	data, err := b.codec.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return put(tx.Bucket(bucket), data)
+	})
+}
+
+// requeue replaces a message in the queue with its
+// new version in a single transaction, so the message
+// is never lost or duplicated.
+func (b *BoltBroker) requeue(key, bucket []byte, m *Message) error {
+	data, err := b.codec.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	return b.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(queueBucket).Delete(key); err != nil {
+			return err
+		}
+		return put(tx.Bucket(bucket), data)
+	})
+}
+
+// remove deletes a message from a bucket.
+func (b *BoltBroker) remove(bucket, key []byte) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).Delete(key)
+	})
+}
+
+func (b *BoltBroker) wakeup() {
+	select {
+	case b.notify <- struct{}{}:
+	default:
+	}
+}
+
+func put(bk *bolt.Bucket, data []byte) error {
+	seq, err := bk.NextSequence()
+	if err != nil {
+		return err
+	}
+	return bk.Put(sequenceKey(seq), data)
+}
+
+func sequenceKey(seq uint64) []byte {
+	k := make([]byte, 8)
+	binary.BigEndian.PutUint64(k, seq)
+	return k
+}
@@ -0,0 +1,270 @@
+package broker
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingProcessor struct {
+	messages chan *Message
+	err      error
+}
+
+func (p *recordingProcessor) AuthorizeDomain(m *Message) error          { return p.record(m) }
+func (p *recordingProcessor) CancelDomain(m *Message) error             { return p.record(m) }
+func (p *recordingProcessor) CleanupDomain(m *Message) error            { return p.record(m) }
+func (p *recordingProcessor) CreateDomain(m *Message) error             { return p.record(m) }
+func (p *recordingProcessor) ModifyDomain(m *Message) error             { return p.record(m) }
+func (p *recordingProcessor) RenewDomain(m *Message) error              { return p.record(m) }
+func (p *recordingProcessor) RequestDomainCertificate(m *Message) error { return p.record(m) }
+func (p *recordingProcessor) RevokeDomain(m *Message) error             { return p.record(m) }
+func (p *recordingProcessor) ValidateDomain(m *Message) error           { return p.record(m) }
+
+func (p *recordingProcessor) record(m *Message) error {
+	p.messages <- m
+	return p.err
+}
+
+func (p *recordingProcessor) receive(t *testing.T) *Message {
+	select {
+	case m := <-p.messages:
+		return m
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for message")
+	}
+	return nil
+}
+
+func newRecordingProcessor(err error) *recordingProcessor {
+	return &recordingProcessor{
+		messages: make(chan *Message, 10),
+		err:      err,
+	}
+}
+
+func pendingMessages(t *testing.T, b *BoltBroker) int {
+	var n int
+	err := b.db.View(func(tx *bolt.Tx) error {
+		n = tx.Bucket(queueBucket).Stats().KeyN
+		return nil
+	})
+	require.NoError(t, err)
+	return n
+}
+
+func TestBoltBrokerDeliversMessages(t *testing.T) {
+	dir, err := ioutil.TempDir("", "isard-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	b, err := NewBoltBroker(filepath.Join(dir, "queue.db"))
+	require.NoError(t, err)
+
+	accountID := uuid.New()
+	err = b.Publish(Creation, &CreateDomainPayload{
+		AccountID:  accountID,
+		DomainName: "test.cabal.io",
+	})
+	require.NoError(t, err)
+
+	p := newRecordingProcessor(nil)
+	require.NoError(t, b.Subscribe(p))
+
+	m := p.receive(t)
+	require.Equal(t, Creation, m.Topic)
+	c, ok := m.Payload.(*CreateDomainPayload)
+	require.True(t, ok, "expected creation payload: %v", m.Payload)
+	require.Equal(t, accountID, c.AccountID)
+	require.Equal(t, "test.cabal.io", c.DomainName)
+
+	err = b.Publish(Validation, &DomainPayload{
+		AccountID:  accountID,
+		DomainName: "test.cabal.io",
+	})
+	require.NoError(t, err)
+
+	m = p.receive(t)
+	require.Equal(t, Validation, m.Topic)
+	v, ok := m.Payload.(*DomainPayload)
+	require.True(t, ok, "expected domain payload: %v", m.Payload)
+	require.Equal(t, "test.cabal.io", v.DomainName)
+
+	require.NoError(t, b.Close())
+	b, err = NewBoltBroker(filepath.Join(dir, "queue.db"))
+	require.NoError(t, err)
+	require.Equal(t, 0, pendingMessages(t, b))
+	require.NoError(t, b.Close())
+}
+
+func TestBoltBrokerRedeliversUnacknowledgedMessages(t *testing.T) {
+	dir, err := ioutil.TempDir("", "isard-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	b, err := NewBoltBroker(filepath.Join(dir, "queue.db"))
+	require.NoError(t, err)
+	b.SetRetryPolicy(Authorization, &RetryPolicy{InitialBackoff: 50 * time.Millisecond})
+
+	failing := newRecordingProcessor(errors.New("CA unavailable"))
+	require.NoError(t, b.Subscribe(failing))
+
+	err = b.Publish(Authorization, &DomainPayload{
+		AccountID:  uuid.New(),
+		DomainName: "test.cabal.io",
+	})
+	require.NoError(t, err)
+
+	first := failing.receive(t)
+	require.NoError(t, b.Close())
+
+	b, err = NewBoltBroker(filepath.Join(dir, "queue.db"))
+	require.NoError(t, err)
+	defer b.Close()
+	require.Equal(t, 1, pendingMessages(t, b))
+
+	p := newRecordingProcessor(nil)
+	require.NoError(t, b.Subscribe(p))
+
+	m := p.receive(t)
+	require.Equal(t, first.JobUUID, m.JobUUID)
+	require.Equal(t, Authorization, m.Topic)
+	require.Equal(t, "test.cabal.io", m.Payload.(*DomainPayload).DomainName)
+	require.Equal(t, 2, m.Attempts)
+	require.Equal(t, "CA unavailable", m.LastError())
+}
+
+func TestBoltBrokerDeadLetters(t *testing.T) {
+	dir, err := ioutil.TempDir("", "isard-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	b, err := NewBoltBroker(filepath.Join(dir, "queue.db"))
+	require.NoError(t, err)
+	defer b.Close()
+
+	b.SetRetryPolicy(Authorization, &RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 10 * time.Millisecond,
+		Multiplier:     2,
+	})
+
+	p := newRecordingProcessor(errors.New("CA unavailable"))
+	require.NoError(t, b.Subscribe(p))
+
+	err = b.Publish(Authorization, &DomainPayload{
+		AccountID:  uuid.New(),
+		DomainName: "test.cabal.io",
+	})
+	require.NoError(t, err)
+
+	for i := 1; i <= 3; i++ {
+		m := p.receive(t)
+		require.Equal(t, i, m.Attempts)
+	}
+
+	var dls []*Message
+	require.Eventually(t, func() bool {
+		dls, err = b.DeadLetters()
+		return err == nil && len(dls) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, 0, pendingMessages(t, b))
+
+	dl, ok := dls[0].Payload.(*DeadLetterPayload)
+	require.True(t, ok, "expected dead letter payload: %v", dls[0].Payload)
+	require.Equal(t, "CA unavailable", dl.Error)
+	require.Equal(t, Authorization, dl.Message.Topic)
+	require.Equal(t, 3, dl.Message.Attempts)
+	require.Len(t, dl.Message.Errors, 3)
+	require.Equal(t, "test.cabal.io", dl.Message.Payload.(*DomainPayload).DomainName)
+}
+
+func TestBoltBrokerPermanentErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "isard-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	b, err := NewBoltBroker(filepath.Join(dir, "queue.db"))
+	require.NoError(t, err)
+	defer b.Close()
+
+	p := newRecordingProcessor(Permanent(errors.New("unsupported ACME challenge: tls-sni-01")))
+	require.NoError(t, b.Subscribe(p))
+
+	err = b.Publish(Creation, &CreateDomainPayload{
+		AccountID:  uuid.New(),
+		DomainName: "test.cabal.io",
+	})
+	require.NoError(t, err)
+
+	m := p.receive(t)
+	require.Equal(t, 1, m.Attempts)
+
+	var dls []*Message
+	require.Eventually(t, func() bool {
+		dls, err = b.DeadLetters()
+		return err == nil && len(dls) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, 0, pendingMessages(t, b))
+
+	dl := dls[0].Payload.(*DeadLetterPayload)
+	require.Equal(t, "unsupported ACME challenge: tls-sni-01", dl.Error)
+	require.Equal(t, 1, dl.Message.Attempts)
+}
+
+func TestBoltBrokerRetriesInNextPass(t *testing.T) {
+	dir, err := ioutil.TempDir("", "isard-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	b, err := NewBoltBroker(filepath.Join(dir, "queue.db"))
+	require.NoError(t, err)
+	defer b.Close()
+
+	b.SetRetryPolicy(Authorization, &RetryPolicy{InitialBackoff: time.Hour})
+
+	err = b.Publish(Authorization, &DomainPayload{
+		AccountID:  uuid.New(),
+		DomainName: "test.cabal.io",
+	})
+	require.NoError(t, err)
+
+	// A failed message stored again is not delivered twice in the same pass.
+	p := newRecordingProcessor(errors.New("CA unavailable"))
+	wait := b.deliver(p)
+	require.Len(t, p.messages, 1)
+	require.True(t, wait > 50*time.Minute, "unexpected wait: %s", wait)
+	require.Equal(t, 1, pendingMessages(t, b))
+}
+
+func TestBoltBrokerPublishAfter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "isard-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	b, err := NewBoltBroker(filepath.Join(dir, "queue.db"))
+	require.NoError(t, err)
+	defer b.Close()
+
+	p := newRecordingProcessor(nil)
+	require.NoError(t, b.Subscribe(p))
+
+	start := time.Now()
+	err = b.PublishAfter(Authorization, &DomainPayload{
+		AccountID:  uuid.New(),
+		DomainName: "test.cabal.io",
+		Polls:      1,
+	}, 200*time.Millisecond)
+	require.NoError(t, err)
+
+	m := p.receive(t)
+	require.True(t, time.Since(start) >= 200*time.Millisecond, "message delivered before its delay")
+	require.Equal(t, 1, m.Payload.(*DomainPayload).Polls)
+}
@@ -1,5 +1,7 @@
 package broker
 
+import "time"
+
 // TopicType defines the operations
 // the broker knows about.
 // They are used as a state machine
@@ -17,6 +19,17 @@ const (
 	Authorization TopicType = "authorization"
 	// CertRequest is the topic to request domain certificates after they have been authorized.
 	CertRequest TopicType = "cert_request"
+	// Renewal is the topic to renew certificates before they expire.
+	Renewal TopicType = "renewal"
+	// Cancellation is the topic to cancel domains and abort their certificate issuance.
+	Cancellation TopicType = "cancellation"
+	// Revocation is the topic to revoke issued certificates.
+	Revocation TopicType = "revocation"
+	// Cleanup is the topic to remove the resources created to resolve challenges.
+	Cleanup TopicType = "cleanup"
+	// DeadLetter is the topic that keeps messages that have run out of attempts.
+	// Messages in this topic are never sent to the processor.
+	DeadLetter TopicType = "dead_letter"
 )
 
 var allTopics = []TopicType{
@@ -25,6 +38,11 @@ var allTopics = []TopicType{
 	Validation,
 	Authorization,
 	CertRequest,
+	Renewal,
+	Cancellation,
+	Revocation,
+	Cleanup,
+	DeadLetter,
 }
 
 // Broker defines an interface to publish
@@ -32,5 +50,8 @@ var allTopics = []TopicType{
 type Broker interface {
 	Close() error
 	Publish(topic TopicType, payload interface{}) error
+	PublishAfter(topic TopicType, payload interface{}, delay time.Duration) error
+	PublishAt(topic TopicType, payload interface{}, t time.Time) error
+	SetRetryPolicy(topic TopicType, policy *RetryPolicy)
 	Subscribe(processor Processor) error
 }
@@ -1,60 +1,185 @@
 package broker
 
-// ChannelBroker implements broker.Broker using channels as a backend.
+import (
+	"sync"
+	"time"
+)
+
+// ChannelBroker implements broker.Broker using an in memory queue,
+// and a channel to notify the subscriber that there is work to do.
+// Publishing never blocks, so processors can publish the next
+// step of a domain's lifecycle while they handle a message.
 // This interface is only suitable for testing.
 // It offers no guarantees about the elements pushed and pulled from the queue.
 type ChannelBroker struct {
-	c map[TopicType]chan *Message
-	e chan struct{}
+	retrier
+	notify chan struct{}
+	exit   chan struct{}
+
+	mu          sync.Mutex
+	queue       []*Message
+	timers      map[*time.Timer]struct{}
+	deadLetters []*Message
+	closed      bool
 }
 
 // NewChannelBroker initializes the channel broker.
 func NewChannelBroker() *ChannelBroker {
-	c := map[TopicType]chan *Message{}
-	for _, t := range allTopics {
-		c[t] = make(chan *Message)
-	}
-
 	return &ChannelBroker{
-		c: c,
-		e: make(chan struct{}),
+		notify: make(chan struct{}, 1),
+		exit:   make(chan struct{}),
+		timers: map[*time.Timer]struct{}{},
 	}
 }
 
-// Close sends a message to the exit channel
-// to stop processing messages.
+// Close stops processing messages and
+// discards the messages scheduled for later.
 func (b *ChannelBroker) Close() error {
-	b.e <- struct{}{}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+
+	for t := range b.timers {
+		t.Stop()
+	}
+	b.timers = nil
+	b.queue = nil
+
+	close(b.exit)
 	return nil
 }
 
-// Publish sends messages to the channel for a specific job.
+// DeadLetters returns the messages that have run out of attempts.
+func (b *ChannelBroker) DeadLetters() []*Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]*Message(nil), b.deadLetters...)
+}
+
+// Publish sends messages to the queue for a specific job.
 func (b *ChannelBroker) Publish(topic TopicType, payload interface{}) error {
 	m := NewMessage(payload)
-	b.c[topic] <- m
+	m.Topic = topic
+
+	b.enqueue(m)
+	return nil
+}
+
+// PublishAfter sends messages to the queue for a specific job
+// once the delay has passed.
+func (b *ChannelBroker) PublishAfter(topic TopicType, payload interface{}, delay time.Duration) error {
+	m := NewMessage(payload)
+	m.Topic = topic
+	m.NotBefore = time.Now().Add(delay)
+
+	b.schedule(m)
 	return nil
 }
 
-// Subscribe receives messages from the channel to process them.
+// PublishAt sends messages to the queue for a specific job
+// at the given time.
+func (b *ChannelBroker) PublishAt(topic TopicType, payload interface{}, t time.Time) error {
+	return b.PublishAfter(topic, payload, time.Until(t))
+}
+
+// Subscribe receives messages from the queue to process them.
+// Failed messages are sent to the queue again after the backoff
+// set in the topic's retry policy.
 func (b *ChannelBroker) Subscribe(processor Processor) error {
 	go func() {
 		for {
 			select {
-			case msg := <-b.c[Creation]:
-				processor.CreateDomain(msg)
-			case msg := <-b.c[Modification]:
-				processor.ModifyDomain(msg)
-			case msg := <-b.c[Validation]:
-				processor.ValidateDomain(msg)
-			case msg := <-b.c[Authorization]:
-				processor.AuthorizeDomain(msg)
-			case msg := <-b.c[CertRequest]:
-				processor.RequestDomainCertificate(msg)
-			case <-b.e:
-				break
+			case <-b.notify:
+			case <-b.exit:
+				return
+			}
+
+			for {
+				m := b.dequeue()
+				if m == nil {
+					break
+				}
+				b.deliver(processor, m)
 			}
 		}
 	}()
 
 	return nil
 }
+
+func (b *ChannelBroker) deliver(processor Processor, m *Message) {
+	dl, err := b.retrier.deliver(processor, m)
+	if err == nil {
+		return
+	}
+
+	if dl != nil {
+		b.Publish(DeadLetter, dl)
+		return
+	}
+
+	b.schedule(m)
+}
+
+// enqueue adds a message at the end of the queue.
+// Messages for the dead letter topic are kept apart,
+// and they are never sent to the processor.
+func (b *ChannelBroker) enqueue(m *Message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if m.Topic == DeadLetter {
+		b.deadLetters = append(b.deadLetters, m)
+		return
+	}
+
+	if b.closed {
+		return
+	}
+	b.queue = append(b.queue, m)
+
+	select {
+	case b.notify <- struct{}{}:
+	default:
+	}
+}
+
+// dequeue removes the first message in the queue,
+// or returns nil when the queue is empty or the broker is closed.
+func (b *ChannelBroker) dequeue() *Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed || len(b.queue) == 0 {
+		return nil
+	}
+
+	m := b.queue[0]
+	b.queue = b.queue[1:]
+	return m
+}
+
+// schedule adds a message to the queue when it's due.
+func (b *ChannelBroker) schedule(m *Message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+
+	var t *time.Timer
+	t = time.AfterFunc(time.Until(m.NotBefore), func() {
+		b.mu.Lock()
+		delete(b.timers, t)
+		b.mu.Unlock()
+
+		b.enqueue(m)
+	})
+	b.timers[t] = struct{}{}
+}
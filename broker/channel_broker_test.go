@@ -0,0 +1,84 @@
+package broker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// chainProcessor publishes the next step of the lifecycle
+// from inside the subscriber, like the domain processor does.
+type chainProcessor struct {
+	recordingProcessor
+	broker Broker
+}
+
+func (p *chainProcessor) CreateDomain(m *Message) error {
+	if err := p.broker.Publish(Validation, &DomainPayload{DomainName: "test.cabal.io"}); err != nil {
+		return err
+	}
+	return p.record(m)
+}
+
+func (p *chainProcessor) ValidateDomain(m *Message) error {
+	if err := p.broker.PublishAfter(Authorization, &DomainPayload{DomainName: "test.cabal.io"}, 10*time.Millisecond); err != nil {
+		return err
+	}
+	return p.record(m)
+}
+
+func TestChannelBrokerPublishFromProcessor(t *testing.T) {
+	b := NewChannelBroker()
+	defer b.Close()
+
+	p := &chainProcessor{recordingProcessor: *newRecordingProcessor(nil), broker: b}
+	require.NoError(t, b.Subscribe(p))
+
+	err := b.Publish(Creation, &CreateDomainPayload{AccountID: uuid.New(), DomainName: "test.cabal.io"})
+	require.NoError(t, err)
+
+	require.Equal(t, Creation, p.receive(t).Topic)
+	require.Equal(t, Validation, p.receive(t).Topic)
+	require.Equal(t, Authorization, p.receive(t).Topic)
+}
+
+func TestChannelBrokerDeadLetters(t *testing.T) {
+	b := NewChannelBroker()
+	defer b.Close()
+
+	b.SetRetryPolicy(Authorization, &RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond})
+
+	p := newRecordingProcessor(errors.New("CA unavailable"))
+	require.NoError(t, b.Subscribe(p))
+
+	require.NoError(t, b.Publish(Authorization, &DomainPayload{DomainName: "test.cabal.io"}))
+	p.receive(t)
+	p.receive(t)
+
+	require.Eventually(t, func() bool {
+		return len(b.DeadLetters()) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestChannelBrokerCloseStopsTimers(t *testing.T) {
+	b := NewChannelBroker()
+
+	p := newRecordingProcessor(nil)
+	require.NoError(t, b.Subscribe(p))
+
+	require.NoError(t, b.PublishAfter(Authorization, &DomainPayload{DomainName: "test.cabal.io"}, 50*time.Millisecond))
+	require.Len(t, b.timers, 1)
+
+	require.NoError(t, b.Close())
+	require.NoError(t, b.Close())
+	require.Nil(t, b.timers)
+
+	select {
+	case m := <-p.messages:
+		t.Fatalf("message delivered after closing the broker: %v", m.Topic)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
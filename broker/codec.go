@@ -0,0 +1,75 @@
+package broker
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Codec defines an interface to serialize messages
+// for brokers that store them or send them over the network.
+type Codec interface {
+	Marshal(m *Message) ([]byte, error)
+	Unmarshal(data []byte) (*Message, error)
+}
+
+// JSONCodec implements broker.Codec using JSON as the wire format.
+// Payloads are decoded into the type registered for the message's topic.
+type JSONCodec struct{}
+
+// Marshal encodes a message in JSON format.
+func (JSONCodec) Marshal(m *Message) ([]byte, error) {
+	d, err := json.Marshal(m)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error encoding message for topic: %s", m.Topic)
+	}
+	return d, nil
+}
+
+// Unmarshal decodes a message in JSON format.
+func (JSONCodec) Unmarshal(data []byte) (*Message, error) {
+	var m Message
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, errors.Wrap(err, "error decoding message")
+	}
+	return &m, nil
+}
+
+var (
+	payloadsMu sync.RWMutex
+	payloads   = map[TopicType]func() interface{}{
+		Creation:      func() interface{} { return &CreateDomainPayload{} },
+		Modification:  func() interface{} { return &ModifyDomainPayload{} },
+		Validation:    func() interface{} { return &DomainPayload{} },
+		Authorization: func() interface{} { return &DomainPayload{} },
+		CertRequest:   func() interface{} { return &DomainPayload{} },
+		Renewal:       func() interface{} { return &RenewDomainPayload{} },
+		Cancellation:  func() interface{} { return &DomainPayload{} },
+		Revocation:    func() interface{} { return &RevokeDomainPayload{} },
+		Cleanup:       func() interface{} { return &DomainPayload{} },
+		DeadLetter:    func() interface{} { return &DeadLetterPayload{} },
+	}
+)
+
+// RegisterPayload sets the function that initializes
+// the payload carried by the messages of a topic.
+// Codecs use it to decode payloads into their original types.
+func RegisterPayload(topic TopicType, newPayload func() interface{}) {
+	payloadsMu.Lock()
+	payloads[topic] = newPayload
+	payloadsMu.Unlock()
+}
+
+// NewPayload initializes an empty payload
+// of the type registered for a topic.
+func NewPayload(topic TopicType) (interface{}, error) {
+	payloadsMu.RLock()
+	f, ok := payloads[topic]
+	payloadsMu.RUnlock()
+
+	if !ok {
+		return nil, errors.Errorf("there is no payload registered for topic: %s", topic)
+	}
+	return f(), nil
+}
@@ -0,0 +1,116 @@
+package broker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONCodecRoundTrip(t *testing.T) {
+	accountID := uuid.New()
+
+	payloads := map[TopicType]interface{}{
+		Creation: &CreateDomainPayload{
+			AccountID:     accountID,
+			AccountToken:  uuid.New(),
+			DomainName:    "test.cabal.io",
+			ChallengeType: "dns-01",
+			KeyType:       "rsa2048",
+			ReuseKey:      true,
+		},
+		Modification: &ModifyDomainPayload{
+			AccountID:   accountID,
+			DomainName:  "test.cabal.io",
+			AddNames:    []string{"beta.cabal.io"},
+			RemoveNames: []string{"alpha.cabal.io"},
+		},
+		Validation: &DomainPayload{
+			AccountID:  accountID,
+			DomainName: "test.cabal.io",
+		},
+		Authorization: &DomainPayload{
+			AccountID:  accountID,
+			DomainName: "test.cabal.io",
+		},
+		CertRequest: &DomainPayload{
+			AccountID:  accountID,
+			DomainName: "test.cabal.io",
+		},
+		Renewal: &RenewDomainPayload{
+			AccountID:  accountID,
+			DomainName: "test.cabal.io",
+			NotAfter:   time.Now().UTC().Add(24 * time.Hour),
+		},
+		Cancellation: &DomainPayload{
+			AccountID:  accountID,
+			DomainName: "test.cabal.io",
+		},
+		Revocation: &RevokeDomainPayload{
+			AccountID:         accountID,
+			DomainName:        "test.cabal.io",
+			Reason:            1,
+			UseCertificateKey: true,
+		},
+		Cleanup: &DomainPayload{
+			AccountID:  accountID,
+			DomainName: "test.cabal.io",
+		},
+		DeadLetter: &DeadLetterPayload{
+			Message: &Message{
+				JobUUID: uuid.New(),
+				Topic:   Authorization,
+				Payload: &DomainPayload{
+					AccountID:  accountID,
+					DomainName: "test.cabal.io",
+				},
+				Attempts: 1,
+				Errors: []MessageError{
+					{Attempt: 1, Error: "CA unavailable", Time: time.Now().UTC()},
+				},
+			},
+			Error: "CA unavailable",
+		},
+	}
+
+	codec := JSONCodec{}
+	for _, topic := range allTopics {
+		payload, ok := payloads[topic]
+		require.True(t, ok, "missing payload for topic: %s", topic)
+
+		m := NewMessage(payload)
+		m.Topic = topic
+
+		data, err := codec.Marshal(m)
+		require.NoError(t, err)
+
+		got, err := codec.Unmarshal(data)
+		require.NoError(t, err)
+		require.Equal(t, m, got, "invalid round trip for topic: %s", topic)
+	}
+}
+
+func TestJSONCodecUnknownTopic(t *testing.T) {
+	_, err := JSONCodec{}.Unmarshal([]byte(`{"Topic": "unknown", "Payload": {}}`))
+	require.EqualError(t, err, "error decoding message: there is no payload registered for topic: unknown")
+}
+
+func TestRegisterPayload(t *testing.T) {
+	type customPayload struct {
+		Value string
+	}
+
+	topic := TopicType("custom")
+	RegisterPayload(topic, func() interface{} { return &customPayload{} })
+
+	m := NewMessage(&customPayload{Value: "isard"})
+	m.Topic = topic
+
+	data, err := JSONCodec{}.Marshal(m)
+	require.NoError(t, err)
+
+	got, err := JSONCodec{}.Unmarshal(data)
+	require.NoError(t, err)
+	require.Equal(t, &customPayload{Value: "isard"}, got.Payload)
+}
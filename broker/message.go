@@ -1,12 +1,76 @@
 package broker
 
-import "github.com/google/uuid"
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
 
 // Message is the structure that the broker sends and receives.
 type Message struct {
-	JobUUID uuid.UUID // unique identifiler for the job that trigerred this message
-	Topic   TopicType
-	Payload interface{}
+	JobUUID   uuid.UUID // unique identifiler for the job that trigerred this message
+	Topic     TopicType
+	Payload   interface{}
+	Attempts  int            // number of times the message has been delivered
+	Errors    []MessageError // errors returned by the processor in failed attempts
+	NotBefore time.Time      // the message is not delivered before this time
+}
+
+// MessageError records why the processor
+// failed to handle a message.
+type MessageError struct {
+	Attempt int
+	Error   string
+	Time    time.Time
+}
+
+// LastError returns the last error returned by the processor,
+// or an empty string if the message has never failed.
+func (m *Message) LastError() string {
+	if len(m.Errors) == 0 {
+		return ""
+	}
+	return m.Errors[len(m.Errors)-1].Error
+}
+
+// UnmarshalJSON decodes a message and its payload.
+// The payload is decoded into the type registered for the message's topic.
+func (m *Message) UnmarshalJSON(data []byte) error {
+	type message Message
+	aux := struct {
+		*message
+		Payload json.RawMessage
+	}{
+		message: (*message)(m),
+	}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	payload, err := NewPayload(m.Topic)
+	if err != nil {
+		return err
+	}
+
+	if len(aux.Payload) > 0 {
+		if err := json.Unmarshal(aux.Payload, payload); err != nil {
+			return errors.Wrapf(err, "error decoding payload for topic: %s", m.Topic)
+		}
+	}
+
+	m.Payload = payload
+	return nil
+}
+
+func (m *Message) recordError(err error) {
+	m.Errors = append(m.Errors, MessageError{
+		Attempt: m.Attempts,
+		Error:   err.Error(),
+		Time:    time.Now().UTC(),
+	})
 }
 
 // NewMessage creates new messages with default ids.
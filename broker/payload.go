@@ -1,15 +1,24 @@
 package broker
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 // CreateDomainPayload is the payload
 // sent by a client to create a new
 // domain.
+// The challenge types are tried in order,
+// starting with the challenge type.
 type CreateDomainPayload struct {
-	AccountID     uuid.UUID `json:"account_id"`
-	AccountToken  uuid.UUID `json:"account_token"`
-	DomainName    string    `json:"domain_name"`
-	ChallengeType string    `json:"challenge_type"`
+	AccountID      uuid.UUID `json:"account_id"`
+	AccountToken   uuid.UUID `json:"account_token"`
+	DomainName     string    `json:"domain_name"`
+	ChallengeType  string    `json:"challenge_type"`
+	ChallengeTypes []string  `json:"challenge_types,omitempty"`
+	KeyType        string    `json:"key_type,omitempty"`
+	ReuseKey       bool      `json:"reuse_key,omitempty"`
 }
 
 // DomainPayload is the payload
@@ -18,4 +27,48 @@ type CreateDomainPayload struct {
 type DomainPayload struct {
 	AccountID  uuid.UUID `json:"account_id"`
 	DomainName string    `json:"domain_name"`
+	Polls      int       `json:"polls,omitempty"`
+}
+
+// ModifyDomainPayload is the payload
+// sent by a client to add and remove
+// names from a domain's certificate.
+type ModifyDomainPayload struct {
+	AccountID   uuid.UUID `json:"account_id"`
+	DomainName  string    `json:"domain_name"`
+	AddNames    []string  `json:"add_names,omitempty"`
+	RemoveNames []string  `json:"remove_names,omitempty"`
+}
+
+// RenewDomainPayload is the payload
+// sent to renew a domain's certificate.
+// NotAfter identifies the certificate to renew,
+// so the certificate is not renewed twice.
+// ExplanationURL is the page where the CA explains
+// why it suggested the renewal, if any.
+type RenewDomainPayload struct {
+	AccountID      uuid.UUID `json:"account_id"`
+	DomainName     string    `json:"domain_name"`
+	NotAfter       time.Time `json:"not_after"`
+	ExplanationURL string    `json:"explanation_url,omitempty"`
+}
+
+// RevokeDomainPayload is the payload
+// sent by a client to revoke a domain's certificate.
+// Reason is one of the CRL reason codes in RFC 5280.
+// UseCertificateKey signs the revocation with the
+// certificate's key instead of the account key.
+type RevokeDomainPayload struct {
+	AccountID         uuid.UUID `json:"account_id"`
+	DomainName        string    `json:"domain_name"`
+	Reason            int       `json:"reason"`
+	UseCertificateKey bool      `json:"use_certificate_key,omitempty"`
+}
+
+// DeadLetterPayload is the payload
+// sent to the dead letter topic when
+// a message runs out of attempts.
+type DeadLetterPayload struct {
+	Message *Message `json:"message"`
+	Error   string   `json:"error"`
 }
@@ -1,8 +1,14 @@
 package broker
 
 import (
+	"strings"
+	"time"
+
+	"github.com/lost-mountain/isard/account"
 	"github.com/lost-mountain/isard/certificates"
+	"github.com/lost-mountain/isard/certificates/challenges"
 	"github.com/lost-mountain/isard/configuration"
+	"github.com/lost-mountain/isard/cryptopolis"
 	"github.com/lost-mountain/isard/domain"
 	"github.com/lost-mountain/isard/domain/validator"
 	"github.com/lost-mountain/isard/storage"
@@ -10,33 +16,114 @@ import (
 	"golang.org/x/crypto/acme"
 )
 
+const (
+	// authzPollInitialDelay is the delay before checking an authorization state the first time.
+	authzPollInitialDelay = 2 * time.Second
+	// authzPollMaxDelay is the longest delay between authorization state checks.
+	authzPollMaxDelay = 2 * time.Minute
+	// defaultChallengeTimeout is how long challenges have to be ready
+	// for the CA when the configuration doesn't set a timeout.
+	defaultChallengeTimeout = 5 * time.Minute
+)
+
 // Processor defines an interface to process messages
 // publised by the Broker.
 type Processor interface {
 	AuthorizeDomain(*Message) error
+	CancelDomain(*Message) error
+	CleanupDomain(*Message) error
 	CreateDomain(*Message) error
 	ModifyDomain(*Message) error
+	RenewDomain(*Message) error
 	RequestDomainCertificate(*Message) error
+	RevokeDomain(*Message) error
 	ValidateDomain(*Message) error
 }
 
+// certificateClient defines the operations the processor
+// negotiates with the CA. certificates.Client implements it.
+type certificateClient interface {
+	AcceptChallenge(d *domain.Domain, chal *acme.Challenge) (*acme.Challenge, error)
+	AuthorizeDomain(d *domain.Domain) (*acme.Order, error)
+	CleanupChallenge(a *domain.Authorization) error
+	DeactivateAuthorization(url string) error
+	GetAuthorization(url string) (*acme.Authorization, error)
+	GetOrder(d *domain.Domain) (*acme.Order, error)
+	OrderRetryAfter(d *domain.Domain) time.Duration
+	PrepareChallenge(a *domain.Authorization, chal *acme.Challenge) error
+	RequestCertificate(d *domain.Domain) (*cryptopolis.Certificate, error)
+	RevokeCertificate(d *domain.Domain, reason acme.CRLReasonCode, useCertificateKey bool) error
+	SupportsChallenge(challengeType string) error
+}
+
+// revocationReasons are the CRL reason codes that
+// clients can use to revoke a certificate.
+var revocationReasons = map[acme.CRLReasonCode]string{
+	acme.CRLReasonUnspecified:          "unspecified",
+	acme.CRLReasonKeyCompromise:        "key compromise",
+	acme.CRLReasonSuperseded:           "superseded",
+	acme.CRLReasonCessationOfOperation: "cessation of operation",
+}
+
 // DomainProcessor controls the lifecycle of a domain.
 // It moves the domain across the state machine
 // accordingly to the previous operation and its result.
+// Each step publishes the message for the next one:
+// Pending -> Validating -> Verified -> Provisioning ->
+// Authorized -> Requesting -> Issued.
+// Domains can be cancelled at any step, the processor
+// stops moving them forward once they are cancelling.
 type DomainProcessor struct {
-	bucket storage.Bucket
-	broker Broker
-	config *configuration.DomainsConfiguration
+	bucket             storage.Bucket
+	broker             Broker
+	config             *configuration.DomainsConfiguration
+	newClient          func(*account.Account) (certificateClient, error)
+	propagated         func(fqdn, value string) (bool, error)
+	checkHTTPChallenge func(hostname, path, expected string) error
 }
 
-// AuthorizeDomain sends an authorization request to the CA.
+// AuthorizeDomain sends an order to the CA and
+// waits until all its authorizations are valid.
 // If the job succeeds, it moves the domain to the
 // certificate request state. Otherwise, it leaves to the broker
 // to decide what to do with the message.
+// Domains that are already authorized are ignored.
 func (p *DomainProcessor) AuthorizeDomain(m *Message) error {
 	v, ok := m.Payload.(*DomainPayload)
 	if !ok {
-		return errors.Errorf("error authorizing domain, invalid payload message: %v", m.Payload)
+		return Permanent(errors.Errorf("error authorizing domain, invalid payload message: %v", m.Payload))
+	}
+
+	d, err := p.bucket.GetDomain(v.AccountID, v.DomainName)
+	if err != nil {
+		return err
+	}
+
+	if !handles(d, domain.Verified, domain.Provisioning, domain.Invalid) {
+		return nil
+	}
+
+	c, err := p.newClient(d.Account)
+	if err != nil {
+		return err
+	}
+
+	if d.OrderURL != "" {
+		return p.checkOrderState(c, d, m, v.Polls)
+	}
+	return p.startAuthProcess(c, d, m)
+}
+
+// CancelDomain aborts the certificate issuance for a domain.
+// It moves the domain to the cancelling state, so the processor
+// stops moving it forward, deactivates its pending authorizations
+// in the CA and cleans up its challenge. If the job succeeds,
+// it moves the domain to the cancelled state. Otherwise, it leaves
+// to the broker to decide what to do with the message.
+func (p *DomainProcessor) CancelDomain(m *Message) error {
+	v, ok := m.Payload.(*DomainPayload)
+	if !ok {
+		return Permanent(errors.Errorf("error cancelling domain, invalid payload message: %v", m.Payload))
 	}
 
 	d, err := p.bucket.GetDomain(v.AccountID, v.DomainName)
@@ -44,25 +131,93 @@ func (p *DomainProcessor) AuthorizeDomain(m *Message) error {
 		return err
 	}
 
-	c, err := certificates.NewClientWithAPIKey(d.Account, p.config.Ns1APIKey)
+	if d.State == domain.Cancelled {
+		return nil
+	}
+
+	if err := p.transition(d, m, domain.Cancelling, "cancellation requested", nil); err != nil {
+		return err
+	}
+
+	c, err := p.newClient(d.Account)
 	if err != nil {
 		return err
 	}
 
-	if d.AuthorizationURL != "" {
-		return p.checkAuthzState(c, d)
+	if d.OrderURL != "" {
+		o, err := c.GetOrder(d)
+		if err != nil {
+			return err
+		}
+
+		for _, u := range o.AuthzURLs {
+			authz, err := c.GetAuthorization(u)
+			if err != nil {
+				return err
+			}
+
+			status := authz.Status
+			if status == acme.StatusPending {
+				if err := c.DeactivateAuthorization(u); err != nil {
+					return err
+				}
+				status = acme.StatusDeactivated
+			}
+
+			if a := d.Authorization(authorizationName(authz)); a != nil && a.URL == u {
+				a.Status = status
+			}
+		}
 	}
-	return p.startAuthProcess(c, d)
+
+	if err := p.cleanupChallenges(c, d, true); err != nil {
+		return err
+	}
+
+	d.OrderURL = ""
+	return p.transition(d, m, domain.Cancelled, "domain cancelled", nil)
+}
+
+// CleanupDomain removes the resources created to resolve the
+// challenges of the authorizations that the CA has finished with.
+// If the job fails, the broker retries it, so the resources
+// are removed eventually.
+// The job runs concurrently with the rest of the domain's steps,
+// so it only records which challenges were cleaned up.
+func (p *DomainProcessor) CleanupDomain(m *Message) error {
+	v, ok := m.Payload.(*DomainPayload)
+	if !ok {
+		return Permanent(errors.Errorf("error cleaning up domain, invalid payload message: %v", m.Payload))
+	}
+
+	d, err := p.bucket.GetDomain(v.AccountID, v.DomainName)
+	if err != nil {
+		return err
+	}
+
+	c, err := p.newClient(d.Account)
+	if err != nil {
+		return err
+	}
+
+	cleaned, cerr := p.removeChallenges(c, d, false)
+	if len(cleaned) > 0 {
+		if err := p.markCleanedUp(d, cleaned); err != nil {
+			return err
+		}
+	}
+	return cerr
 }
 
 // CreateDomain creates a new domain.
 // If the job succeeds, it moves the domain to the
 // validation state. Otherwise, it leaves to the broker
 // to decide what to do with the message.
+// Invalid names, challenge types and key types are permanent errors.
 func (p *DomainProcessor) CreateDomain(m *Message) error {
 	v, ok := m.Payload.(*CreateDomainPayload)
 	if !ok {
-		return errors.Errorf("error creating domain, invalid payload message: %v", m.Payload)
+		return Permanent(errors.Errorf("error creating domain, invalid payload message: %v", m.Payload))
 	}
 
 	a, err := p.bucket.GetAccount(v.AccountID, v.AccountToken)
@@ -70,28 +225,123 @@ func (p *DomainProcessor) CreateDomain(m *Message) error {
 		return err
 	}
 
-	d, err := domain.NewDomainWithChallengeType(a, v.DomainName, v.ChallengeType)
+	types := append([]string{v.ChallengeType}, v.ChallengeTypes...)
+	d, err := domain.NewDomainWithChallengeTypes(a, v.DomainName, types)
 	if err != nil {
+		return Permanent(err)
+	}
+
+	d.KeyType, err = cryptopolis.ParseKeyType(v.KeyType)
+	if err != nil {
+		return Permanent(err)
+	}
+	d.ReuseKey = v.ReuseKey
+
+	if err := p.transition(d, m, domain.Pending, "domain created", nil); err != nil {
 		return err
 	}
 
-	return p.bucket.SaveDomain(d)
+	return p.broker.Publish(Validation, newDomainPayload(d))
 }
 
-// ModifyDomain modifies a domain.
-// If the job succeeds, it moves the domain to the
-// validation state. Otherwise, it leaves to the broker
-// to decide what to do with the message.
-func (p *DomainProcessor) ModifyDomain(*Message) error { return nil }
+// ModifyDomain adds and removes names from an issued domain.
+// If the job succeeds, it moves the domain to the provisioning state
+// to order a new certificate. The CA reuses the authorizations
+// that are still valid, so only the names added need new challenges.
+// The issued certificate is kept until the new one replaces it.
+// Otherwise, it leaves to the broker to decide what to do with the message.
+func (p *DomainProcessor) ModifyDomain(m *Message) error {
+	v, ok := m.Payload.(*ModifyDomainPayload)
+	if !ok {
+		return Permanent(errors.Errorf("error modifying domain, invalid payload message: %v", m.Payload))
+	}
+
+	d, err := p.bucket.GetDomain(v.AccountID, v.DomainName)
+	if err != nil {
+		return err
+	}
+
+	if cancelled(d) {
+		return nil
+	}
+
+	// Retrying the message doesn't change the domain's state.
+	if d.State != domain.Issued {
+		return Permanent(errors.Errorf("unable to modify domain %s in state: %s", d.Name, d.State))
+	}
+
+	var changed bool
+	for _, n := range v.AddNames {
+		err := d.AddSANName(n)
+		if err == domain.ErrDuplicatedSANName {
+			continue
+		}
+		if err != nil {
+			return Permanent(err)
+		}
+		changed = true
+	}
+
+	for _, n := range v.RemoveNames {
+		size := len(d.SAN)
+		d.RemoveSANName(n)
+		changed = changed || len(d.SAN) != size
+	}
+
+	if !changed {
+		return nil
+	}
+
+	d.OrderURL = ""
+	if err := p.transition(d, m, domain.Provisioning, "names modified", nil); err != nil {
+		return err
+	}
+	return p.broker.Publish(Authorization, newDomainPayload(d))
+}
+
+// RenewDomain starts a new authorization for an issued domain
+// to replace its certificate before it expires.
+// The current certificate is kept until the new one replaces it.
+// Renewals for certificates that have already been replaced,
+// or for domains that are not issued, are ignored.
+func (p *DomainProcessor) RenewDomain(m *Message) error {
+	v, ok := m.Payload.(*RenewDomainPayload)
+	if !ok {
+		return Permanent(errors.Errorf("error renewing domain, invalid payload message: %v", m.Payload))
+	}
+
+	d, err := p.bucket.GetDomain(v.AccountID, v.DomainName)
+	if err != nil {
+		return err
+	}
+
+	if d.State != domain.Issued || d.Certificate == nil || !d.Certificate.NotAfter.Equal(v.NotAfter) {
+		return nil
+	}
+
+	cause := "renewal started"
+	if v.ExplanationURL != "" {
+		cause = "renewal suggested by the CA: " + v.ExplanationURL
+	}
+
+	d.OrderURL = ""
+	if err := p.transition(d, m, domain.Provisioning, cause, nil); err != nil {
+		return err
+	}
+
+	return p.broker.Publish(Authorization, newDomainPayload(d))
+}
 
 // RequestDomainCertificate sends a request to retrieve a certificate to the CA.
 // If the job succeeds, it marks the domain as issued and removes it from any
-// processing queue. Otherwise, it leaves to the broker
+// processing queue. While the CA is processing the order, it requests the
+// certificate again later. Otherwise, it leaves to the broker
 // to decide what to do with the message.
+// Domains that are already issued are ignored.
 func (p *DomainProcessor) RequestDomainCertificate(m *Message) error {
 	v, ok := m.Payload.(*DomainPayload)
 	if !ok {
-		return errors.Errorf("error verifying domain, invalid payload message: %v", m.Payload)
+		return Permanent(errors.Errorf("error verifying domain, invalid payload message: %v", m.Payload))
 	}
 
 	d, err := p.bucket.GetDomain(v.AccountID, v.DomainName)
@@ -99,20 +349,86 @@ func (p *DomainProcessor) RequestDomainCertificate(m *Message) error {
 		return err
 	}
 
-	c, err := certificates.NewClientWithAPIKey(d.Account, p.config.Ns1APIKey)
+	if !handles(d, domain.Authorized, domain.Requesting) {
+		return nil
+	}
+
+	c, err := p.newClient(d.Account)
 	if err != nil {
 		return err
 	}
 
+	// Domains are already requesting when the CA is processing their order.
+	if d.State != domain.Requesting {
+		if err := p.transition(d, m, domain.Requesting, "certificate requested", nil); err != nil {
+			return err
+		}
+	}
+
 	cert, err := c.RequestCertificate(d)
 	if err != nil {
-		return err
+		// Keep the certificate key, the CA can issue
+		// the certificate after the request fails.
+		if serr := p.bucket.SaveDomain(d); serr != nil {
+			return serr
+		}
+
+		if errors.Cause(err) != certificates.ErrOrderProcessing {
+			return err
+		}
+
+		next := newDomainPayload(d)
+		next.Polls = v.Polls + 1
+		delay := authzPollDelay(next.Polls, c.OrderRetryAfter(d))
+		return p.broker.PublishAfter(CertRequest, next, delay)
 	}
 
 	d.Certificate = cert
-	d.State = domain.Issued
+	return p.transition(d, m, domain.Issued, "certificate issued", nil)
+}
 
-	return p.bucket.SaveDomain(d)
+// RevokeDomain revokes the certificate issued for a domain.
+// If the job succeeds, it records the revocation in the certificate
+// and moves the domain to the revoked state. Otherwise, it leaves
+// to the broker to decide what to do with the message.
+func (p *DomainProcessor) RevokeDomain(m *Message) error {
+	v, ok := m.Payload.(*RevokeDomainPayload)
+	if !ok {
+		return Permanent(errors.Errorf("error revoking domain, invalid payload message: %v", m.Payload))
+	}
+
+	reason := acme.CRLReasonCode(v.Reason)
+	name, ok := revocationReasons[reason]
+	if !ok {
+		return Permanent(errors.Errorf("invalid revocation reason: %d", v.Reason))
+	}
+
+	d, err := p.bucket.GetDomain(v.AccountID, v.DomainName)
+	if err != nil {
+		return err
+	}
+
+	if d.State == domain.Revoked {
+		return nil
+	}
+
+	// Retrying the message doesn't change the domain's state.
+	if d.State != domain.Issued || d.Certificate == nil {
+		return Permanent(errors.Errorf("unable to revoke domain %s in state: %s", d.Name, d.State))
+	}
+
+	c, err := p.newClient(d.Account)
+	if err != nil {
+		return err
+	}
+
+	if err := c.RevokeCertificate(d, reason, v.UseCertificateKey); err != nil {
+		return err
+	}
+
+	d.Certificate.RevokedAt = time.Now().UTC()
+	d.Certificate.RevocationReason = v.Reason
+	return p.transition(d, m, domain.Revoked, "certificate revoked: "+name, nil)
 }
 
 // ValidateDomain validates that a domain is correctly configured
@@ -120,10 +436,12 @@ func (p *DomainProcessor) RequestDomainCertificate(m *Message) error {
 // If the job succeeds, it moves the domain to the
 // authorization state. Otherwise, it leaves to the broker
 // to decide what to do with the message.
+// Domains are always valid when there is no header validator configured.
+// Domains that are already verified are ignored.
 func (p *DomainProcessor) ValidateDomain(m *Message) error {
 	v, ok := m.Payload.(*DomainPayload)
 	if !ok {
-		return errors.Errorf("error verifying domain, invalid payload message: %v", m.Payload)
+		return Permanent(errors.Errorf("error verifying domain, invalid payload message: %v", m.Payload))
 	}
 
 	d, err := p.bucket.GetDomain(v.AccountID, v.DomainName)
@@ -131,93 +449,582 @@ func (p *DomainProcessor) ValidateDomain(m *Message) error {
 		return err
 	}
 
-	next := domain.Verified
-	var vErr error
+	if !handles(d, domain.Pending, domain.Validating, domain.Invalid) {
+		return nil
+	}
 
+	if err := p.transition(d, m, domain.Validating, "validation started", nil); err != nil {
+		return err
+	}
+
+	// Wildcard names can't be requested, their base name is checked instead.
 	h := p.config.HeaderValidator
-	valid := validator.ValidHeader(d.Name, h.Name, h.Value)
+	if h.Name != "" && !validator.ValidHeader(domain.BaseName(d.Name), h.Name, h.Value) {
+		verr := errors.Errorf("domain validation failed for domain: %s", d.Name)
+		if err := p.transition(d, m, domain.Invalid, "invalid domain headers", verr); err != nil {
+			return err
+		}
 
-	if !valid {
-		next = domain.Invalid
-		vErr = errors.Errorf("domain validation failed for domain: %s", d.Name)
+		return verr
 	}
 
-	d.State = next
-	if err := p.bucket.SaveDomain(d); err != nil {
+	if err := p.transition(d, m, domain.Verified, "domain verified", nil); err != nil {
 		return err
 	}
 
-	return vErr
+	return p.broker.Publish(Authorization, newDomainPayload(d))
 }
 
-func (p *DomainProcessor) checkAuthzState(c *certificates.Client, d *domain.Domain) error {
-	authz, err := c.GetAuthorization(d)
+// checkOrderState polls the order state in the CA.
+// It moves the domain to the authorized state when all the
+// authorizations in the order are valid. While the order is pending,
+// it checks again later, waiting longer after each poll or as long as the CA asks for.
+func (p *DomainProcessor) checkOrderState(c certificateClient, d *domain.Domain, m *Message, polls int) error {
+	o, err := c.GetOrder(d)
 	if err != nil {
 		return err
 	}
 
-	m := NewMessage(&DomainPayload{
-		AccountID:  d.Account.ID,
-		DomainName: d.Name,
-	})
+	// Accept the challenges that a previous attempt failed to accept.
+	if err := p.updateAuthorizations(c, d, m, o); err != nil {
+		return err
+	}
 
-	if authz.Status != acme.StatusPending && authz.Status != acme.StatusProcessing {
-		d.State = domain.Authorized
-		if err := p.bucket.SaveDomain(d); err != nil {
+	if d.Authorized() {
+		if err := p.transition(d, m, domain.Authorized, "authorization valid", nil); err != nil {
 			return err
 		}
 
-		return p.broker.Publish(CertRequest, m)
+		return p.broker.Publish(CertRequest, newDomainPayload(d))
+	}
+
+	if o.Status == acme.StatusPending {
+		next := newDomainPayload(d)
+		next.Polls = polls + 1
+		delay := authzPollDelay(next.Polls, c.OrderRetryAfter(d))
+		return p.broker.PublishAfter(Authorization, next, delay)
 	}
 
-	return p.broker.Publish(Authorization, m)
+	reason := o.Status
+	var failed []string
+	fallback := true
+	for _, a := range d.Authorizations {
+		if !a.Valid() && a.Status != acme.StatusPending {
+			failed = append(failed, a.Name)
+			fallback = fallback && canFallback(d, a)
+		}
+	}
+	if len(failed) > 0 {
+		reason = "invalid names: " + strings.Join(failed, ", ")
+	}
+
+	if len(failed) > 0 && fallback {
+		return p.fallbackChallenges(c, d, m, reason)
+	}
+
+	// Clear the order, so the next attempt starts a new one.
+	d.OrderURL = ""
+	aerr := errors.Errorf("authorization failed for domain: %s - %s", d.Name, reason)
+
+	// Domains renewing a certificate that is still valid keep serving it,
+	// and the scheduler tries to renew it again in its next scan.
+	// The failure is recorded in the domain's history, the message is
+	// not retried, so it doesn't start another renewal right away.
+	if keepsCertificate(d) {
+		return p.transition(d, m, domain.Issued, "renewal failed, the current certificate is kept", aerr)
+	}
+
+	if err := p.transition(d, m, domain.Invalid, "authorization failed", aerr); err != nil {
+		return err
+	}
+
+	return aerr
+}
+
+// keepsCertificate checks if a domain still has
+// a certificate that it can serve while it's renewed.
+func keepsCertificate(d *domain.Domain) bool {
+	c := d.Certificate
+	return c != nil && !c.Revoked() && time.Now().Before(c.NotAfter)
 }
 
-func (p *DomainProcessor) startAuthProcess(c *certificates.Client, d *domain.Domain) error {
-	authz, err := c.AuthorizeDomain(d)
+// fallbackChallenges starts a new order for the domain after the CA
+// failed to validate some of its challenges. The names that failed
+// are authorized with the next challenge type in the new order.
+func (p *DomainProcessor) fallbackChallenges(c certificateClient, d *domain.Domain, m *Message, reason string) error {
+	for _, a := range d.Authorizations {
+		if a.Status == acme.StatusInvalid {
+			a.FailedChallengeTypes = append(a.FailedChallengeTypes, a.ChallengeType)
+			// Replace the authorization, the CA never validates it again.
+			a.URL = ""
+		}
+	}
+
+	d.OrderURL = ""
+	ferr := errors.Errorf("challenge validation failed for domain: %s - %s", d.Name, reason)
+	if err := p.diagnose(d, m, "falling back to the next challenge type", ferr); err != nil {
+		return err
+	}
+
+	return p.startAuthProcess(c, d, m)
+}
+
+// startAuthProcess creates a new order for the domain
+// and accepts the challenges of all its pending authorizations.
+func (p *DomainProcessor) startAuthProcess(c certificateClient, d *domain.Domain, m *Message) error {
+	o, err := c.AuthorizeDomain(d)
 	if err != nil {
 		return err
 	}
 
-	d.AuthorizationURL = authz.URI
+	d.OrderURL = o.URI
+	if err := p.transition(d, m, domain.Provisioning, "authorization started", nil); err != nil {
+		return err
+	}
+
+	if err := p.updateAuthorizations(c, d, m, o); err != nil {
+		return err
+	}
+
+	return p.broker.PublishAfter(Authorization, newDomainPayload(d), authzPollDelay(0, 0))
+}
+
+// updateAuthorizations records the state of every authorization
+// in an order, one per SAN name, and accepts the challenges of the
+// pending ones. Authorizations that the CA has already validated,
+// or that are validating, are skipped. Challenges that are not ready
+// for the CA yet are accepted in a later poll.
+func (p *DomainProcessor) updateAuthorizations(c certificateClient, d *domain.Domain, m *Message, o *acme.Order) error {
+	var pending []*acme.Authorization
+	auths := make([]*domain.Authorization, 0, len(o.AuthzURLs))
+
+	for _, u := range o.AuthzURLs {
+		authz, err := c.GetAuthorization(u)
+		if err != nil {
+			return err
+		}
+
+		// Keep the challenge prepared for the same authorization in previous attempts.
+		a := d.Authorization(authorizationName(authz))
+		if a == nil || a.URL != u {
+			// Remove the challenge of the replaced authorization,
+			// so it doesn't conflict with the new challenge.
+			if a != nil && a.Prepared() {
+				if err := p.cleanupChallenge(c, a); err != nil {
+					return err
+				}
+			}
+			a = replaceAuthorization(a, authorizationName(authz), u)
+		}
+		a.Status = authz.Status
+		auths = append(auths, a)
+
+		if authz.Status == acme.StatusPending {
+			pending = append(pending, authz)
+		}
+	}
+
+	d.Authorizations = auths
 	if err := p.bucket.SaveDomain(d); err != nil {
 		return err
 	}
 
-	var chal *acme.Challenge
-	for _, c := range authz.Challenges {
-		if c.Type == d.ChallengeType {
-			chal = c
+	for _, a := range d.Authorizations {
+		if a.NeedsCleanup() {
+			if err := p.broker.Publish(Cleanup, newDomainPayload(d)); err != nil {
+				return err
+			}
 			break
 		}
 	}
 
-	if chal == nil {
-		return errors.Errorf("unable to find a valid challenge for domain: %s", d.Name)
+	for _, authz := range pending {
+		a := d.Authorization(authorizationName(authz))
+		chal, err := selectChallenge(c, d, a, authz)
+		if err != nil {
+			return err
+		}
+
+		if chal.Status != acme.StatusPending {
+			continue
+		}
+
+		// Keep the challenge prepared in a previous attempt,
+		// while its record propagates.
+		if !a.Prepared() || a.ChallengeURL != chal.URI {
+			if err := p.prepareChallenge(c, a, chal); err != nil {
+				return err
+			}
+
+			if err := p.bucket.SaveDomain(d); err != nil {
+				return err
+			}
+		}
+
+		ready, err := p.challengeReady(c, d, m, a)
+		if err != nil {
+			return err
+		}
+		if !ready {
+			continue
+		}
+
+		if _, err := c.AcceptChallenge(d, chal); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// selectChallenge picks the challenge to authorize a name with.
+// It keeps the challenge prepared in a previous attempt. Otherwise, it picks
+// the first of the domain's challenge types that the CA offers and that
+// the client can resolve, skipping the types that already failed for the name.
+func selectChallenge(c certificateClient, d *domain.Domain, a *domain.Authorization, authz *acme.Authorization) (*acme.Challenge, error) {
+	offered := make(map[string]*acme.Challenge, len(authz.Challenges))
+	for _, chal := range authz.Challenges {
+		offered[chal.Type] = chal
+	}
+
+	if chal, ok := offered[a.ChallengeType]; ok && a.Prepared() {
+		return chal, nil
+	}
+
+	var reasons []string
+	for _, t := range d.ChallengeTypesFor(a) {
+		chal, ok := offered[t]
+		if !ok {
+			reasons = append(reasons, t+" not offered by the CA")
+			continue
+		}
+
+		if err := c.SupportsChallenge(t); err != nil {
+			reasons = append(reasons, err.Error())
+			continue
+		}
+		return chal, nil
+	}
+
+	if len(reasons) == 0 {
+		return nil, errors.Errorf("unable to find a valid challenge for domain: %s", a.Name)
+	}
+	return nil, errors.Errorf("unable to find a valid challenge for domain: %s - %s", a.Name, strings.Join(reasons, ", "))
+}
+
+// canFallback checks if a name can be authorized with another
+// challenge type, after the CA failed to validate its challenge.
+func canFallback(d *domain.Domain, a *domain.Authorization) bool {
+	if a.Status != acme.StatusInvalid || a.ChallengeType == "" {
+		return false
+	}
+
+	for _, t := range d.ChallengeTypesFor(a) {
+		if t != a.ChallengeType {
+			return true
+		}
+	}
+	return false
+}
+
+// authorizationName returns the SAN name that an authorization is for.
+// The CA identifies wildcard authorizations by their base name,
+// so they don't conflict with the authorization of the base name.
+func authorizationName(authz *acme.Authorization) string {
+	if authz.Wildcard {
+		return "*." + authz.Identifier.Value
+	}
+	return authz.Identifier.Value
+}
+
+// replaceAuthorization initializes the authorization for a name in a new order.
+// It keeps the challenge types that failed in the previous authorization.
+func replaceAuthorization(prev *domain.Authorization, name, url string) *domain.Authorization {
+	a := &domain.Authorization{Name: name, URL: url}
+	if prev != nil {
+		a.FailedChallengeTypes = prev.FailedChallengeTypes
+	}
+	return a
+}
+
+// challengeReady checks that the CA can validate a challenge,
+// so failed validations don't count against the CA's rate limits.
+// dns-01 challenges are ready once their record has propagated to all the
+// nameservers of the zone. http-01 challenges are ready once Isard can
+// request them itself, the reason why it can't is recorded in the domain history.
+// When a challenge is not ready before the timeout, its resources are removed,
+// so the next attempt creates them again.
+func (p *DomainProcessor) challengeReady(c certificateClient, d *domain.Domain, m *Message, a *domain.Authorization) (bool, error) {
+	var (
+		cerr    error
+		timeout time.Duration
+		failure string
+	)
+
+	switch a.ChallengeType {
+	case "dns-01":
+		ok, err := p.propagated(domain.ChallengeRecordName(a.Name), a.DNS01ChallengeRecord)
+		if ok {
+			return true, nil
+		}
+		cerr = err
+		timeout = p.config.PropagationTimeout.Duration
+		failure = "the DNS record for domain %s didn't propagate in %s"
+	case "http-01":
+		if p.config.DisableSelfCheck {
+			return true, nil
+		}
+
+		cerr = p.checkHTTPChallenge(a.Name, a.HTTP01ChallengePath, a.HTTP01ChallengeResponse)
+		if cerr == nil {
+			return true, nil
+		}
+		timeout = p.config.SelfCheckTimeout.Duration
+		failure = "the http-01 self-check for domain %s didn't pass in %s"
+
+		if err := p.diagnose(d, m, "http-01 self-check failed", cerr); err != nil {
+			return false, err
+		}
+	default:
+		return true, nil
+	}
+
+	if timeout == 0 {
+		timeout = defaultChallengeTimeout
+	}
+
+	if time.Since(a.PreparedAt) < timeout {
+		return false, nil
+	}
+
+	if err := p.cleanupChallenge(c, a); err != nil {
+		return false, err
 	}
 
-	d, err = c.PrepareChallenge(d, chal)
 	if err := p.bucket.SaveDomain(d); err != nil {
+		return false, err
+	}
+
+	if cerr != nil {
+		return false, errors.Wrapf(cerr, failure, a.Name, timeout)
+	}
+	return false, errors.Errorf(failure, a.Name, timeout)
+}
+
+// diagnose records in the domain history why it can't move forward,
+// without changing its state. The same reason is only recorded once in a row.
+func (p *DomainProcessor) diagnose(d *domain.Domain, m *Message, cause string, derr error) error {
+	if n := len(d.History); n > 0 && d.History[n-1].Cause == cause && d.History[n-1].Error == derr.Error() {
+		return nil
+	}
+	return p.transition(d, m, d.State, cause, derr)
+}
+
+// cleanupChallenges removes the resources created to resolve
+// the challenges of the domain's authorizations, and saves the domain.
+// It only cleans up the authorizations that the CA has finished with,
+// unless all is true. It returns the first error found.
+func (p *DomainProcessor) cleanupChallenges(c certificateClient, d *domain.Domain, all bool) error {
+	_, cerr := p.removeChallenges(c, d, all)
+	if err := p.bucket.SaveDomain(d); err != nil {
+		return err
+	}
+	return cerr
+}
+
+// removeChallenges removes the resources created to resolve
+// the challenges of the domain's authorizations, without saving the domain.
+// It tries every authorization, and returns the ones cleaned up
+// and the first error found.
+func (p *DomainProcessor) removeChallenges(c certificateClient, d *domain.Domain, all bool) ([]*domain.Authorization, error) {
+	var cleaned []*domain.Authorization
+	var cerr error
+	for _, a := range d.Authorizations {
+		if !a.Prepared() || (!all && !a.NeedsCleanup()) {
+			continue
+		}
+
+		if err := p.cleanupChallenge(c, a); err != nil {
+			if cerr == nil {
+				cerr = errors.Wrapf(err, "error cleaning up challenge for domain: %s", a.Name)
+			}
+			continue
+		}
+		cleaned = append(cleaned, a)
+	}
+	return cleaned, cerr
+}
+
+// markCleanedUp records the challenges cleaned up in the latest copy
+// of the domain, so the changes saved by other steps are kept.
+// Challenges prepared again in the meantime are not marked.
+func (p *DomainProcessor) markCleanedUp(d *domain.Domain, cleaned []*domain.Authorization) error {
+	latest, err := p.bucket.GetDomain(d.Account.ID, d.Name)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range cleaned {
+		a := latest.Authorization(c.Name)
+		if a == nil || a.URL != c.URL || !a.PreparedAt.Equal(c.PreparedAt) {
+			continue
+		}
+		a.CleanedUp = true
+	}
+	return p.bucket.SaveDomain(latest)
+}
+
+// prepareChallenge creates the resources to resolve the challenge
+// of an authorization, and indexes the challenge so the responders
+// can find it without listing the domains.
+func (p *DomainProcessor) prepareChallenge(c certificateClient, a *domain.Authorization, chal *acme.Challenge) error {
+	if err := c.PrepareChallenge(a, chal); err != nil {
+		return err
+	}
+	a.CleanedUp = false
+	a.PreparedAt = time.Now()
+
+	return p.bucket.SaveChallenge(a)
+}
+
+// cleanupChallenge removes the resources created to resolve
+// the challenge of an authorization, and its index entry.
+func (p *DomainProcessor) cleanupChallenge(c certificateClient, a *domain.Authorization) error {
+	if err := c.CleanupChallenge(a); err != nil {
 		return err
 	}
 
-	if _, err := c.AcceptChallenge(d, chal); err != nil {
+	if err := p.bucket.DeleteChallenge(a); err != nil {
 		return err
 	}
+	a.CleanedUp = true
+	return nil
+}
+
+// transition moves the domain to a new state, recording
+// the job that triggered the change, and saves it.
+// Invalid transitions are permanent errors, retrying
+// the message doesn't change the domain's state.
+func (p *DomainProcessor) transition(d *domain.Domain, m *Message, to domain.State, cause string, cerr error) error {
+	if err := d.TransitionTo(to, cause, m.JobUUID, cerr); err != nil {
+		return Permanent(err)
+	}
+	return p.bucket.SaveDomain(d)
+}
+
+// authzPollDelay returns how long to wait before polling
+// an authorization again. The delay doubles with each poll,
+// up to a limit. The CA's Retry-After takes precedence when it's longer.
+func authzPollDelay(polls int, retryAfter time.Duration) time.Duration {
+	d := authzPollInitialDelay
+	for i := 0; i < polls && d < authzPollMaxDelay; i++ {
+		d *= 2
+	}
+	if d > authzPollMaxDelay {
+		d = authzPollMaxDelay
+	}
 
-	m := NewMessage(&DomainPayload{
+	if retryAfter > d {
+		return retryAfter
+	}
+	return d
+}
+
+// process sends a message to the processor
+// operation that handles its topic.
+func process(processor Processor, m *Message) error {
+	switch m.Topic {
+	case Creation:
+		return processor.CreateDomain(m)
+	case Modification:
+		return processor.ModifyDomain(m)
+	case Validation:
+		return processor.ValidateDomain(m)
+	case Authorization:
+		return processor.AuthorizeDomain(m)
+	case CertRequest:
+		return processor.RequestDomainCertificate(m)
+	case Renewal:
+		return processor.RenewDomain(m)
+	case Cancellation:
+		return processor.CancelDomain(m)
+	case Revocation:
+		return processor.RevokeDomain(m)
+	case Cleanup:
+		return processor.CleanupDomain(m)
+	default:
+		return Permanent(errors.Errorf("unknown message topic: %s", m.Topic))
+	}
+}
+
+// cancelled checks if a domain is being cancelled,
+// so the processor stops moving it across the lifecycle.
+func cancelled(d *domain.Domain) bool {
+	return d.State == domain.Cancelling || d.State == domain.Cancelled
+}
+
+// handles checks if a domain is in one of the states that a step handles.
+// The brokers deliver messages at least once, so a step can receive
+// a message again after the domain has moved past it, or after it has
+// been cancelled. Those messages are ignored.
+func handles(d *domain.Domain, states ...domain.State) bool {
+	for _, s := range states {
+		if d.State == s {
+			return true
+		}
+	}
+	return false
+}
+
+func newDomainPayload(d *domain.Domain) *DomainPayload {
+	return &DomainPayload{
 		AccountID:  d.Account.ID,
 		DomainName: d.Name,
-	})
-
-	return p.broker.Publish(Authorization, m)
+	}
 }
 
 // NewDomainProcessor initializes the domain processor.
 func NewDomainProcessor(bucket storage.Bucket, broker Broker, config *configuration.DomainsConfiguration) *DomainProcessor {
+	if config == nil {
+		config = &configuration.DomainsConfiguration{}
+	}
+
+	provider, providerErr := newDNSProvider(config)
+
 	return &DomainProcessor{
-		bucket: bucket,
-		broker: broker,
-		config: config,
+		bucket:             bucket,
+		broker:             broker,
+		config:             config,
+		propagated:         domain.TXTRecordPropagated,
+		checkHTTPChallenge: validator.CheckHTTPChallenge,
+		newClient: func(a *account.Account) (certificateClient, error) {
+			if a.DNSProvider != "" {
+				p, err := challenges.NewDNSProvider(a.DNSProvider, a.DNSProviderOptions)
+				if err != nil {
+					return nil, err
+				}
+				return certificates.NewClientWithDNSProvider(a, p)
+			}
+
+			if providerErr != nil {
+				return nil, providerErr
+			}
+			return certificates.NewClientWithDNSProvider(a, provider)
+		},
+	}
+}
+
+// newDNSProvider initializes the DNS provider set in the configuration,
+// used by the accounts that don't have their own.
+// It falls back to NS1 when only its api key is set,
+// and returns nil when there is no provider to resolve dns-01 challenges.
+func newDNSProvider(config *configuration.DomainsConfiguration) (challenges.DNSProvider, error) {
+	if config.DNSProvider != nil {
+		return challenges.NewDNSProvider(config.DNSProvider.Name, config.DNSProvider.Options)
+	}
+
+	if config.Ns1APIKey != "" {
+		return challenges.NewNS1Provider(config.Ns1APIKey), nil
 	}
+	return nil, nil
 }
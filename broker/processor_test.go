@@ -4,20 +4,271 @@ import (
 	"io/ioutil"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/lost-mountain/isard/account"
+	"github.com/lost-mountain/isard/certificates"
 	"github.com/lost-mountain/isard/configuration"
+	"github.com/lost-mountain/isard/cryptopolis"
 	"github.com/lost-mountain/isard/domain"
 	"github.com/lost-mountain/isard/storage"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
+	"golang.org/x/crypto/acme"
 )
 
 type noopBroker struct{}
 
-func (b *noopBroker) Close() error                                       { return nil }
-func (b *noopBroker) Publish(topic TopicType, payload interface{}) error { return nil }
-func (b *noopBroker) Subscribe(processor Processor) error                { return nil }
+func (b *noopBroker) Close() error                                                       { return nil }
+func (b *noopBroker) Publish(topic TopicType, payload interface{}) error                 { return nil }
+func (b *noopBroker) PublishAfter(topic TopicType, p interface{}, d time.Duration) error { return nil }
+func (b *noopBroker) PublishAt(topic TopicType, payload interface{}, t time.Time) error  { return nil }
+func (b *noopBroker) SetRetryPolicy(topic TopicType, policy *RetryPolicy)                {}
+func (b *noopBroker) Subscribe(processor Processor) error                                { return nil }
+
+// queueBroker keeps the published messages in memory,
+// so tests can drive the processor step by step.
+type queueBroker struct {
+	noopBroker
+	messages []*Message
+}
+
+func (b *queueBroker) Publish(topic TopicType, payload interface{}) error {
+	return b.PublishAt(topic, payload, time.Time{})
+}
+
+func (b *queueBroker) PublishAfter(topic TopicType, payload interface{}, d time.Duration) error {
+	return b.PublishAt(topic, payload, time.Now().Add(d))
+}
+
+func (b *queueBroker) PublishAt(topic TopicType, payload interface{}, t time.Time) error {
+	m := NewMessage(payload)
+	m.Topic = topic
+	m.NotBefore = t
+	b.messages = append(b.messages, m)
+	return nil
+}
+
+// next processes the first message in the queue and returns its topic.
+func (b *queueBroker) next(t *testing.T, p Processor) TopicType {
+	require.NotEmpty(t, b.messages)
+
+	m := b.messages[0]
+	b.messages = b.messages[1:]
+
+	require.NoError(t, process(p, m))
+	return m.Topic
+}
+
+// drain processes messages until the queue is empty,
+// ignoring their delivery time. It returns the topics processed.
+func (b *queueBroker) drain(t *testing.T, p Processor) []TopicType {
+	var topics []TopicType
+	for len(b.messages) > 0 {
+		topics = append(topics, b.next(t, p))
+	}
+	return topics
+}
+
+// stateBucket records the state of the domains every time they are saved.
+type stateBucket struct {
+	storage.Bucket
+	states []domain.State
+}
+
+func (b *stateBucket) SaveDomain(d *domain.Domain) error {
+	if n := len(b.states); n == 0 || b.states[n-1] != d.State {
+		b.states = append(b.states, d.State)
+	}
+	return b.Bucket.SaveDomain(d)
+}
+
+// fakeCertificateClient simulates a CA that validates
+// orders after being polled a number of times.
+// Authorizations stay valid once the CA validates them,
+// so new orders only need challenges for the new names.
+type fakeCertificateClient struct {
+	pendingPolls int
+	processing   int               // certificate requests answered while the CA processes the order
+	authz        map[string]string // authorization status by name
+	accepted     map[string]bool   // names with a challenge accepted
+	rejected     map[string]bool   // names that the CA fails to validate
+	acceptedType map[string]string // challenge type accepted by name
+	rejectedType map[string]bool   // challenge types that the CA fails to validate
+	unsupported  map[string]bool   // challenge types that the client can't resolve
+	order        []string
+	authorized   []string
+	deactivated  []string
+	cleaned      []string
+	failCleanups int
+	onCleanup    func() // called while a challenge is cleaned up
+	revoked      []acme.CRLReasonCode
+	revokedByKey []bool
+	prepared     []string
+}
+
+func (c *fakeCertificateClient) AcceptChallenge(d *domain.Domain, chal *acme.Challenge) (*acme.Challenge, error) {
+	name := chal.URI[len("https://ca.example.com/chal/"):]
+	c.authorized = append(c.authorized, name)
+	c.accepted[name] = true
+	c.acceptedType[name] = chal.Type
+	return chal, nil
+}
+
+func (c *fakeCertificateClient) AuthorizeDomain(d *domain.Domain) (*acme.Order, error) {
+	if c.authz == nil {
+		c.authz = map[string]string{}
+		c.accepted = map[string]bool{}
+		c.acceptedType = map[string]string{}
+	}
+
+	o := &acme.Order{
+		URI:    "https://ca.example.com/order/" + d.Name,
+		Status: acme.StatusPending,
+	}
+
+	c.order = d.SANNames()
+	for _, n := range c.order {
+		if c.authz[n] == acme.StatusInvalid {
+			// The CA creates a new authorization for names that failed.
+			c.accepted[n] = false
+		}
+		if c.authz[n] != acme.StatusValid {
+			c.authz[n] = acme.StatusPending
+		}
+		o.AuthzURLs = append(o.AuthzURLs, "https://ca.example.com/authz/"+n)
+	}
+	return o, nil
+}
+
+func (c *fakeCertificateClient) CleanupChallenge(a *domain.Authorization) error {
+	if c.failCleanups > 0 {
+		c.failCleanups--
+		return errors.New("DNS provider unavailable")
+	}
+
+	if c.onCleanup != nil {
+		c.onCleanup()
+	}
+
+	c.cleaned = append(c.cleaned, a.Name)
+	return nil
+}
+
+func (c *fakeCertificateClient) DeactivateAuthorization(url string) error {
+	c.deactivated = append(c.deactivated, url)
+	return nil
+}
+
+func (c *fakeCertificateClient) GetAuthorization(url string) (*acme.Authorization, error) {
+	name := url[len("https://ca.example.com/authz/"):]
+	status := c.authz[name]
+	chalStatus := status
+	if c.accepted[name] && status == acme.StatusPending {
+		chalStatus = acme.StatusProcessing
+	}
+
+	authz := &acme.Authorization{
+		URI:        url,
+		Status:     status,
+		Identifier: acme.AuthzID{Type: "dns", Value: domain.BaseName(name)},
+		Wildcard:   domain.IsWildcard(name),
+		Challenges: []*acme.Challenge{
+			{Type: "dns-01", URI: "https://ca.example.com/chal/" + name, Token: "dns-token", Status: chalStatus},
+		},
+	}
+
+	// CAs only offer dns-01 challenges for wildcard names.
+	if !authz.Wildcard {
+		authz.Challenges = append(authz.Challenges,
+			&acme.Challenge{Type: "http-01", URI: "https://ca.example.com/chal/" + name, Token: "http-token", Status: chalStatus})
+	}
+	return authz, nil
+}
+
+func (c *fakeCertificateClient) GetOrder(d *domain.Domain) (*acme.Order, error) {
+	o := &acme.Order{
+		URI:    d.OrderURL,
+		Status: acme.StatusPending,
+	}
+	for _, n := range c.order {
+		o.AuthzURLs = append(o.AuthzURLs, "https://ca.example.com/authz/"+n)
+	}
+
+	if c.pendingPolls > 0 {
+		c.pendingPolls--
+		return o, nil
+	}
+
+	for _, n := range c.order {
+		if c.authz[n] != acme.StatusValid && !c.accepted[n] {
+			return o, nil
+		}
+	}
+
+	o.Status = acme.StatusReady
+	for _, n := range c.order {
+		if c.authz[n] == acme.StatusValid {
+			continue
+		}
+
+		c.authz[n] = acme.StatusValid
+		if c.rejected[n] || c.rejectedType[c.acceptedType[n]] {
+			c.authz[n] = acme.StatusInvalid
+			o.Status = acme.StatusInvalid
+		}
+	}
+	return o, nil
+}
+
+func (c *fakeCertificateClient) OrderRetryAfter(d *domain.Domain) time.Duration {
+	return 0
+}
+
+func (c *fakeCertificateClient) PrepareChallenge(a *domain.Authorization, chal *acme.Challenge) error {
+	c.prepared = append(c.prepared, a.Name)
+	a.ChallengeType = chal.Type
+	a.ChallengeURL = chal.URI
+	if chal.Type == "dns-01" {
+		a.DNS01ChallengeRecord = chal.Token + ".record"
+		return nil
+	}
+	a.HTTP01ChallengePath = "/.well-known/acme-challenge/" + chal.Token
+	a.HTTP01ChallengeResponse = chal.Token + ".thumbprint"
+	return nil
+}
+
+func (c *fakeCertificateClient) SupportsChallenge(challengeType string) error {
+	if c.unsupported[challengeType] {
+		return errors.Errorf("unsupported challenge type: %s", challengeType)
+	}
+	return nil
+}
+
+func (c *fakeCertificateClient) RevokeCertificate(d *domain.Domain, reason acme.CRLReasonCode, useCertificateKey bool) error {
+	c.revoked = append(c.revoked, reason)
+	c.revokedByKey = append(c.revokedByKey, useCertificateKey)
+	return nil
+}
+
+func (c *fakeCertificateClient) RequestCertificate(d *domain.Domain) (*cryptopolis.Certificate, error) {
+	if c.processing > 0 {
+		c.processing--
+		d.PendingKey = []byte("key")
+		return nil, certificates.ErrOrderProcessing
+	}
+
+	d.PendingKey = nil
+	now := time.Now()
+	return &cryptopolis.Certificate{
+		Cert:      []byte("certificate"),
+		Key:       []byte("key"),
+		CA:        []byte("chain"),
+		NotBefore: now,
+		NotAfter:  now.Add(90 * 24 * time.Hour),
+	}, nil
+}
 
 type testSuite struct {
 	suite.Suite
@@ -74,6 +325,756 @@ func (s *testSuite) createDefaultDomain(name string) {
 	require.NoError(s.T(), err)
 }
 
+// lifecycle drives a processor with an in memory queue
+// and a fake CA to test complete domain workflows.
+type lifecycle struct {
+	processor *DomainProcessor
+	queue     *queueBroker
+	bucket    *stateBucket
+	ca        *fakeCertificateClient
+	account   *account.Account
+}
+
+func newLifecycle(t *testing.T) (*lifecycle, func()) {
+	f, err := ioutil.TempFile("", "isard-")
+	require.NoError(t, err)
+
+	err = f.Close()
+	require.NoError(t, err)
+
+	bb, err := storage.NewBoltBucket(f.Name())
+	require.NoError(t, err)
+
+	a, err := account.NewAccount("david.calavera@gmail.com")
+	require.NoError(t, err)
+	require.NoError(t, bb.SaveAccount(a))
+
+	l := &lifecycle{
+		queue:   &queueBroker{},
+		bucket:  &stateBucket{Bucket: bb},
+		ca:      &fakeCertificateClient{},
+		account: a,
+	}
+	l.processor = NewDomainProcessor(l.bucket, l.queue, nil)
+	l.processor.newClient = func(*account.Account) (certificateClient, error) {
+		return l.ca, nil
+	}
+	l.processor.checkHTTPChallenge = func(hostname, path, expected string) error {
+		return nil
+	}
+
+	return l, func() {
+		bb.Close()
+		os.Remove(f.Name())
+	}
+}
+
+// issue creates a domain and drains the queue until its certificate is issued.
+func (l *lifecycle) issue(t *testing.T, name string) []TopicType {
+	err := l.queue.Publish(Creation, &CreateDomainPayload{
+		AccountID:    l.account.ID,
+		AccountToken: l.account.Token,
+		DomainName:   name,
+	})
+	require.NoError(t, err)
+
+	return l.queue.drain(t, l.processor)
+}
+
+func TestDomainLifecycle(t *testing.T) {
+	l, cleanup := newLifecycle(t)
+	defer cleanup()
+
+	l.ca.pendingPolls = 2
+	topics := l.issue(t, "test.cabal.io")
+	require.Equal(t, []TopicType{
+		Creation,
+		Validation,
+		Authorization, // start the authorization
+		Authorization, // pending
+		Authorization, // pending
+		Authorization, // valid
+		Cleanup,
+		CertRequest,
+	}, topics)
+
+	require.Equal(t, []domain.State{
+		domain.Pending,
+		domain.Validating,
+		domain.Verified,
+		domain.Provisioning,
+		domain.Authorized,
+		domain.Requesting,
+		domain.Issued,
+	}, l.bucket.states)
+
+	d, err := l.bucket.GetDomain(l.account.ID, "test.cabal.io")
+	require.NoError(t, err)
+	require.Equal(t, domain.Issued, d.State)
+	require.Len(t, d.Authorizations, 1)
+	require.Equal(t, "test.cabal.io", d.Authorizations[0].Name)
+	require.Equal(t, acme.StatusValid, d.Authorizations[0].Status)
+	require.Equal(t, "/.well-known/acme-challenge/http-token", d.Authorizations[0].HTTP01ChallengePath)
+	require.True(t, d.Authorizations[0].CleanedUp)
+	require.Equal(t, []string{"test.cabal.io"}, l.ca.cleaned)
+	require.Equal(t, []byte("certificate"), d.Certificate.Cert)
+
+	var history []domain.State
+	for _, tr := range d.History {
+		history = append(history, tr.To)
+	}
+	require.Equal(t, l.bucket.states, history)
+	require.Equal(t, "domain created", d.History[0].Cause)
+	require.Equal(t, "certificate issued", d.History[len(d.History)-1].Cause)
+}
+
+func TestRedeliveredMessages(t *testing.T) {
+	l, cleanup := newLifecycle(t)
+	defer cleanup()
+
+	l.issue(t, "test.cabal.io")
+	states := len(l.bucket.states)
+
+	// The brokers can deliver the messages of finished steps again.
+	payload := &DomainPayload{AccountID: l.account.ID, DomainName: "test.cabal.io"}
+	for _, topic := range []TopicType{Validation, Authorization, CertRequest} {
+		m := NewMessage(payload)
+		m.Topic = topic
+		require.NoError(t, process(l.processor, m), "%s", topic)
+	}
+	require.Empty(t, l.queue.messages)
+	require.Len(t, l.bucket.states, states)
+
+	d, err := l.bucket.GetDomain(l.account.ID, "test.cabal.io")
+	require.NoError(t, err)
+	require.Equal(t, domain.Issued, d.State)
+}
+
+func TestChallengeIndex(t *testing.T) {
+	l, cleanup := newLifecycle(t)
+	defer cleanup()
+
+	l.ca.pendingPolls = 1
+	err := l.queue.Publish(Creation, &CreateDomainPayload{
+		AccountID:    l.account.ID,
+		AccountToken: l.account.Token,
+		DomainName:   "test.cabal.io",
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, Creation, l.queue.next(t, l.processor))
+	require.Equal(t, Validation, l.queue.next(t, l.processor))
+	require.Equal(t, Authorization, l.queue.next(t, l.processor))
+
+	// The responders find the challenge while it's pending.
+	a, err := l.bucket.GetChallenge("http-01", "test.cabal.io", "http-token")
+	require.NoError(t, err)
+	require.Equal(t, "http-token.thumbprint", a.HTTP01ChallengeResponse)
+
+	l.queue.drain(t, l.processor)
+
+	_, err = l.bucket.GetChallenge("http-01", "test.cabal.io", "http-token")
+	require.Error(t, err)
+}
+
+func TestRequestCertificateWhileProcessing(t *testing.T) {
+	l, cleanup := newLifecycle(t)
+	defer cleanup()
+
+	l.ca.processing = 2
+	topics := l.issue(t, "test.cabal.io")
+	require.Equal(t, []TopicType{
+		Creation,
+		Validation,
+		Authorization, // start the authorization
+		Authorization, // valid
+		Cleanup,
+		CertRequest, // finalize the order
+		CertRequest, // processing
+		CertRequest, // issued
+	}, topics)
+
+	require.Equal(t, []domain.State{
+		domain.Pending,
+		domain.Validating,
+		domain.Verified,
+		domain.Provisioning,
+		domain.Authorized,
+		domain.Requesting,
+		domain.Issued,
+	}, l.bucket.states)
+
+	d, err := l.bucket.GetDomain(l.account.ID, "test.cabal.io")
+	require.NoError(t, err)
+	require.Equal(t, domain.Issued, d.State)
+	require.Nil(t, d.PendingKey)
+	require.Equal(t, []byte("certificate"), d.Certificate.Cert)
+}
+
+func TestAuthorizeAllNames(t *testing.T) {
+	l, cleanup := newLifecycle(t)
+	defer cleanup()
+
+	l.ca.pendingPolls = 1
+	topics := l.issue(t, "cabal.io")
+	require.Equal(t, []TopicType{
+		Creation,
+		Validation,
+		Authorization, // start the authorization
+		Authorization, // pending
+		Authorization, // valid
+		Cleanup,
+		CertRequest,
+	}, topics)
+	require.Equal(t, []string{"cabal.io", "www.cabal.io"}, l.ca.authorized)
+	require.Equal(t, []string{"cabal.io", "www.cabal.io"}, l.ca.cleaned)
+
+	d, err := l.bucket.GetDomain(l.account.ID, "cabal.io")
+	require.NoError(t, err)
+	require.Equal(t, domain.Issued, d.State)
+	require.True(t, d.Authorized())
+
+	for _, n := range d.SANNames() {
+		a := d.Authorization(n)
+		require.NotNil(t, a)
+		require.Equal(t, acme.StatusValid, a.Status)
+		require.Equal(t, "https://ca.example.com/authz/"+n, a.URL)
+		require.Equal(t, "https://ca.example.com/chal/"+n, a.ChallengeURL)
+	}
+}
+
+func TestAuthorizeDomainInvalidName(t *testing.T) {
+	l, cleanup := newLifecycle(t)
+	defer cleanup()
+
+	l.ca.rejected = map[string]bool{"www.cabal.io": true}
+	err := l.queue.Publish(Creation, &CreateDomainPayload{
+		AccountID:    l.account.ID,
+		AccountToken: l.account.Token,
+		DomainName:   "cabal.io",
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, Creation, l.queue.next(t, l.processor))
+	require.Equal(t, Validation, l.queue.next(t, l.processor))
+	require.Equal(t, Authorization, l.queue.next(t, l.processor))
+
+	require.Len(t, l.queue.messages, 1)
+	err = process(l.processor, l.queue.messages[0])
+	require.EqualError(t, err, "authorization failed for domain: cabal.io - invalid names: www.cabal.io")
+
+	d, err := l.bucket.GetDomain(l.account.ID, "cabal.io")
+	require.NoError(t, err)
+	require.Equal(t, domain.Invalid, d.State)
+	require.Empty(t, d.OrderURL)
+	require.True(t, d.Authorization("cabal.io").Valid())
+	require.Equal(t, acme.StatusInvalid, d.Authorization("www.cabal.io").Status)
+}
+
+func TestChallengeTypeFallback(t *testing.T) {
+	l, cleanup := newLifecycle(t)
+	defer cleanup()
+
+	l.ca.rejectedType = map[string]bool{"http-01": true}
+	l.processor.propagated = func(fqdn, value string) (bool, error) {
+		return true, nil
+	}
+
+	err := l.queue.Publish(Creation, &CreateDomainPayload{
+		AccountID:      l.account.ID,
+		AccountToken:   l.account.Token,
+		DomainName:     "test.cabal.io",
+		ChallengeTypes: []string{"http-01", "dns-01"},
+	})
+	require.NoError(t, err)
+
+	l.queue.drain(t, l.processor)
+	// Both challenges are prepared and cleaned up.
+	require.Equal(t, []string{"test.cabal.io", "test.cabal.io"}, l.ca.prepared)
+	require.Equal(t, []string{"test.cabal.io", "test.cabal.io"}, l.ca.cleaned)
+
+	d, err := l.bucket.GetDomain(l.account.ID, "test.cabal.io")
+	require.NoError(t, err)
+	require.Equal(t, domain.Issued, d.State)
+
+	a := d.Authorization("test.cabal.io")
+	require.True(t, a.Valid())
+	require.Equal(t, "dns-01", a.ChallengeType)
+	require.Equal(t, []string{"http-01"}, a.FailedChallengeTypes)
+
+	var fallback bool
+	for _, h := range d.History {
+		if h.Cause == "falling back to the next challenge type" {
+			fallback = true
+			require.Contains(t, h.Error, "invalid names: test.cabal.io")
+		}
+	}
+	require.True(t, fallback)
+}
+
+func TestChallengeTypeFallbackExhausted(t *testing.T) {
+	l, cleanup := newLifecycle(t)
+	defer cleanup()
+
+	l.ca.rejectedType = map[string]bool{"http-01": true, "dns-01": true}
+	l.processor.propagated = func(fqdn, value string) (bool, error) {
+		return true, nil
+	}
+
+	err := l.queue.Publish(Creation, &CreateDomainPayload{
+		AccountID:      l.account.ID,
+		AccountToken:   l.account.Token,
+		DomainName:     "test.cabal.io",
+		ChallengeTypes: []string{"http-01", "dns-01"},
+	})
+	require.NoError(t, err)
+
+	for len(l.queue.messages) > 0 {
+		m := l.queue.messages[0]
+		l.queue.messages = l.queue.messages[1:]
+		if err := process(l.processor, m); err != nil {
+			require.EqualError(t, err, "authorization failed for domain: test.cabal.io - invalid names: test.cabal.io")
+			break
+		}
+	}
+
+	d, err := l.bucket.GetDomain(l.account.ID, "test.cabal.io")
+	require.NoError(t, err)
+	require.Equal(t, domain.Invalid, d.State)
+
+	a := d.Authorization("test.cabal.io")
+	require.Equal(t, acme.StatusInvalid, a.Status)
+	require.Equal(t, "dns-01", a.ChallengeType)
+	require.Equal(t, []string{"http-01"}, a.FailedChallengeTypes)
+}
+
+func TestChallengeTypePreflight(t *testing.T) {
+	l, cleanup := newLifecycle(t)
+	defer cleanup()
+
+	l.ca.unsupported = map[string]bool{"dns-01": true}
+
+	err := l.queue.Publish(Creation, &CreateDomainPayload{
+		AccountID:      l.account.ID,
+		AccountToken:   l.account.Token,
+		DomainName:     "test.cabal.io",
+		ChallengeTypes: []string{"dns-01", "http-01"},
+	})
+	require.NoError(t, err)
+
+	l.queue.drain(t, l.processor)
+
+	d, err := l.bucket.GetDomain(l.account.ID, "test.cabal.io")
+	require.NoError(t, err)
+	require.Equal(t, domain.Issued, d.State)
+	require.Equal(t, "http-01", d.Authorization("test.cabal.io").ChallengeType)
+	require.Empty(t, d.Authorization("test.cabal.io").FailedChallengeTypes)
+}
+
+func TestWildcardDomain(t *testing.T) {
+	l, cleanup := newLifecycle(t)
+	defer cleanup()
+
+	var checks []string
+	l.processor.propagated = func(fqdn, value string) (bool, error) {
+		checks = append(checks, fqdn)
+		return true, nil
+	}
+
+	err := l.queue.Publish(Creation, &CreateDomainPayload{
+		AccountID:      l.account.ID,
+		AccountToken:   l.account.Token,
+		DomainName:     "*.customer.cabal.io",
+		ChallengeTypes: []string{"http-01", "dns-01"},
+	})
+	require.NoError(t, err)
+
+	l.queue.drain(t, l.processor)
+	require.Equal(t, []string{"_acme-challenge.customer.cabal.io."}, checks)
+
+	d, err := l.bucket.GetDomain(l.account.ID, "*.customer.cabal.io")
+	require.NoError(t, err)
+	require.Equal(t, domain.Issued, d.State)
+	require.Equal(t, []string{"*.customer.cabal.io", "customer.cabal.io"}, d.SANNames())
+
+	// The base name is authorized with the preferred challenge.
+	require.Equal(t, "dns-01", d.Authorization("*.customer.cabal.io").ChallengeType)
+	require.Equal(t, "http-01", d.Authorization("customer.cabal.io").ChallengeType)
+}
+
+func TestModifyDomainAddNames(t *testing.T) {
+	l, cleanup := newLifecycle(t)
+	defer cleanup()
+
+	l.issue(t, "test.cabal.io")
+	l.ca.authorized = nil
+
+	err := l.queue.Publish(Modification, &ModifyDomainPayload{
+		AccountID:  l.account.ID,
+		DomainName: "test.cabal.io",
+		AddNames:   []string{"beta.cabal.io", "gamma.cabal.io"},
+	})
+	require.NoError(t, err)
+
+	// The certificate is still available while the new names are authorized.
+	l.queue.next(t, l.processor)
+
+	d, err := l.bucket.GetDomain(l.account.ID, "test.cabal.io")
+	require.NoError(t, err)
+	require.Equal(t, domain.Provisioning, d.State)
+	require.Empty(t, d.OrderURL)
+	require.NotNil(t, d.Certificate)
+
+	topics := l.queue.drain(t, l.processor)
+	require.Equal(t, []TopicType{
+		Authorization, // start the order
+		Authorization, // ready
+		Cleanup,
+		CertRequest,
+	}, topics)
+	// The CA reuses the authorization for test.cabal.io.
+	require.Equal(t, []string{"beta.cabal.io", "gamma.cabal.io"}, l.ca.authorized)
+
+	d, err = l.bucket.GetDomain(l.account.ID, "test.cabal.io")
+	require.NoError(t, err)
+	require.Equal(t, domain.Issued, d.State)
+	require.Equal(t, []string{"test.cabal.io", "beta.cabal.io", "gamma.cabal.io"}, d.SANNames())
+}
+
+func TestModifyDomainRemoveNames(t *testing.T) {
+	l, cleanup := newLifecycle(t)
+	defer cleanup()
+
+	l.issue(t, "test.cabal.io")
+
+	err := l.queue.Publish(Modification, &ModifyDomainPayload{
+		AccountID:  l.account.ID,
+		DomainName: "test.cabal.io",
+		AddNames:   []string{"beta.cabal.io"},
+	})
+	require.NoError(t, err)
+	l.queue.drain(t, l.processor)
+	l.ca.authorized = nil
+	l.bucket.states = nil
+
+	err = l.queue.Publish(Modification, &ModifyDomainPayload{
+		AccountID:   l.account.ID,
+		DomainName:  "test.cabal.io",
+		RemoveNames: []string{"beta.cabal.io"},
+	})
+	require.NoError(t, err)
+
+	topics := l.queue.drain(t, l.processor)
+	require.Equal(t, []TopicType{Modification, Authorization, Authorization, CertRequest}, topics)
+	require.Empty(t, l.ca.authorized)
+
+	// Removing names also goes through provisioning to order the new certificate.
+	require.Equal(t, []domain.State{domain.Provisioning, domain.Authorized, domain.Requesting, domain.Issued}, l.bucket.states)
+
+	d, err := l.bucket.GetDomain(l.account.ID, "test.cabal.io")
+	require.NoError(t, err)
+	require.Equal(t, domain.Issued, d.State)
+	require.Equal(t, []string{"test.cabal.io"}, d.SANNames())
+
+	// Removing a name that the domain doesn't have is a noop.
+	err = l.queue.Publish(Modification, &ModifyDomainPayload{
+		AccountID:   l.account.ID,
+		DomainName:  "test.cabal.io",
+		RemoveNames: []string{"beta.cabal.io"},
+	})
+	require.NoError(t, err)
+
+	topics = l.queue.drain(t, l.processor)
+	require.Equal(t, []TopicType{Modification}, topics)
+}
+
+func TestCleanupDomainRetry(t *testing.T) {
+	l, cleanup := newLifecycle(t)
+	defer cleanup()
+
+	d, err := domain.NewDomain(l.account, "test.cabal.io")
+	require.NoError(t, err)
+	d.Authorizations = []*domain.Authorization{
+		{Name: "test.cabal.io", Status: acme.StatusValid, ChallengeType: "dns-01"},
+		{Name: "www.test.cabal.io", Status: acme.StatusPending, ChallengeType: "dns-01"},
+	}
+	require.NoError(t, l.bucket.SaveDomain(d))
+
+	m := NewMessage(newDomainPayload(d))
+	m.Topic = Cleanup
+
+	l.ca.failCleanups = 1
+	err = process(l.processor, m)
+	require.EqualError(t, err, "error cleaning up challenge for domain: test.cabal.io: DNS provider unavailable")
+
+	d, err = l.bucket.GetDomain(l.account.ID, "test.cabal.io")
+	require.NoError(t, err)
+	require.False(t, d.Authorizations[0].CleanedUp)
+
+	// The broker delivers the message again. The pending authorization is kept.
+	require.NoError(t, process(l.processor, m))
+	require.Equal(t, []string{"test.cabal.io"}, l.ca.cleaned)
+
+	d, err = l.bucket.GetDomain(l.account.ID, "test.cabal.io")
+	require.NoError(t, err)
+	require.True(t, d.Authorizations[0].CleanedUp)
+	require.False(t, d.Authorizations[1].CleanedUp)
+}
+
+func TestCleanupDomainKeepsConcurrentChanges(t *testing.T) {
+	l, cleanup := newLifecycle(t)
+	defer cleanup()
+
+	d, err := domain.NewDomain(l.account, "test.cabal.io")
+	require.NoError(t, err)
+	d.State = domain.Provisioning
+	d.Authorizations = []*domain.Authorization{
+		{Name: "test.cabal.io", Status: acme.StatusValid, ChallengeType: "dns-01", PreparedAt: time.Now()},
+	}
+	require.NoError(t, l.bucket.SaveDomain(d))
+
+	m := NewMessage(newDomainPayload(d))
+	m.Topic = Cleanup
+
+	// The authorization step moves the domain forward while the challenge is cleaned up.
+	l.ca.onCleanup = func() {
+		d, err := l.bucket.GetDomain(l.account.ID, "test.cabal.io")
+		require.NoError(t, err)
+		require.NoError(t, d.TransitionTo(domain.Authorized, "domain authorized", m.JobUUID, nil))
+		require.NoError(t, l.bucket.SaveDomain(d))
+	}
+	require.NoError(t, process(l.processor, m))
+
+	d, err = l.bucket.GetDomain(l.account.ID, "test.cabal.io")
+	require.NoError(t, err)
+	require.Equal(t, domain.Authorized, d.State)
+	require.True(t, d.Authorizations[0].CleanedUp)
+}
+
+func TestModifyDomainNotIssued(t *testing.T) {
+	l, cleanup := newLifecycle(t)
+	defer cleanup()
+
+	err := l.processor.CreateDomain(NewMessage(&CreateDomainPayload{
+		AccountID:    l.account.ID,
+		AccountToken: l.account.Token,
+		DomainName:   "test.cabal.io",
+	}))
+	require.NoError(t, err)
+
+	err = l.processor.ModifyDomain(NewMessage(&ModifyDomainPayload{
+		AccountID:  l.account.ID,
+		DomainName: "test.cabal.io",
+		AddNames:   []string{"beta.cabal.io"},
+	}))
+	require.EqualError(t, err, "unable to modify domain test.cabal.io in state: pending")
+	require.True(t, IsPermanent(err))
+}
+
+func TestCancelDomain(t *testing.T) {
+	l, cleanup := newLifecycle(t)
+	defer cleanup()
+
+	l.ca.pendingPolls = 10
+	err := l.queue.Publish(Creation, &CreateDomainPayload{
+		AccountID:    l.account.ID,
+		AccountToken: l.account.Token,
+		DomainName:   "test.cabal.io",
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, Creation, l.queue.next(t, l.processor))
+	require.Equal(t, Validation, l.queue.next(t, l.processor))
+	require.Equal(t, Authorization, l.queue.next(t, l.processor))
+
+	err = l.queue.Publish(Cancellation, &DomainPayload{
+		AccountID:  l.account.ID,
+		DomainName: "test.cabal.io",
+	})
+	require.NoError(t, err)
+
+	topics := l.queue.drain(t, l.processor)
+	require.Equal(t, []TopicType{
+		Authorization, // pending
+		Cancellation,
+		Authorization, // cancelled, the polling stops
+	}, topics)
+
+	require.Equal(t, []string{"https://ca.example.com/authz/test.cabal.io"}, l.ca.deactivated)
+	require.Equal(t, []string{"test.cabal.io"}, l.ca.cleaned)
+
+	d, err := l.bucket.GetDomain(l.account.ID, "test.cabal.io")
+	require.NoError(t, err)
+	require.Equal(t, domain.Cancelled, d.State)
+	require.Empty(t, d.OrderURL)
+
+	// Cancelling the domain again is a noop.
+	err = l.processor.CancelDomain(NewMessage(&DomainPayload{
+		AccountID:  l.account.ID,
+		DomainName: "test.cabal.io",
+	}))
+	require.NoError(t, err)
+	require.Len(t, l.ca.cleaned, 1)
+}
+
+func TestRenewDomain(t *testing.T) {
+	l, cleanup := newLifecycle(t)
+	defer cleanup()
+
+	l.issue(t, "test.cabal.io")
+
+	d, err := l.bucket.GetDomain(l.account.ID, "test.cabal.io")
+	require.NoError(t, err)
+	notAfter := d.Certificate.NotAfter
+
+	renewal := &RenewDomainPayload{
+		AccountID:  l.account.ID,
+		DomainName: "test.cabal.io",
+		NotAfter:   notAfter,
+	}
+	require.NoError(t, l.queue.Publish(Renewal, renewal))
+
+	// The certificate is still available while it's renewed.
+	require.Equal(t, Renewal, l.queue.next(t, l.processor))
+	d, err = l.bucket.GetDomain(l.account.ID, "test.cabal.io")
+	require.NoError(t, err)
+	require.Equal(t, domain.Provisioning, d.State)
+	require.NotNil(t, d.Certificate)
+
+	topics := l.queue.drain(t, l.processor)
+	require.Equal(t, []TopicType{Authorization, Authorization, CertRequest}, topics)
+
+	d, err = l.bucket.GetDomain(l.account.ID, "test.cabal.io")
+	require.NoError(t, err)
+	require.Equal(t, domain.Issued, d.State)
+	require.True(t, d.Certificate.NotAfter.After(notAfter))
+
+	// A renewal for the replaced certificate is ignored.
+	require.NoError(t, l.queue.Publish(Renewal, renewal))
+	topics = l.queue.drain(t, l.processor)
+	require.Equal(t, []TopicType{Renewal}, topics)
+}
+
+func TestRenewDomainFailure(t *testing.T) {
+	l, cleanup := newLifecycle(t)
+	defer cleanup()
+
+	l.issue(t, "test.cabal.io")
+
+	d, err := l.bucket.GetDomain(l.account.ID, "test.cabal.io")
+	require.NoError(t, err)
+	notAfter := d.Certificate.NotAfter
+
+	renewal := &RenewDomainPayload{
+		AccountID:  l.account.ID,
+		DomainName: "test.cabal.io",
+		NotAfter:   notAfter,
+	}
+	require.NoError(t, l.queue.Publish(Renewal, renewal))
+
+	// The CA asks for a new challenge, and fails to validate it.
+	l.ca.rejected = map[string]bool{"test.cabal.io": true}
+	l.ca.authz["test.cabal.io"] = acme.StatusPending
+	require.Equal(t, Renewal, l.queue.next(t, l.processor))
+	require.Equal(t, Authorization, l.queue.next(t, l.processor))
+
+	// The failure doesn't retry the message, nor start another renewal.
+	require.Equal(t, Authorization, l.queue.next(t, l.processor))
+	require.Empty(t, l.queue.messages)
+
+	// The domain keeps its certificate, so the scheduler renews it again.
+	d, err = l.bucket.GetDomain(l.account.ID, "test.cabal.io")
+	require.NoError(t, err)
+	require.Equal(t, domain.Issued, d.State)
+	require.True(t, d.Certificate.NotAfter.Equal(notAfter))
+
+	last := d.History[len(d.History)-1]
+	require.Equal(t, domain.Provisioning, last.From)
+	require.Equal(t, "renewal failed, the current certificate is kept", last.Cause)
+	require.Contains(t, last.Error, "invalid names: test.cabal.io")
+
+	issued, err := l.bucket.ListDomains(domain.Issued)
+	require.NoError(t, err)
+	require.Len(t, issued, 1)
+
+	// The next renewal replaces the certificate.
+	l.ca.rejected = nil
+	require.NoError(t, l.queue.Publish(Renewal, renewal))
+	l.queue.drain(t, l.processor)
+
+	d, err = l.bucket.GetDomain(l.account.ID, "test.cabal.io")
+	require.NoError(t, err)
+	require.Equal(t, domain.Issued, d.State)
+	require.True(t, d.Certificate.NotAfter.After(notAfter))
+}
+
+func TestRevokeDomain(t *testing.T) {
+	l, cleanup := newLifecycle(t)
+	defer cleanup()
+
+	l.issue(t, "test.cabal.io")
+
+	revocation := &RevokeDomainPayload{
+		AccountID:         l.account.ID,
+		DomainName:        "test.cabal.io",
+		Reason:            int(acme.CRLReasonKeyCompromise),
+		UseCertificateKey: true,
+	}
+	require.NoError(t, l.queue.Publish(Revocation, revocation))
+	require.Equal(t, Revocation, l.queue.next(t, l.processor))
+
+	require.Equal(t, []acme.CRLReasonCode{acme.CRLReasonKeyCompromise}, l.ca.revoked)
+	require.Equal(t, []bool{true}, l.ca.revokedByKey)
+
+	d, err := l.bucket.GetDomain(l.account.ID, "test.cabal.io")
+	require.NoError(t, err)
+	require.Equal(t, domain.Revoked, d.State)
+	require.True(t, d.Certificate.Revoked())
+	require.Equal(t, int(acme.CRLReasonKeyCompromise), d.Certificate.RevocationReason)
+	require.Equal(t, "certificate revoked: key compromise", d.History[len(d.History)-1].Cause)
+
+	// Revoking the certificate again is a noop.
+	require.NoError(t, l.processor.RevokeDomain(NewMessage(revocation)))
+	require.Len(t, l.ca.revoked, 1)
+}
+
+func TestRevokeDomainErrors(t *testing.T) {
+	l, cleanup := newLifecycle(t)
+	defer cleanup()
+
+	err := l.processor.CreateDomain(NewMessage(&CreateDomainPayload{
+		AccountID:    l.account.ID,
+		AccountToken: l.account.Token,
+		DomainName:   "test.cabal.io",
+	}))
+	require.NoError(t, err)
+
+	err = l.processor.RevokeDomain(NewMessage(&RevokeDomainPayload{
+		AccountID:  l.account.ID,
+		DomainName: "test.cabal.io",
+		Reason:     int(acme.CRLReasonSuperseded),
+	}))
+	require.EqualError(t, err, "unable to revoke domain test.cabal.io in state: pending")
+	require.True(t, IsPermanent(err))
+
+	err = l.processor.RevokeDomain(NewMessage(&RevokeDomainPayload{
+		AccountID:  l.account.ID,
+		DomainName: "test.cabal.io",
+		Reason:     2,
+	}))
+	require.EqualError(t, err, "invalid revocation reason: 2")
+	require.Empty(t, l.ca.revoked)
+}
+
+func TestAuthzPollDelay(t *testing.T) {
+	require.Equal(t, 2*time.Second, authzPollDelay(0, 0))
+	require.Equal(t, 4*time.Second, authzPollDelay(1, 0))
+	require.Equal(t, 8*time.Second, authzPollDelay(2, 0))
+	require.Equal(t, authzPollMaxDelay, authzPollDelay(20, 0))
+	require.Equal(t, 30*time.Second, authzPollDelay(1, 30*time.Second))
+	require.Equal(t, 8*time.Second, authzPollDelay(2, time.Second))
+}
+
 func TestProcessor(t *testing.T) {
 	f, err := ioutil.TempFile("", "isard-")
 	require.NoError(t, err)
@@ -108,3 +1109,159 @@ func TestProcessor(t *testing.T) {
 
 	suite.Run(t, s)
 }
+
+func TestDNSPropagation(t *testing.T) {
+	l, cleanup := newLifecycle(t)
+	defer cleanup()
+
+	var checks []string
+	l.processor.propagated = func(fqdn, value string) (bool, error) {
+		checks = append(checks, fqdn+" "+value)
+		return len(checks) > 2, nil
+	}
+
+	err := l.queue.Publish(Creation, &CreateDomainPayload{
+		AccountID:     l.account.ID,
+		AccountToken:  l.account.Token,
+		DomainName:    "test.cabal.io",
+		ChallengeType: "dns-01",
+	})
+	require.NoError(t, err)
+
+	topics := l.queue.drain(t, l.processor)
+	require.Equal(t, []TopicType{
+		Creation,
+		Validation,
+		Authorization, // start the authorization, the record hasn't propagated
+		Authorization, // the record hasn't propagated
+		Authorization, // the record has propagated, accept the challenge
+		Authorization, // valid
+		Cleanup,
+		CertRequest,
+	}, topics)
+
+	require.Len(t, checks, 3)
+	require.Equal(t, "_acme-challenge.test.cabal.io. dns-token.record", checks[0])
+	require.Equal(t, []string{"test.cabal.io"}, l.ca.prepared)
+	require.Equal(t, []string{"test.cabal.io"}, l.ca.authorized)
+
+	d, err := l.bucket.GetDomain(l.account.ID, "test.cabal.io")
+	require.NoError(t, err)
+	require.Equal(t, domain.Issued, d.State)
+}
+
+func TestDNSPropagationTimeout(t *testing.T) {
+	l, cleanup := newLifecycle(t)
+	defer cleanup()
+
+	propagated := false
+	l.processor.propagated = func(fqdn, value string) (bool, error) {
+		return propagated, nil
+	}
+	l.processor.config.PropagationTimeout.Duration = time.Nanosecond
+
+	err := l.queue.Publish(Creation, &CreateDomainPayload{
+		AccountID:     l.account.ID,
+		AccountToken:  l.account.Token,
+		DomainName:    "test.cabal.io",
+		ChallengeType: "dns-01",
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, Creation, l.queue.next(t, l.processor))
+	require.Equal(t, Validation, l.queue.next(t, l.processor))
+
+	m := l.queue.messages[0]
+	l.queue.messages = nil
+
+	err = process(l.processor, m)
+	require.EqualError(t, err, "the DNS record for domain test.cabal.io didn't propagate in 1ns")
+	require.Equal(t, []string{"test.cabal.io"}, l.ca.cleaned)
+	require.Empty(t, l.ca.authorized)
+
+	d, err := l.bucket.GetDomain(l.account.ID, "test.cabal.io")
+	require.NoError(t, err)
+	require.True(t, d.Authorizations[0].CleanedUp)
+
+	// The broker delivers the message again, and the record is created again.
+	propagated = true
+	require.NoError(t, process(l.processor, m))
+	require.Equal(t, []string{"test.cabal.io", "test.cabal.io"}, l.ca.prepared)
+	require.Equal(t, []string{"test.cabal.io"}, l.ca.authorized)
+}
+
+func TestHTTPSelfCheck(t *testing.T) {
+	l, cleanup := newLifecycle(t)
+	defer cleanup()
+
+	checks := 0
+	l.processor.checkHTTPChallenge = func(hostname, path, expected string) error {
+		checks++
+		require.Equal(t, "test.cabal.io", hostname)
+		require.Equal(t, "/.well-known/acme-challenge/http-token", path)
+		require.Equal(t, "http-token.thumbprint", expected)
+
+		if checks > 2 {
+			return nil
+		}
+		return errors.New("invalid response requesting http-01 challenge: test.cabal.io - 404 Not Found")
+	}
+
+	topics := l.issue(t, "test.cabal.io")
+	require.Equal(t, []TopicType{
+		Creation,
+		Validation,
+		Authorization, // start the authorization, the self-check fails
+		Authorization, // the self-check fails again
+		Authorization, // the self-check passes, accept the challenge
+		Authorization, // valid
+		Cleanup,
+		CertRequest,
+	}, topics)
+	require.Equal(t, 3, checks)
+	require.Equal(t, []string{"test.cabal.io"}, l.ca.authorized)
+
+	d, err := l.bucket.GetDomain(l.account.ID, "test.cabal.io")
+	require.NoError(t, err)
+	require.Equal(t, domain.Issued, d.State)
+
+	var diagnostics []domain.Transition
+	for _, tr := range d.History {
+		if tr.Cause == "http-01 self-check failed" {
+			diagnostics = append(diagnostics, tr)
+		}
+	}
+	require.Len(t, diagnostics, 1)
+	require.Equal(t, domain.Provisioning, diagnostics[0].To)
+	require.Equal(t, "invalid response requesting http-01 challenge: test.cabal.io - 404 Not Found", diagnostics[0].Error)
+}
+
+func TestHTTPSelfCheckTimeout(t *testing.T) {
+	l, cleanup := newLifecycle(t)
+	defer cleanup()
+
+	l.processor.checkHTTPChallenge = func(hostname, path, expected string) error {
+		return errors.New("connection refused")
+	}
+	l.processor.config.SelfCheckTimeout.Duration = time.Nanosecond
+
+	err := l.queue.Publish(Creation, &CreateDomainPayload{
+		AccountID:    l.account.ID,
+		AccountToken: l.account.Token,
+		DomainName:   "test.cabal.io",
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, Creation, l.queue.next(t, l.processor))
+	require.Equal(t, Validation, l.queue.next(t, l.processor))
+
+	m := l.queue.messages[0]
+	err = process(l.processor, m)
+	require.EqualError(t, err, "the http-01 self-check for domain test.cabal.io didn't pass in 1ns: connection refused")
+	require.Empty(t, l.ca.authorized)
+
+	// Domains can skip the self-check.
+	l.processor.config.DisableSelfCheck = true
+	require.NoError(t, process(l.processor, m))
+	require.Equal(t, []string{"test.cabal.io"}, l.ca.authorized)
+}
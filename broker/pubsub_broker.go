@@ -1,7 +1,7 @@
 package broker
 
 import (
-	"encoding/json"
+	"sync"
 	"time"
 
 	"golang.org/x/net/context"
@@ -11,12 +11,31 @@ import (
 	"cloud.google.com/go/pubsub"
 )
 
+// pubsubRepublishDelay is how long the broker waits to publish
+// a delayed message again when PubSub rejects it.
+const pubsubRepublishDelay = 5 * time.Second
+
+// errPubSubBrokerClosed is returned when a message is delayed after closing the broker.
+var errPubSubBrokerClosed = errors.New("the PubSub broker is closed")
+
 // PubSubBroker implements broker.Broker using Google Cloud's PubSub as a backend.
+// PubSub doesn't support scheduled delivery, so messages received before they
+// are due are acknowledged and kept in a delayed queue in memory. They are
+// published again when they are due, or when the broker is closed.
+// The delayed queue is lost if the process stops without closing the broker.
 type PubSubBroker struct {
+	retrier
+	codec       Codec
 	client      *pubsub.Client
+	topic       *pubsub.Topic
+	deadLetters *pubsub.Topic
 	subs        *pubsub.Subscription
 	subsCancel  context.CancelFunc
 	subsContext context.Context
+
+	mu      sync.Mutex
+	delayed map[*Message]*time.Timer
+	closed  bool
 }
 
 // NewPubSubBroker initializes a new broker and stablish a
@@ -31,9 +50,11 @@ func NewPubSubBroker(projectID string) (*PubSubBroker, error) {
 
 	cctx, cancel := context.WithCancel(ctx)
 	b := &PubSubBroker{
+		codec:       JSONCodec{},
 		client:      client,
 		subsCancel:  cancel,
 		subsContext: cctx,
+		delayed:     map[*Message]*time.Timer{},
 	}
 
 	if err := b.setupPubSub(); err != nil {
@@ -44,55 +65,91 @@ func NewPubSubBroker(projectID string) (*PubSubBroker, error) {
 }
 
 // Close cancels the subscription listener.
+// Delayed messages are published again right away,
+// so they are not lost when the process stops.
 func (b *PubSubBroker) Close() error {
 	b.subsCancel()
-	return nil
+
+	b.mu.Lock()
+	b.closed = true
+	var pending []*Message
+	for m, t := range b.delayed {
+		if t.Stop() {
+			pending = append(pending, m)
+		}
+		delete(b.delayed, m)
+	}
+	b.mu.Unlock()
+
+	var err error
+	for _, m := range pending {
+		if perr := b.publish(m); perr != nil && err == nil {
+			err = errors.Wrapf(perr, "error publishing delayed message %s", m.JobUUID)
+		}
+	}
+	return err
 }
 
 // Publish sends messages to the broker for a specific job.
+// Messages for the dead letter topic are sent to a separate
+// PubSub topic, and they are never sent to the processor.
 func (b *PubSubBroker) Publish(topic TopicType, payload interface{}) error {
-	m := NewMessage(payload)
-	m.Topic = topic
+	return b.PublishAt(topic, payload, time.Time{})
+}
 
-	d, err := json.Marshal(m)
-	if err != nil {
-		return err
-	}
+// PublishAfter sends messages to the broker for a specific job
+// that are not processed until the delay has passed.
+func (b *PubSubBroker) PublishAfter(topic TopicType, payload interface{}, delay time.Duration) error {
+	return b.PublishAt(topic, payload, time.Now().Add(delay))
+}
 
-	t := b.client.Topic(string(topic))
-	ctx := context.Background()
-	_, err = t.Publish(ctx, &pubsub.Message{
-		Data: d,
-	}).Get(ctx)
+// PublishAt sends messages to the broker for a specific job
+// that are not processed until the given time.
+// PubSub doesn't support scheduled delivery, so the subscriber
+// keeps the message in its delayed queue until it's due.
+func (b *PubSubBroker) PublishAt(topic TopicType, payload interface{}, t time.Time) error {
+	m := NewMessage(payload)
+	m.Topic = topic
+	m.NotBefore = t
 
-	return err
+	return b.publish(m)
 }
 
 // Subscribe receives messages from the broker to process them.
+// Failed messages are published again with their attempts history,
+// to be delivered after the backoff set in the topic's retry policy.
 func (b *PubSubBroker) Subscribe(processor Processor) error {
 	go func() {
 		b.subs.Receive(b.subsContext, func(ctx context.Context, msg *pubsub.Message) {
-			var umsg Message
-			if err := json.Unmarshal(msg.Data, &umsg); err != nil {
+			umsg, err := b.codec.Unmarshal(msg.Data)
+			if err != nil {
 				msg.Ack()
 				return
 			}
 
-			var err error
-			switch umsg.Topic {
-			case Creation:
-				err = processor.CreateDomain(&umsg)
-			case Modification:
-				err = processor.ModifyDomain(&umsg)
-			case Validation:
-				err = processor.ValidateDomain(&umsg)
-			case Authorization:
-				err = processor.AuthorizeDomain(&umsg)
-			case CertRequest:
-				err = processor.RequestDomainCertificate(&umsg)
+			// Don't hold messages that are not due yet, they would count
+			// against the outstanding messages until their ack deadline expires.
+			if time.Until(umsg.NotBefore) > 0 {
+				if err := b.delay(umsg); err != nil {
+					msg.Nack()
+					return
+				}
+				msg.Ack()
+				return
 			}
 
-			if err != nil {
+			dl, err := b.retrier.deliver(processor, umsg)
+			if err == nil {
+				msg.Ack()
+				return
+			}
+
+			if dl != nil {
+				umsg = NewMessageWithID(umsg.JobUUID, dl)
+				umsg.Topic = DeadLetter
+			}
+
+			if err := b.publish(umsg); err != nil {
 				msg.Nack()
 				return
 			}
@@ -103,6 +160,61 @@ func (b *PubSubBroker) Subscribe(processor Processor) error {
 	return nil
 }
 
+// delay keeps a message in the delayed queue
+// and publishes it again when it's due.
+func (b *PubSubBroker) delay(m *Message) error {
+	return b.schedule(m, time.Until(m.NotBefore))
+}
+
+func (b *PubSubBroker) schedule(m *Message, d time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return errPubSubBrokerClosed
+	}
+
+	b.delayed[m] = time.AfterFunc(d, func() {
+		b.release(m)
+	})
+	return nil
+}
+
+// release publishes a delayed message that is due.
+// It tries again later if PubSub rejects the message.
+func (b *PubSubBroker) release(m *Message) {
+	b.mu.Lock()
+	if _, ok := b.delayed[m]; !ok {
+		b.mu.Unlock()
+		return
+	}
+	delete(b.delayed, m)
+	b.mu.Unlock()
+
+	if err := b.publish(m); err != nil {
+		b.schedule(m, pubsubRepublishDelay)
+	}
+}
+
+func (b *PubSubBroker) publish(m *Message) error {
+	d, err := b.codec.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	t := b.topic
+	if m.Topic == DeadLetter {
+		t = b.deadLetters
+	}
+
+	ctx := context.Background()
+	_, err = t.Publish(ctx, &pubsub.Message{
+		Data: d,
+	}).Get(ctx)
+
+	return err
+}
+
 func (b *PubSubBroker) setupPubSub() error {
 	ctx := context.Background()
 	topic, err := b.client.CreateTopic(ctx, "isard-topic")
@@ -110,11 +222,18 @@ func (b *PubSubBroker) setupPubSub() error {
 		return err
 	}
 
+	deadLetters, err := b.client.CreateTopic(ctx, "isard-dead-letters")
+	if err != nil {
+		return err
+	}
+
 	s, err := b.client.CreateSubscription(ctx, "isard-subscription", topic, 20*time.Second, nil)
 	if err != nil {
 		return err
 	}
 
+	b.topic = topic
+	b.deadLetters = deadLetters
 	b.subs = s
 
 	return nil
@@ -0,0 +1,204 @@
+package broker
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/lost-mountain/isard/configuration"
+	"github.com/pkg/errors"
+)
+
+// RetryPolicy decides how many times a message is
+// delivered again when the processor fails to handle it,
+// and how long the broker waits between attempts.
+type RetryPolicy struct {
+	// MaxAttempts is the number of deliveries before the message
+	// is sent to the dead letter topic. Zero means no limit.
+	MaxAttempts int
+	// InitialBackoff is the delay before the second attempt.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between attempts.
+	MaxBackoff time.Duration
+	// Multiplier increases the delay after each attempt.
+	Multiplier float64
+	// Jitter is the fraction of the delay, between 0 and 1,
+	// that is randomized to spread retries over time.
+	Jitter float64
+}
+
+// DefaultRetryPolicy is the policy used for topics
+// that don't have a specific policy.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    10,
+	InitialBackoff: 5 * time.Second,
+	MaxBackoff:     10 * time.Minute,
+	Multiplier:     2,
+	Jitter:         0.2,
+}
+
+// Backoff returns the delay before delivering
+// a message again after a number of failed attempts.
+func (p *RetryPolicy) Backoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	d := float64(p.InitialBackoff) * math.Pow(p.Multiplier, float64(attempts-1))
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+
+	if p.Jitter > 0 {
+		d += d * p.Jitter * (2*rand.Float64() - 1)
+	}
+
+	return time.Duration(d)
+}
+
+// Exhausted returns true when a message
+// cannot be delivered anymore.
+func (p *RetryPolicy) Exhausted(attempts int) bool {
+	return p.MaxAttempts > 0 && attempts >= p.MaxAttempts
+}
+
+// Validate checks that the policy doesn't retry
+// failed messages forever without waiting between attempts.
+func (p *RetryPolicy) Validate() error {
+	if p.MaxAttempts < 0 {
+		return errors.Errorf("invalid retry policy, negative max attempts: %d", p.MaxAttempts)
+	}
+	if p.MaxAttempts == 0 && p.InitialBackoff <= 0 {
+		return errors.New("invalid retry policy, unlimited attempts require a backoff")
+	}
+	return nil
+}
+
+// NewRetryPolicy initializes a retry policy from its configuration.
+// Values missing in the configuration take the default policy's values.
+// It returns an error if the resulting policy is not valid.
+func NewRetryPolicy(c *configuration.RetryConfiguration) (*RetryPolicy, error) {
+	p := DefaultRetryPolicy
+
+	if c.MaxAttempts != 0 {
+		p.MaxAttempts = c.MaxAttempts
+	}
+	if c.InitialBackoff.Duration != 0 {
+		p.InitialBackoff = c.InitialBackoff.Duration
+	}
+	if c.MaxBackoff.Duration != 0 {
+		p.MaxBackoff = c.MaxBackoff.Duration
+	}
+	if c.Multiplier != 0 {
+		p.Multiplier = c.Multiplier
+	}
+	if c.Jitter != 0 {
+		p.Jitter = c.Jitter
+	}
+
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// permanentError marks errors that delivering
+// the message again cannot fix.
+type permanentError struct {
+	error
+}
+
+// Cause returns the original error.
+func (e *permanentError) Cause() error {
+	return e.error
+}
+
+// Permanent marks an error as permanent. Brokers send messages that fail
+// with permanent errors to the dead letter topic without retrying them.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err}
+}
+
+// IsPermanent checks if an error, or any of its causes,
+// has been marked as permanent.
+func IsPermanent(err error) bool {
+	type causer interface {
+		Cause() error
+	}
+
+	for err != nil {
+		if _, ok := err.(*permanentError); ok {
+			return true
+		}
+
+		c, ok := err.(causer)
+		if !ok {
+			return false
+		}
+		err = c.Cause()
+	}
+	return false
+}
+
+// retrier keeps the retry policies for each topic.
+// Brokers embed it to decide what to do with failed messages.
+type retrier struct {
+	mu       sync.RWMutex
+	policies map[TopicType]*RetryPolicy
+}
+
+// SetRetryPolicy changes the policy applied to the messages of a topic.
+// Invalid policies are ignored, the topic keeps its current policy.
+func (r *retrier) SetRetryPolicy(topic TopicType, policy *RetryPolicy) {
+	if policy.Validate() != nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.policies == nil {
+		r.policies = map[TopicType]*RetryPolicy{}
+	}
+	r.policies[topic] = policy
+}
+
+func (r *retrier) policy(topic TopicType) *RetryPolicy {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if p, ok := r.policies[topic]; ok {
+		return p
+	}
+	return &DefaultRetryPolicy
+}
+
+// deliver sends a message to the processor and records the attempt in the message.
+// When the processor fails, it schedules the next attempt in the message,
+// or returns a dead letter when the message has run out of attempts
+// or the error is permanent. It returns the processor's error.
+func (r *retrier) deliver(processor Processor, m *Message) (*DeadLetterPayload, error) {
+	m.Attempts++
+
+	err := process(processor, m)
+	if err == nil {
+		return nil, nil
+	}
+
+	m.recordError(err)
+
+	p := r.policy(m.Topic)
+	if p.Exhausted(m.Attempts) || IsPermanent(err) {
+		return &DeadLetterPayload{
+			Message: m,
+			Error:   err.Error(),
+		}, err
+	}
+
+	m.NotBefore = time.Now().Add(p.Backoff(m.Attempts))
+	return nil, err
+}
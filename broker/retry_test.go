@@ -0,0 +1,86 @@
+package broker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lost-mountain/isard/configuration"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := &RetryPolicy{
+		InitialBackoff: time.Second,
+		MaxBackoff:     10 * time.Second,
+		Multiplier:     2,
+	}
+
+	require.Equal(t, time.Second, p.Backoff(1))
+	require.Equal(t, 2*time.Second, p.Backoff(2))
+	require.Equal(t, 4*time.Second, p.Backoff(3))
+	require.Equal(t, 8*time.Second, p.Backoff(4))
+	require.Equal(t, 10*time.Second, p.Backoff(5))
+}
+
+func TestRetryPolicyBackoffJitter(t *testing.T) {
+	p := &RetryPolicy{
+		InitialBackoff: 10 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.5,
+	}
+
+	for i := 0; i < 100; i++ {
+		d := p.Backoff(1)
+		require.True(t, d >= 5*time.Second && d <= 15*time.Second, "backoff out of range: %s", d)
+	}
+}
+
+func TestRetryPolicyExhausted(t *testing.T) {
+	p := &RetryPolicy{MaxAttempts: 3}
+	require.False(t, p.Exhausted(2))
+	require.True(t, p.Exhausted(3))
+
+	p = &RetryPolicy{}
+	require.False(t, p.Exhausted(1000))
+}
+
+func TestNewRetryPolicy(t *testing.T) {
+	c := &configuration.RetryConfiguration{MaxAttempts: 3}
+	c.InitialBackoff.Duration = time.Minute
+
+	p, err := NewRetryPolicy(c)
+	require.NoError(t, err)
+	require.Equal(t, 3, p.MaxAttempts)
+	require.Equal(t, time.Minute, p.InitialBackoff)
+	require.Equal(t, DefaultRetryPolicy.MaxBackoff, p.MaxBackoff)
+	require.Equal(t, DefaultRetryPolicy.Multiplier, p.Multiplier)
+	require.Equal(t, DefaultRetryPolicy.Jitter, p.Jitter)
+}
+
+func TestRetryPolicyValidate(t *testing.T) {
+	require.NoError(t, (&RetryPolicy{MaxAttempts: 3}).Validate())
+	require.NoError(t, (&RetryPolicy{InitialBackoff: time.Second}).Validate())
+	require.EqualError(t, (&RetryPolicy{}).Validate(), "invalid retry policy, unlimited attempts require a backoff")
+	require.Error(t, (&RetryPolicy{MaxAttempts: -1, InitialBackoff: time.Second}).Validate())
+
+	c := &configuration.RetryConfiguration{MaxAttempts: -1}
+	_, err := NewRetryPolicy(c)
+	require.Error(t, err)
+
+	r := &retrier{}
+	r.SetRetryPolicy(Creation, &RetryPolicy{})
+	require.Equal(t, &DefaultRetryPolicy, r.policy(Creation))
+}
+
+func TestIsPermanent(t *testing.T) {
+	err := errors.New("invalid payload")
+	require.False(t, IsPermanent(err))
+	require.False(t, IsPermanent(nil))
+	require.Nil(t, Permanent(nil))
+
+	perr := Permanent(err)
+	require.True(t, IsPermanent(perr))
+	require.Equal(t, "invalid payload", perr.Error())
+	require.True(t, IsPermanent(errors.Wrap(perr, "error creating domain")))
+}
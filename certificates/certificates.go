@@ -7,8 +7,12 @@ import (
 	"crypto/ecdsa"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"net/http"
 	"time"
 
 	"github.com/lost-mountain/isard/account"
@@ -20,12 +24,23 @@ import (
 	"golang.org/x/crypto/acme"
 )
 
+// finalizeTimeout is how long the client waits for the CA
+// to issue a certificate after finalizing its order.
+// Orders that take longer are requested again later.
+const finalizeTimeout = 10 * time.Second
+
+// ErrOrderProcessing is an error returned when the CA
+// has not issued the certificate of a finalized order yet.
+var ErrOrderProcessing = errors.New("the CA is processing the order")
+
 // Client uses an account to negotiate
 // certificate operations with an ACME service.
 type Client struct {
-	account   *account.Account
-	client    *acme.Client
-	ns1ApiKey string
+	account     *account.Account
+	client      *acme.Client
+	dnsProvider challenges.DNSProvider
+	retryAfter  *retryAfterTransport
+	renewalInfo string // renewal information endpoint, discovered from the directory
 }
 
 // AcceptChallenge sends the request to the ACME service to accept a challenge.
@@ -40,44 +55,118 @@ func (c *Client) AcceptChallenge(d *domain.Domain, chal *acme.Challenge) (*acme.
 }
 
 // AuthorizeDomain initiates a domain name registration
-// by sending the initial authorization.
-func (c *Client) AuthorizeDomain(d *domain.Domain) (*acme.Authorization, error) {
-	return c.client.Authorize(context.Background(), d.Name)
+// by creating a new order for all the domain's names.
+// The CA reuses the authorizations that are still valid,
+// so only the names that are not authorized need new challenges.
+func (c *Client) AuthorizeDomain(d *domain.Domain) (*acme.Order, error) {
+	o, err := c.client.AuthorizeOrder(context.Background(), acme.DomainIDs(d.SANNames()...))
+	if err != nil {
+		return nil, errors.Wrapf(err, "error creating order for domain: %s", d.Name)
+	}
+	return o, nil
 }
 
-// GetAuthorization requests an authorization object that
-// has already been issued.
-func (c *Client) GetAuthorization(d *domain.Domain) (*acme.Authorization, error) {
-	return c.client.GetAuthorization(context.Background(), d.AuthorizationURL)
+// DeactivateAuthorization relinquishes an authorization,
+// so the CA stops waiting for its challenge to be completed.
+func (c *Client) DeactivateAuthorization(url string) error {
+	err := c.client.RevokeAuthorization(context.Background(), url)
+	return errors.Wrapf(err, "error deactivating authorization: %s", url)
 }
 
-// PrepareChallenge uses a challenge resolver to prepare a challenge.
-func (c *Client) PrepareChallenge(d *domain.Domain, chal *acme.Challenge) (*domain.Domain, error) {
-	var res challenges.Resolver
-	switch chal.Type {
-	case "dns-01":
-		res = challenges.NewDNSResolver(c.ns1ApiKey, c.client)
-	case "http-01":
-		res = challenges.NewHTTPResolver(c.client)
-	default:
-		return nil, errors.Errorf("unsupported ACME challenge: %s", chal.Type)
+// CleanupChallenge removes the resources created
+// to resolve the challenge of an authorization.
+func (c *Client) CleanupChallenge(a *domain.Authorization) error {
+	res, err := c.resolver(a.ChallengeType)
+	if err != nil {
+		return err
 	}
+	return res.Cleanup(a)
+}
 
-	d, err := res.Resolve(d, chal)
+// GetAuthorization requests one of the
+// authorization objects in an order.
+func (c *Client) GetAuthorization(url string) (*acme.Authorization, error) {
+	authz, err := c.client.GetAuthorization(context.Background(), url)
 	if err != nil {
-		return nil, err
+		return nil, errors.Wrapf(err, "error retrieving authorization: %s", url)
+	}
+	return authz, nil
+}
+
+// GetOrder requests the domain's current order.
+func (c *Client) GetOrder(d *domain.Domain) (*acme.Order, error) {
+	o, err := c.client.GetOrder(context.Background(), d.OrderURL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error retrieving order for domain: %s", d.Name)
+	}
+	return o, nil
+}
+
+// OrderRetryAfter returns how long the CA asked to wait
+// before requesting the domain's order again.
+// It returns zero if the CA didn't send a Retry-After header.
+func (c *Client) OrderRetryAfter(d *domain.Domain) time.Duration {
+	return c.retryAfter.retryAfter(d.OrderURL)
+}
+
+// PrepareChallenge uses a challenge resolver to prepare
+// the challenge of one of the domain's authorizations.
+func (c *Client) PrepareChallenge(a *domain.Authorization, chal *acme.Challenge) error {
+	res, err := c.resolver(chal.Type)
+	if err != nil {
+		return err
 	}
-	return d, nil
+
+	if err := res.Resolve(a, chal); err != nil {
+		return err
+	}
+
+	a.ChallengeType = chal.Type
+	a.ChallengeURL = chal.URI
+	return nil
 }
 
-// RequestCertificate retrieves a certificate once the challenge has been completed
-// and the authorization is valid.
+// RequestCertificate checks the state of the domain's order and
+// retrieves its certificate. It finalizes the order once all its
+// authorizations are valid, and fetches the certificate if the order
+// was already finalized. It returns ErrOrderProcessing while the
+// CA is issuing the certificate, so it can be requested again later.
+// The certificate request is signed with its own private key,
+// never with the account key. The key is kept in the domain
+// until the certificate is issued.
 func (c *Client) RequestCertificate(d *domain.Domain) (*cryptopolis.Certificate, error) {
-	pk, err := d.Account.PrivateKey()
+	ctx := context.Background()
+	o, err := c.client.GetOrder(ctx, d.OrderURL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error requesting certificate for domain: %s", d.Name)
+	}
+
+	switch o.Status {
+	case acme.StatusReady:
+		return c.finalizeOrder(ctx, d, o)
+	case acme.StatusProcessing:
+		return nil, ErrOrderProcessing
+	case acme.StatusValid:
+		return c.fetchCertificate(ctx, d, o)
+	default:
+		return nil, errors.Errorf("error requesting certificate for domain: %s - order %s", d.Name, o.Status)
+	}
+}
+
+// finalizeOrder sends the certificate request to the CA
+// and waits a short time for the certificate to be issued.
+func (c *Client) finalizeOrder(ctx context.Context, d *domain.Domain, o *acme.Order) (*cryptopolis.Certificate, error) {
+	pk, err := certificateKey(d)
 	if err != nil {
 		return nil, errors.Wrapf(err, "error requesting certificate for domain: %s", d.Name)
 	}
 
+	var keyBuf bytes.Buffer
+	if err := cryptopolis.EncodeKeyPEM(&keyBuf, pk); err != nil {
+		return nil, errors.Wrapf(err, "error requesting certificate for domain: %s", d.Name)
+	}
+	d.PendingKey = keyBuf.Bytes()
+
 	req := &x509.CertificateRequest{
 		Subject:  pkix.Name{CommonName: d.Name},
 		DNSNames: d.SANNames(),
@@ -88,13 +177,121 @@ func (c *Client) RequestCertificate(d *domain.Domain) (*cryptopolis.Certificate,
 		return nil, errors.Wrapf(err, "error requesting certificate for domain: %s", d.Name)
 	}
 
-	ctx := context.Background()
-	der, _, err := c.client.CreateCert(ctx, cr, 0, true)
+	ctx, cancel := context.WithTimeout(ctx, finalizeTimeout)
+	defer cancel()
+
+	der, _, err := c.client.CreateOrderCert(ctx, o.FinalizeURL, cr, true)
 	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, ErrOrderProcessing
+		}
 		return nil, errors.Wrapf(err, "error requesting certificate for domain: %s", d.Name)
 	}
 
-	return validateCertificate(d.Name, pk, der)
+	return issuedCertificate(d, pk, der)
+}
+
+// fetchCertificate downloads the certificate of an order
+// that the CA issued after a previous request.
+func (c *Client) fetchCertificate(ctx context.Context, d *domain.Domain, o *acme.Order) (*cryptopolis.Certificate, error) {
+	if len(d.PendingKey) == 0 {
+		return nil, errors.Errorf("error requesting certificate for domain: %s - the certificate key is missing", d.Name)
+	}
+
+	pk, err := cryptopolis.ExtractPEMSigner(string(d.PendingKey))
+	if err != nil {
+		return nil, errors.Wrapf(err, "error decoding certificate key for domain: %s", d.Name)
+	}
+
+	der, err := c.client.FetchCert(ctx, o.CertURL, true)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error requesting certificate for domain: %s", d.Name)
+	}
+
+	return issuedCertificate(d, pk, der)
+}
+
+// issuedCertificate validates the certificate issued
+// for a domain and discards its pending key.
+func issuedCertificate(d *domain.Domain, pk crypto.Signer, der [][]byte) (*cryptopolis.Certificate, error) {
+	cert, err := validateCertificate(d.Name, pk, der)
+	if err != nil {
+		return nil, err
+	}
+
+	d.PendingKey = nil
+	return cert, nil
+}
+
+// certificateKey returns the private key for a domain's certificate.
+// It generates a new key, unless the domain reuses the key of
+// its current certificate and that key has the expected type.
+func certificateKey(d *domain.Domain) (crypto.Signer, error) {
+	kt := d.CertificateKeyType()
+	if d.ReuseKey && d.Certificate != nil && len(d.Certificate.Key) > 0 {
+		key, err := cryptopolis.ExtractPEMSigner(string(d.Certificate.Key))
+		if err != nil {
+			return nil, err
+		}
+
+		if t, err := cryptopolis.KeyTypeOf(key); err == nil && t == kt {
+			return key, nil
+		}
+	}
+
+	return cryptopolis.GenerateKey(kt)
+}
+
+// RevokeCertificate asks the CA to revoke the domain's certificate
+// with a CRL reason code. It authenticates the request with the
+// certificate's key when useCertificateKey is true, and with the
+// account key otherwise.
+func (c *Client) RevokeCertificate(d *domain.Domain, reason acme.CRLReasonCode, useCertificateKey bool) error {
+	if d.Certificate == nil {
+		return errors.Errorf("the certificate for domain %s has not been issued", d.Name)
+	}
+
+	block, _ := pem.Decode(d.Certificate.Cert)
+	if block == nil {
+		return errors.Errorf("error decoding certificate for domain: %s", d.Name)
+	}
+
+	var key crypto.Signer
+	if useCertificateKey {
+		var err error
+		key, err = cryptopolis.ExtractPEMSigner(string(d.Certificate.Key))
+		if err != nil {
+			return errors.Wrapf(err, "error decoding certificate key for domain: %s", d.Name)
+		}
+	}
+
+	err := c.client.RevokeCert(context.Background(), key, block.Bytes, reason)
+	return errors.Wrapf(err, "error revoking certificate for domain: %s", d.Name)
+}
+
+// SupportsChallenge returns an error when the client
+// cannot resolve a challenge type, i.e: dns-01 challenges
+// when there is no DNS provider configured.
+func (c *Client) SupportsChallenge(challengeType string) error {
+	_, err := c.resolver(challengeType)
+	return err
+}
+
+// resolver initializes the challenge resolver for a challenge type.
+func (c *Client) resolver(challengeType string) (challenges.Resolver, error) {
+	switch challengeType {
+	case "dns-01":
+		if c.dnsProvider == nil {
+			return nil, errors.New("there is no DNS provider configured to resolve dns-01 challenges")
+		}
+		return challenges.NewDNSResolver(c.dnsProvider, c.client), nil
+	case "http-01":
+		return challenges.NewHTTPResolver(c.client), nil
+	case "tls-alpn-01":
+		return challenges.NewTLSALPNResolver(c.client), nil
+	default:
+		return nil, errors.Errorf("unsupported ACME challenge: %s", challengeType)
+	}
 }
 
 // Register sends the account information to the ACME service.
@@ -112,31 +309,46 @@ func (c *Client) Register() error {
 // NewClient initializes a new certificate client
 // to handle ACME requests.
 func NewClient(a *account.Account) (*Client, error) {
-	return NewClientWithAPIKey(a, "")
+	return NewClientWithDNSProvider(a, nil)
 }
 
 // NewClientWithAPIKey initializes a new certificate client
 // to handle ACME requests. It uses the api key
-// to contact the DNS provider.
+// to contact NS1 as DNS provider.
 func NewClientWithAPIKey(a *account.Account, apiKey string) (*Client, error) {
+	if apiKey == "" {
+		return NewClientWithDNSProvider(a, nil)
+	}
+	return NewClientWithDNSProvider(a, challenges.NewNS1Provider(apiKey))
+}
+
+// NewClientWithDNSProvider initializes a new certificate client
+// to handle ACME requests. It uses the DNS provider
+// to resolve dns-01 challenges.
+func NewClientWithDNSProvider(a *account.Account, p challenges.DNSProvider) (*Client, error) {
 	pk, err := a.PrivateKey()
 	if err != nil {
 		return nil, err
 	}
 
+	t := newRetryAfterTransport()
 	c := &acme.Client{
 		Key:          pk,
 		DirectoryURL: a.DirectoryURL,
+		HTTPClient:   &http.Client{Transport: t},
 	}
 
 	return &Client{
-		account:   a,
-		ns1ApiKey: apiKey,
-		client:    c,
+		account:     a,
+		dnsProvider: p,
+		client:      c,
+		retryAfter:  t,
 	}, nil
 }
 
 // validateCertificate parses the certificate to ensure it's valid.
+// It stores the leaf and chain certificates in PEM format,
+// with the metadata extracted from the leaf certificate.
 // Extracted from acme/autocert:
 // https://github.com/golang/crypto/blob/9b1a210a06ea1176ec1f0a1ddf83ad7463b8ea3e/acme/autocert/autocert.go#L714
 func validateCertificate(domain string, key crypto.Signer, der [][]byte) (*cryptopolis.Certificate, error) {
@@ -171,66 +383,56 @@ func validateCertificate(domain string, key crypto.Signer, der [][]byte) (*crypt
 		return nil, errors.Wrapf(err, "invalid hostname for certificate: %s", domain)
 	}
 
-	cert := &cryptopolis.Certificate{}
-	var caBuf bytes.Buffer
-	for _, c := range x509Cert[1:] {
-		pubDer, err := x509.MarshalPKIXPublicKey(c.PublicKey)
-		if err != nil {
-			return nil, errors.Wrapf(err, "error marshaling certificate chain for domain: %s", domain)
-		}
-		if err := cryptopolis.EncodeCertPEM(&caBuf, pubDer); err != nil {
-			return nil, errors.Wrapf(err, "error marshaling certificate chain for domain: %s", domain)
-		}
-	}
-	cert.CA = caBuf.Bytes()
-
-	pubDer, err := x509.MarshalPKIXPublicKey(leaf.PublicKey)
-	if err != nil {
-		return nil, errors.Wrapf(err, "error marshaling public key for domain: %s", domain)
-	}
-
-	var pubBuf bytes.Buffer
-	if err := cryptopolis.EncodeCertPEM(&pubBuf, pubDer); err != nil {
-		return nil, errors.Wrapf(err, "error marshaling public key for domain: %s", domain)
-	}
-
-	cert.Cert = pubBuf.Bytes()
-
 	switch pubKey := leaf.PublicKey.(type) {
 	case *rsa.PublicKey:
 		prv, ok := key.(*rsa.PrivateKey)
-		if !ok {
-			return nil, errors.Errorf("mismatched public and private keys for certificate: %s", domain)
-		}
-
-		if pubKey.N.Cmp(prv.N) != 0 {
+		if !ok || pubKey.N.Cmp(prv.N) != 0 {
 			return nil, errors.Errorf("mismatched public and private keys for certificate: %s", domain)
 		}
-
-		var prvBuf bytes.Buffer
-		if err := cryptopolis.EncodeRSAKeyPEM(&prvBuf, prv); err != nil {
-			return nil, errors.Wrapf(err, "error marshaling private key for domain: %s", domain)
-		}
-
-		cert.Key = prvBuf.Bytes()
 	case *ecdsa.PublicKey:
 		prv, ok := key.(*ecdsa.PrivateKey)
-		if !ok {
-			return nil, errors.Errorf("mismatched public and private keys for certificate: %s", domain)
-		}
-		if pubKey.X.Cmp(prv.X) != 0 || pubKey.Y.Cmp(prv.Y) != 0 {
+		if !ok || pubKey.X.Cmp(prv.X) != 0 || pubKey.Y.Cmp(prv.Y) != 0 {
 			return nil, errors.Errorf("mismatched public and private keys for certificate: %s", domain)
 		}
+	default:
+		return nil, errors.Errorf("unsupported certificate type for domain: %s", domain)
+	}
+
+	keyType, err := cryptopolis.KeyTypeOf(key)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unsupported private key for domain: %s", domain)
+	}
+
+	var certBuf bytes.Buffer
+	if err := cryptopolis.EncodeCertPEM(&certBuf, leaf.Raw); err != nil {
+		return nil, errors.Wrapf(err, "error marshaling certificate for domain: %s", domain)
+	}
 
-		var prvBuf bytes.Buffer
-		if err := cryptopolis.EncodeECDSAKeyPEM(&prvBuf, prv); err != nil {
-			return nil, errors.Wrapf(err, "error marshaling private key for domain: %s", domain)
+	var caBuf bytes.Buffer
+	for _, c := range x509Cert[1:] {
+		if err := cryptopolis.EncodeCertPEM(&caBuf, c.Raw); err != nil {
+			return nil, errors.Wrapf(err, "error marshaling certificate chain for domain: %s", domain)
 		}
+	}
 
-		cert.Key = prvBuf.Bytes()
-	default:
-		return nil, errors.Errorf("unsupported certificate type for domain: %s", domain)
+	var keyBuf bytes.Buffer
+	if err := cryptopolis.EncodeKeyPEM(&keyBuf, key); err != nil {
+		return nil, errors.Wrapf(err, "error marshaling private key for domain: %s", domain)
 	}
 
-	return cert, nil
+	fingerprint := sha256.Sum256(leaf.Raw)
+
+	return &cryptopolis.Certificate{
+		Cert:          certBuf.Bytes(),
+		Key:           keyBuf.Bytes(),
+		CA:            caBuf.Bytes(),
+		NotBefore:     leaf.NotBefore,
+		NotAfter:      leaf.NotAfter,
+		RenewalInfoID: renewalInfoID(leaf),
+		SerialNumber:  leaf.SerialNumber.Text(16),
+		Issuer:        leaf.Issuer.String(),
+		DNSNames:      leaf.DNSNames,
+		Fingerprint:   hex.EncodeToString(fingerprint[:]),
+		KeyType:       keyType,
+	}, nil
 }
@@ -1,8 +1,17 @@
 package certificates
 
 import (
+	"bytes"
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
 	"fmt"
+	"math/big"
 	"net"
 	"net/http"
 	"net/http/httptest"
@@ -13,6 +22,7 @@ import (
 	"golang.org/x/crypto/acme"
 
 	"github.com/lost-mountain/isard/account"
+	"github.com/lost-mountain/isard/cryptopolis"
 	"github.com/lost-mountain/isard/domain"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
@@ -38,9 +48,10 @@ func (s testSuite) TestAuthorizeDomain() {
 	err = c.Register()
 	require.NoError(s.T(), err)
 
-	authz, err := c.AuthorizeDomain(d)
+	o, err := c.AuthorizeDomain(d)
 	require.NoError(s.T(), err)
-	require.NotNil(s.T(), authz.URI)
+	require.NotEmpty(s.T(), o.URI)
+	require.Len(s.T(), o.AuthzURLs, 1)
 }
 
 func (s testSuite) TestCompleteChallenge() {
@@ -56,7 +67,11 @@ func (s testSuite) TestCompleteChallenge() {
 	err = c.Register()
 	require.NoError(s.T(), err)
 
-	authz, err := c.AuthorizeDomain(d)
+	o, err := c.AuthorizeDomain(d)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), o.AuthzURLs, 1)
+
+	authz, err := c.GetAuthorization(o.AuthzURLs[0])
 	require.NoError(s.T(), err)
 
 	var chal *acme.Challenge
@@ -69,14 +84,15 @@ func (s testSuite) TestCompleteChallenge() {
 
 	require.NotNil(s.T(), chal)
 
-	d, err = c.PrepareChallenge(d, chal)
+	a := &domain.Authorization{Name: authz.Identifier.Value, URL: authz.URI}
+	err = c.PrepareChallenge(a, chal)
 	require.NoError(s.T(), err)
-	require.NotEmpty(s.T(), d.HTTP01ChallengePath)
-	require.NotEmpty(s.T(), d.HTTP01ChallengeResponse)
+	require.NotEmpty(s.T(), a.HTTP01ChallengePath)
+	require.NotEmpty(s.T(), a.HTTP01ChallengeResponse)
 
 	mux := http.NewServeMux()
-	mux.HandleFunc(d.HTTP01ChallengePath, func(w http.ResponseWriter, req *http.Request) {
-		fmt.Fprint(w, d.HTTP01ChallengeResponse)
+	mux.HandleFunc(a.HTTP01ChallengePath, func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, a.HTTP01ChallengeResponse)
 	})
 	ts := httptest.NewUnstartedServer(mux)
 	l, err := net.Listen("tcp", httpTestListenerAddr)
@@ -88,6 +104,9 @@ func (s testSuite) TestCompleteChallenge() {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	_, err = c.AcceptChallenge(d, chal)
+	require.NoError(s.T(), err)
+
 	_, err = c.client.WaitAuthorization(ctx, authz.URI)
 	require.NoError(s.T(), err)
 }
@@ -114,3 +133,192 @@ Set ISARD_TEST_ACME_DIRECTORY with the ACME test directory URL to enable them`)
 		directoryURL: directoryURL,
 	})
 }
+
+func TestCertificateKey(t *testing.T) {
+	a, err := account.NewAccount("david.calavera@gmail.com")
+	require.NoError(t, err)
+
+	d, err := domain.NewDomain(a, "test.cabal.io")
+	require.NoError(t, err)
+
+	key, err := certificateKey(d)
+	require.NoError(t, err)
+
+	kt, err := cryptopolis.KeyTypeOf(key)
+	require.NoError(t, err)
+	require.Equal(t, cryptopolis.DefaultKeyType, kt)
+
+	// The certificate key is never the account key.
+	ak, err := a.PrivateKey()
+	require.NoError(t, err)
+	require.NotEqual(t, ak.Public(), key.Public())
+
+	var buf bytes.Buffer
+	require.NoError(t, cryptopolis.EncodeKeyPEM(&buf, key))
+	d.Certificate = &cryptopolis.Certificate{Key: buf.Bytes()}
+
+	next, err := certificateKey(d)
+	require.NoError(t, err)
+	require.NotEqual(t, key.Public(), next.Public())
+
+	d.ReuseKey = true
+	next, err = certificateKey(d)
+	require.NoError(t, err)
+	require.Equal(t, key.Public(), next.Public())
+
+	// A key with a different type is not reused.
+	d.KeyType = cryptopolis.EC384
+	next, err = certificateKey(d)
+	require.NoError(t, err)
+	kt, err = cryptopolis.KeyTypeOf(next)
+	require.NoError(t, err)
+	require.Equal(t, cryptopolis.EC384, kt)
+}
+
+func TestValidateCertificate(t *testing.T) {
+	now := time.Now()
+
+	caKey, err := cryptopolis.GenerateKey(cryptopolis.EC256)
+	require.NoError(t, err)
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Isard Test CA"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		SubjectKeyId:          []byte{1, 2, 3, 4},
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, caKey.Public(), caKey)
+	require.NoError(t, err)
+
+	key, err := cryptopolis.GenerateKey(cryptopolis.EC256)
+	require.NoError(t, err)
+	leafTmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(0xcafe),
+		Subject:      pkix.Name{CommonName: "test.cabal.io"},
+		DNSNames:     []string{"test.cabal.io", "www.test.cabal.io"},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(time.Hour),
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leafTmpl, caTmpl, key.Public(), caKey)
+	require.NoError(t, err)
+
+	cert, err := validateCertificate("test.cabal.io", key, [][]byte{leafDER, caDER})
+	require.NoError(t, err)
+
+	block, rest := pem.Decode(cert.Cert)
+	require.Empty(t, rest)
+	require.Equal(t, "CERTIFICATE", block.Type)
+	require.Equal(t, leafDER, block.Bytes)
+
+	block, rest = pem.Decode(cert.CA)
+	require.Empty(t, rest)
+	require.Equal(t, caDER, block.Bytes)
+
+	_, err = tls.X509KeyPair(append(cert.Cert, cert.CA...), cert.Key)
+	require.NoError(t, err)
+
+	fingerprint := sha256.Sum256(leafDER)
+	require.Equal(t, "cafe", cert.SerialNumber)
+	require.Equal(t, "CN=Isard Test CA", cert.Issuer)
+	require.Equal(t, []string{"test.cabal.io", "www.test.cabal.io"}, cert.DNSNames)
+	require.Equal(t, hex.EncodeToString(fingerprint[:]), cert.Fingerprint)
+	require.Equal(t, cryptopolis.EC256, cert.KeyType)
+	require.NotEmpty(t, cert.RenewalInfoID)
+
+	other, err := cryptopolis.GenerateKey(cryptopolis.EC256)
+	require.NoError(t, err)
+	_, err = validateCertificate("test.cabal.io", other, [][]byte{leafDER, caDER})
+	require.EqualError(t, err, "mismatched public and private keys for certificate: test.cabal.io")
+}
+
+// newOrderDirectory starts a fake ACME directory
+// that serves an order and its certificate.
+func newOrderDirectory(t *testing.T, status string, chain [][]byte) *httptest.Server {
+	mux := http.NewServeMux()
+	ts := httptest.NewServer(mux)
+
+	mux.HandleFunc("/directory", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"newNonce": "%[1]s/new-nonce", "newOrder": "%[1]s/new-order"}`, ts.URL)
+	})
+	mux.HandleFunc("/new-nonce", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Replay-Nonce", "nonce")
+	})
+	mux.HandleFunc("/order", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Replay-Nonce", "nonce")
+		fmt.Fprintf(w, `{"status": %q, "finalize": "%[2]s/finalize", "certificate": "%[2]s/cert"}`, status, ts.URL)
+	})
+	mux.HandleFunc("/cert", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Replay-Nonce", "nonce")
+		w.Header().Set("Content-Type", "application/pem-certificate-chain")
+		for _, b := range chain {
+			require.NoError(t, cryptopolis.EncodeCertPEM(w, b))
+		}
+	})
+
+	return ts
+}
+
+func newOrderDomain(t *testing.T, serverURL string) (*Client, *domain.Domain) {
+	a, err := account.NewAccount("david.calavera@gmail.com")
+	require.NoError(t, err)
+	a.DirectoryURL = serverURL + "/directory"
+
+	c, err := NewClient(a)
+	require.NoError(t, err)
+	c.client.KID = acme.KeyID(serverURL + "/account")
+
+	d, err := domain.NewDomain(a, "test.cabal.io")
+	require.NoError(t, err)
+	d.OrderURL = serverURL + "/order"
+	return c, d
+}
+
+func TestRequestCertificateFromValidOrder(t *testing.T) {
+	now := time.Now()
+
+	key, err := cryptopolis.GenerateKey(cryptopolis.EC256)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test.cabal.io"},
+		DNSNames:     []string{"test.cabal.io", "www.test.cabal.io"},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	require.NoError(t, err)
+
+	ts := newOrderDirectory(t, acme.StatusValid, [][]byte{der})
+	defer ts.Close()
+
+	c, d := newOrderDomain(t, ts.URL)
+
+	// The key of the certificate request is lost.
+	_, err = c.RequestCertificate(d)
+	require.EqualError(t, err, "error requesting certificate for domain: test.cabal.io - the certificate key is missing")
+
+	var buf bytes.Buffer
+	require.NoError(t, cryptopolis.EncodeKeyPEM(&buf, key))
+	d.PendingKey = buf.Bytes()
+
+	cert, err := c.RequestCertificate(d)
+	require.NoError(t, err)
+	require.Equal(t, []string{"test.cabal.io", "www.test.cabal.io"}, cert.DNSNames)
+	require.Nil(t, d.PendingKey)
+
+	_, err = tls.X509KeyPair(cert.Cert, cert.Key)
+	require.NoError(t, err)
+}
+
+func TestRequestCertificateFromProcessingOrder(t *testing.T) {
+	ts := newOrderDirectory(t, acme.StatusProcessing, nil)
+	defer ts.Close()
+
+	c, d := newOrderDomain(t, ts.URL)
+
+	_, err := c.RequestCertificate(d)
+	require.Equal(t, ErrOrderProcessing, err)
+}
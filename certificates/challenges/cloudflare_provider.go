@@ -0,0 +1,206 @@
+package challenges
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/pkg/errors"
+)
+
+// cloudflareBaseURL is the address of Cloudflare's v4 api.
+const cloudflareBaseURL = "https://api.cloudflare.com/client/v4"
+
+func init() {
+	RegisterDNSProvider("cloudflare", func(options map[string]string) (DNSProvider, error) {
+		return NewCloudflareProvider(options)
+	})
+}
+
+// CloudflareProvider manages TXT records with the Cloudflare API.
+// It authenticates with an api token, or with the
+// account email and global api key.
+type CloudflareProvider struct {
+	baseURL  string // Cloudflare's api, the tests replace it with a fake server
+	apiToken string
+	email    string
+	apiKey   string
+	client   *http.Client
+}
+
+type cloudflareRecord struct {
+	ID      string `json:"id,omitempty"`
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Content string `json:"content"`
+	TTL     int    `json:"ttl"`
+}
+
+type cloudflareZone struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type cloudflareResponse struct {
+	Success bool `json:"success"`
+	Errors  []struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"errors"`
+	Result json.RawMessage `json:"result"`
+}
+
+// Present creates the TXT record in the domain zone.
+// It doesn't create the record again if it already
+// has the challenge value, like when a request is retried.
+func (p *CloudflareProvider) Present(domain, fqdn, value string) error {
+	zoneID, err := p.findZone(domain)
+	if err != nil {
+		return errors.Wrapf(err, "error getting the hosted zone for domain: %s", domain)
+	}
+
+	records, err := p.findRecords(zoneID, fqdn, value)
+	if err != nil {
+		return errors.Wrapf(err, "error finding DNS record for domain challenge: %s", domain)
+	}
+	if len(records) > 0 {
+		return nil
+	}
+
+	record := &cloudflareRecord{
+		Type:    "TXT",
+		Name:    strings.TrimSuffix(fqdn, "."),
+		Content: value,
+		TTL:     120,
+	}
+
+	err = p.do("POST", fmt.Sprintf("/zones/%s/dns_records", zoneID), record, nil)
+	return errors.Wrapf(err, "error creating DNS record for domain challenge: %s", domain)
+}
+
+// Cleanup removes the TXT record with the challenge value from the domain zone.
+func (p *CloudflareProvider) Cleanup(domain, fqdn, value string) error {
+	zoneID, err := p.findZone(domain)
+	if err != nil {
+		return errors.Wrapf(err, "error getting the hosted zone for domain: %s", domain)
+	}
+
+	records, err := p.findRecords(zoneID, fqdn, value)
+	if err != nil {
+		return errors.Wrapf(err, "error finding DNS record for domain challenge: %s", domain)
+	}
+
+	for _, r := range records {
+		err := p.do("DELETE", fmt.Sprintf("/zones/%s/dns_records/%s", zoneID, r.ID), nil, nil)
+		if err != nil {
+			return errors.Wrapf(err, "error removing DNS record for domain challenge: %s", domain)
+		}
+	}
+	return nil
+}
+
+// findRecords returns the TXT records in the zone
+// with the challenge value.
+func (p *CloudflareProvider) findRecords(zoneID, fqdn, value string) ([]*cloudflareRecord, error) {
+	q := url.Values{}
+	q.Set("type", "TXT")
+	q.Set("name", strings.TrimSuffix(fqdn, "."))
+	q.Set("content", value)
+
+	var records []*cloudflareRecord
+	err := p.do("GET", fmt.Sprintf("/zones/%s/dns_records?%s", zoneID, q.Encode()), nil, &records)
+	return records, err
+}
+
+// findZone walks up from the domain name until
+// it finds a zone in the Cloudflare account.
+func (p *CloudflareProvider) findZone(domain string) (string, error) {
+	labels := dns.SplitDomainName(domain)
+	for i := range labels {
+		name := strings.Join(labels[i:], ".")
+
+		var zones []*cloudflareZone
+		if err := p.do("GET", "/zones?name="+url.QueryEscape(name), nil, &zones); err != nil {
+			return "", err
+		}
+
+		for _, z := range zones {
+			if z.Name == name {
+				return z.ID, nil
+			}
+		}
+	}
+
+	return "", errors.Errorf("unable to find a Cloudflare zone for domain: %s", domain)
+}
+
+func (p *CloudflareProvider) do(method, path string, body, result interface{}) error {
+	var b bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&b).Encode(body); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(method, p.baseURL+path, &b)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if p.apiToken != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiToken)
+	} else {
+		req.Header.Set("X-Auth-Email", p.email)
+		req.Header.Set("X-Auth-Key", p.apiKey)
+	}
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	var r cloudflareResponse
+	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
+		return errors.Wrapf(err, "invalid Cloudflare response: %s", res.Status)
+	}
+
+	if !r.Success {
+		msgs := make([]string, 0, len(r.Errors))
+		for _, e := range r.Errors {
+			msgs = append(msgs, fmt.Sprintf("%d: %s", e.Code, e.Message))
+		}
+		return errors.Errorf("Cloudflare request failed: %s - %s", res.Status, strings.Join(msgs, ", "))
+	}
+
+	if result != nil {
+		return json.Unmarshal(r.Result, result)
+	}
+	return nil
+}
+
+// NewCloudflareProvider initializes a DNS provider
+// that uses Cloudflare's api. These are the options it accepts:
+//
+//	apiToken: api token with permission to edit the zone's DNS records.
+//	email, apiKey: account email and global api key, when there is no token.
+func NewCloudflareProvider(options map[string]string) (*CloudflareProvider, error) {
+	p := &CloudflareProvider{
+		baseURL:  cloudflareBaseURL,
+		apiToken: options["apiToken"],
+		email:    options["email"],
+		apiKey:   options["apiKey"],
+		client:   &http.Client{Timeout: time.Second * 10},
+	}
+
+	if p.apiToken == "" && (p.email == "" || p.apiKey == "") {
+		return nil, errors.New("the Cloudflare api token, or email and api key, are missing")
+	}
+	return p, nil
+}
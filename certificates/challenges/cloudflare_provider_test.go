@@ -0,0 +1,144 @@
+package challenges
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/lost-mountain/isard/domain"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCloudflare is a stand-in for the
+// zones and DNS records endpoints of Cloudflare's api.
+type fakeCloudflare struct {
+	sync.Mutex
+	zones   map[string]string // zone name to zone id
+	records map[string]*cloudflareRecord
+	nextID  int
+}
+
+func (f *fakeCloudflare) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer test-token" {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"errors":  []map[string]interface{}{{"code": 9109, "message": "Invalid access token"}},
+		})
+		return
+	}
+
+	var result interface{}
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	switch {
+	case r.Method == "GET" && len(parts) == 1:
+		zones := []*cloudflareZone{}
+		name := r.URL.Query().Get("name")
+		if id, ok := f.zones[name]; ok {
+			zones = append(zones, &cloudflareZone{ID: id, Name: name})
+		}
+		result = zones
+	case r.Method == "POST" && len(parts) == 3:
+		var rec cloudflareRecord
+		json.NewDecoder(r.Body).Decode(&rec)
+		f.nextID++
+		rec.ID = parts[1] + "-" + string(rune('0'+f.nextID))
+		f.records[rec.ID] = &rec
+		result = rec
+	case r.Method == "GET" && len(parts) == 3:
+		q := r.URL.Query()
+		records := []*cloudflareRecord{}
+		for _, rec := range f.records {
+			if rec.Type == q.Get("type") && rec.Name == q.Get("name") && rec.Content == q.Get("content") {
+				records = append(records, rec)
+			}
+		}
+		result = records
+	case r.Method == "DELETE" && len(parts) == 4:
+		delete(f.records, parts[3])
+		result = map[string]string{"id": parts[3]}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	b, _ := json.Marshal(result)
+	json.NewEncoder(w).Encode(&cloudflareResponse{Success: true, Result: b})
+}
+
+func newFakeCloudflare(t *testing.T) (*fakeCloudflare, *httptest.Server) {
+	f := &fakeCloudflare{
+		zones:   map[string]string{"example.com": "zone1"},
+		records: make(map[string]*cloudflareRecord),
+	}
+	ts := httptest.NewServer(f)
+	t.Cleanup(ts.Close)
+	return f, ts
+}
+
+func TestCloudflarePresentAndCleanup(t *testing.T) {
+	f, ts := newFakeCloudflare(t)
+
+	p, err := NewCloudflareProvider(map[string]string{"apiToken": "test-token"})
+	require.NoError(t, err)
+	p.baseURL = ts.URL
+
+	fqdn := domain.ChallengeRecordName("www.example.com")
+	require.NoError(t, p.Present("www.example.com", fqdn, "token-1"))
+	require.NoError(t, p.Present("www.example.com", fqdn, "token-2"))
+	require.Len(t, f.records, 2)
+
+	// Retried requests don't duplicate the record.
+	require.NoError(t, p.Present("www.example.com", fqdn, "token-2"))
+	require.Len(t, f.records, 2)
+
+	for _, r := range f.records {
+		require.Equal(t, "TXT", r.Type)
+		require.Equal(t, "_acme-challenge.www.example.com", r.Name)
+	}
+
+	require.NoError(t, p.Cleanup("www.example.com", fqdn, "token-1"))
+	require.Len(t, f.records, 1)
+	for _, r := range f.records {
+		require.Equal(t, "token-2", r.Content)
+	}
+}
+
+func TestCloudflareErrors(t *testing.T) {
+	_, ts := newFakeCloudflare(t)
+
+	p, err := NewCloudflareProvider(map[string]string{"apiToken": "test-token"})
+	require.NoError(t, err)
+	p.baseURL = ts.URL
+
+	err = p.Present("www.example.org", domain.ChallengeRecordName("www.example.org"), "token")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unable to find a Cloudflare zone")
+
+	p, err = NewCloudflareProvider(map[string]string{"apiToken": "wrong-token"})
+	require.NoError(t, err)
+	p.baseURL = ts.URL
+
+	err = p.Present("www.example.com", domain.ChallengeRecordName("www.example.com"), "token")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Invalid access token")
+
+	_, err = NewCloudflareProvider(map[string]string{"email": "admin@example.com"})
+	require.Error(t, err)
+}
+
+func TestCloudflareIgnoresBaseURLOption(t *testing.T) {
+	p, err := NewDNSProvider("cloudflare", map[string]string{
+		"apiToken": "test-token",
+		"baseURL":  "http://169.254.169.254",
+	})
+	require.NoError(t, err)
+	require.Equal(t, cloudflareBaseURL, p.(*CloudflareProvider).baseURL)
+}
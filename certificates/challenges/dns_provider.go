@@ -0,0 +1,64 @@
+package challenges
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// DNSProvider creates and removes the TXT records
+// that resolve dns-01 challenges.
+// The domain is the name being authorized, and
+// the fqdn is the fully qualified name of the record.
+type DNSProvider interface {
+	Present(domain, fqdn, value string) error
+	Cleanup(domain, fqdn, value string) error
+}
+
+// DNSProviderFactory initializes a DNS provider
+// with the options set in the configuration.
+type DNSProviderFactory func(options map[string]string) (DNSProvider, error)
+
+var (
+	dnsProvidersMu sync.RWMutex
+	dnsProviders   = map[string]DNSProviderFactory{}
+)
+
+// RegisterDNSProvider makes a DNS provider
+// available under a name in the configuration.
+func RegisterDNSProvider(name string, factory DNSProviderFactory) {
+	dnsProvidersMu.Lock()
+	dnsProviders[name] = factory
+	dnsProvidersMu.Unlock()
+}
+
+// NewDNSProvider initializes the DNS provider registered under a name.
+func NewDNSProvider(name string, options map[string]string) (DNSProvider, error) {
+	dnsProvidersMu.RLock()
+	factory, ok := dnsProviders[name]
+	dnsProvidersMu.RUnlock()
+
+	if !ok {
+		return nil, errors.Errorf("unknown DNS provider: %s", name)
+	}
+
+	p, err := factory(options)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error initializing DNS provider: %s", name)
+	}
+	return p, nil
+}
+
+// DNSProviders returns the names of the registered DNS providers.
+func DNSProviders() []string {
+	dnsProvidersMu.RLock()
+	defer dnsProvidersMu.RUnlock()
+
+	names := make([]string, 0, len(dnsProviders))
+	for n := range dnsProviders {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -15,14 +15,15 @@ import (
 type dnsTestSuite struct {
 	suite.Suite
 	resolver *DNSResolver
+	provider *NS1Provider
 }
 
 func (s *dnsTestSuite) TearDownSuite() {
-	s.resolver.ns1Client.Zones.Delete("test-dns-isard.cabal.io")
+	s.provider.client.Zones.Delete("test-dns-isard.cabal.io")
 }
 
 func (s *dnsTestSuite) TestResolveAndCleanup() {
-	d := &domain.Domain{
+	a := &domain.Authorization{
 		Name: "cabal.io",
 	}
 
@@ -31,15 +32,15 @@ func (s *dnsTestSuite) TestResolveAndCleanup() {
 		Token: "123==",
 	}
 
-	_, err := s.resolver.Resolve(d, chal)
+	err := s.resolver.Resolve(a, chal)
 	require.NoError(s.T(), err)
 
-	err = s.resolver.Cleanup(d)
+	err = s.resolver.Cleanup(a)
 	require.NoError(s.T(), err)
 }
 
 func (s *dnsTestSuite) TestResolveAndCleanupWithMissingZone() {
-	d := &domain.Domain{
+	a := &domain.Authorization{
 		Name: "test-dns-isard.cabal.io",
 	}
 
@@ -48,10 +49,10 @@ func (s *dnsTestSuite) TestResolveAndCleanupWithMissingZone() {
 		Token: "123==",
 	}
 
-	_, err := s.resolver.Resolve(d, chal)
+	err := s.resolver.Resolve(a, chal)
 	require.NoError(s.T(), err)
 
-	err = s.resolver.Cleanup(d)
+	err = s.resolver.Cleanup(a)
 	require.NoError(s.T(), err)
 }
 
@@ -72,7 +73,9 @@ Set ISARD_TEST_NS1_API_KEY with the NS1 api key to enable them`)
 		Key: pk,
 	}
 
+	p := NewNS1Provider(apiKey)
 	suite.Run(t, &dnsTestSuite{
-		resolver: NewDNSResolver(apiKey, c),
+		resolver: NewDNSResolver(p, c),
+		provider: p,
 	})
 }
@@ -12,21 +12,21 @@ type HTTPResolver struct {
 }
 
 // Cleanup is a NOOP for the HTTPResolver.
-func (r *HTTPResolver) Cleanup(d *domain.Domain) error {
+func (r *HTTPResolver) Cleanup(a *domain.Authorization) error {
 	return nil
 }
 
-// Resolve stores the HTTP path and response challenges in the domain.
+// Resolve stores the HTTP path and response challenges in the authorization.
 // So it can pass it along when the ACME verification is triggered.
-func (r *HTTPResolver) Resolve(d *domain.Domain, challenge *acme.Challenge) (*domain.Domain, error) {
-	d.HTTP01ChallengePath = r.acmeClient.HTTP01ChallengePath(challenge.Token)
+func (r *HTTPResolver) Resolve(a *domain.Authorization, challenge *acme.Challenge) error {
 	res, err := r.acmeClient.HTTP01ChallengeResponse(challenge.Token)
 	if err != nil {
-		return nil, errors.Wrapf(err, "error generating response for http-01 challenge: %s", d.Name)
+		return errors.Wrapf(err, "error generating response for http-01 challenge: %s", a.Name)
 	}
 
-	d.HTTP01ChallengeResponse = res
-	return d, nil
+	a.HTTP01ChallengePath = r.acmeClient.HTTP01ChallengePath(challenge.Token)
+	a.HTTP01ChallengeResponse = res
+	return nil
 }
 
 // NewHTTPResolver initializes a new http challenge resolver.
@@ -0,0 +1,151 @@
+package challenges
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/ns1/ns1-go.v2/rest"
+	"gopkg.in/ns1/ns1-go.v2/rest/model/dns"
+)
+
+func init() {
+	RegisterDNSProvider("ns1", func(options map[string]string) (DNSProvider, error) {
+		if options["apiKey"] == "" {
+			return nil, errors.New("the NS1 api key is missing")
+		}
+		return NewNS1Provider(options["apiKey"]), nil
+	})
+}
+
+// NS1Provider manages TXT records with the NS1 API.
+// It creates the domain's zone when it doesn't exist.
+type NS1Provider struct {
+	client *rest.Client
+}
+
+// Present adds the challenge value to the TXT record in the domain zone.
+// The record keeps the values of other challenges for the same name,
+// like the challenges for a name and its wildcard.
+func (p *NS1Provider) Present(domain, fqdn, value string) error {
+	zone, err := p.getHostedZone(domain)
+	if err != nil {
+		return errors.Wrapf(err, "error getting the hosted zone for domain: %s", domain)
+	}
+
+	record := &dns.Record{
+		Type:   "TXT",
+		TTL:    120,
+		Zone:   zone.Zone,
+		Domain: strings.TrimSuffix(fqdn, "."),
+		Answers: []*dns.Answer{
+			{Rdata: []string{value}},
+		},
+	}
+
+	_, err = p.client.Records.Create(record)
+	if err != rest.ErrRecordExists {
+		return errors.Wrapf(err, "error creating DNS record for domain challenge: %s", domain)
+	}
+
+	record, _, err = p.client.Records.Get(zone.Zone, strings.TrimSuffix(fqdn, "."), "TXT")
+	if err != nil {
+		return errors.Wrapf(err, "error getting DNS record for domain challenge: %s", domain)
+	}
+
+	if ns1AnswerIndex(record, value) >= 0 {
+		return nil
+	}
+
+	record.Answers = append(record.Answers, &dns.Answer{Rdata: []string{value}})
+	_, err = p.client.Records.Update(record)
+	return errors.Wrapf(err, "error updating DNS record for domain challenge: %s", domain)
+}
+
+// Cleanup removes the challenge value from the TXT record in the domain zone.
+// It removes the record when it doesn't have other values.
+func (p *NS1Provider) Cleanup(domain, fqdn, value string) error {
+	zone, err := p.getHostedZone(domain)
+	if err != nil {
+		return err
+	}
+
+	record, _, err := p.client.Records.Get(zone.Zone, strings.TrimSuffix(fqdn, "."), "TXT")
+	if err == rest.ErrRecordMissing {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "error getting DNS record for domain challenge: %s", domain)
+	}
+
+	i := ns1AnswerIndex(record, value)
+	if i < 0 {
+		return nil
+	}
+
+	if len(record.Answers) == 1 {
+		_, err = p.client.Records.Delete(zone.Zone, record.Domain, "TXT")
+		if err == rest.ErrRecordMissing {
+			return nil
+		}
+		return errors.Wrapf(err, "error removing DNS record for domain challenge: %s", domain)
+	}
+
+	record.Answers = append(record.Answers[:i], record.Answers[i+1:]...)
+	_, err = p.client.Records.Update(record)
+	return errors.Wrapf(err, "error updating DNS record for domain challenge: %s", domain)
+}
+
+// ns1AnswerIndex returns the position of the answer with
+// a challenge value in a TXT record, or -1 if it's not there.
+func ns1AnswerIndex(record *dns.Record, value string) int {
+	for i, a := range record.Answers {
+		if len(a.Rdata) == 1 && a.Rdata[0] == value {
+			return i
+		}
+	}
+	return -1
+}
+
+func (p *NS1Provider) getHostedZone(domain string) (*dns.Zone, error) {
+	zone, _, err := p.client.Zones.Get(domain)
+	if err != nil {
+		if err != rest.ErrZoneMissing {
+			return nil, err
+		}
+
+		_, err = p.client.Zones.Create(&dns.Zone{Zone: domain})
+		if err != nil {
+			return nil, err
+		}
+
+		zone, _, err = p.client.Zones.Get(domain)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return zone, nil
+}
+
+// NewNS1Provider initializes a DNS provider
+// that uses NS1's api with the given key.
+func NewNS1Provider(key string) *NS1Provider {
+	return newNS1Provider(key, "")
+}
+
+// newNS1Provider initializes a DNS provider that uses the NS1 api
+// in an endpoint, or in NS1's default endpoint when it's empty.
+// Only the tests change the endpoint.
+func newNS1Provider(key, endpoint string) *NS1Provider {
+	options := []func(*rest.Client){rest.SetAPIKey(key)}
+	if endpoint != "" {
+		options = append(options, rest.SetEndpoint(endpoint))
+	}
+
+	httpClient := &http.Client{Timeout: time.Second * 10}
+	return &NS1Provider{
+		client: rest.NewClient(httpClient, options...),
+	}
+}
@@ -0,0 +1,129 @@
+package challenges
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/lost-mountain/isard/domain"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/ns1/ns1-go.v2/rest/model/dns"
+)
+
+// fakeNS1 is a stand-in for the zones
+// and records endpoints of NS1's api.
+type fakeNS1 struct {
+	sync.Mutex
+	zones   map[string]bool
+	records map[string]*dns.Record // records by zone, domain and type
+}
+
+func (f *fakeNS1) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/"), "/"), "/")
+	if len(parts) < 2 || parts[0] != "zones" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if len(parts) == 2 {
+		if !f.zones[parts[1]] {
+			ns1Error(w, "zone not found")
+			return
+		}
+		json.NewEncoder(w).Encode(&dns.Zone{Zone: parts[1]})
+		return
+	}
+
+	key := strings.Join(parts[1:], "/")
+	rec := f.records[key]
+
+	switch r.Method {
+	case "GET":
+		if rec == nil {
+			ns1Error(w, "record not found")
+			return
+		}
+		json.NewEncoder(w).Encode(rec)
+	case "PUT", "POST":
+		if r.Method == "PUT" && rec != nil {
+			ns1Error(w, "record already exists")
+			return
+		}
+		if r.Method == "POST" && rec == nil {
+			ns1Error(w, "record not found")
+			return
+		}
+
+		var next dns.Record
+		json.NewDecoder(r.Body).Decode(&next)
+		f.records[key] = &next
+		json.NewEncoder(w).Encode(&next)
+	case "DELETE":
+		if rec == nil {
+			ns1Error(w, "record not found")
+			return
+		}
+		delete(f.records, key)
+		w.Write([]byte("{}"))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func ns1Error(w http.ResponseWriter, message string) {
+	w.WriteHeader(http.StatusNotFound)
+	json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
+
+// values returns the answers of a TXT record.
+func (f *fakeNS1) values(name string) []string {
+	f.Lock()
+	defer f.Unlock()
+
+	rec := f.records["example.com/"+name+"/TXT"]
+	if rec == nil {
+		return nil
+	}
+
+	var values []string
+	for _, a := range rec.Answers {
+		values = append(values, a.Rdata...)
+	}
+	return values
+}
+
+func TestNS1PresentAndCleanup(t *testing.T) {
+	f := &fakeNS1{
+		zones:   map[string]bool{"example.com": true},
+		records: make(map[string]*dns.Record),
+	}
+	ts := httptest.NewServer(f)
+	defer ts.Close()
+
+	p := newNS1Provider("test-key", ts.URL+"/v1/")
+
+	// A name and its wildcard are validated with the same record.
+	fqdn := domain.ChallengeRecordName("example.com")
+	name := strings.TrimSuffix(fqdn, ".")
+	require.NoError(t, p.Present("example.com", fqdn, "token-1"))
+	require.NoError(t, p.Present("example.com", fqdn, "token-2"))
+	require.Equal(t, []string{"token-1", "token-2"}, f.values(name))
+
+	// Retried requests don't duplicate the value.
+	require.NoError(t, p.Present("example.com", fqdn, "token-2"))
+	require.Equal(t, []string{"token-1", "token-2"}, f.values(name))
+
+	require.NoError(t, p.Cleanup("example.com", fqdn, "token-1"))
+	require.Equal(t, []string{"token-2"}, f.values(name))
+
+	require.NoError(t, p.Cleanup("example.com", fqdn, "token-2"))
+	require.Empty(t, f.records)
+
+	require.NoError(t, p.Cleanup("example.com", fqdn, "token-2"))
+}
@@ -6,7 +6,8 @@ import (
 )
 
 // Resolver decides how to act upon an ACME challenge.
+// Each of the domain's names is authorized with its own challenge.
 type Resolver interface {
-	Cleanup(d *domain.Domain) error
-	Resolve(d *domain.Domain, chal *acme.Challenge) (*domain.Domain, error)
+	Cleanup(a *domain.Authorization) error
+	Resolve(a *domain.Authorization, chal *acme.Challenge) error
 }
@@ -0,0 +1,178 @@
+package challenges
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/pkg/errors"
+)
+
+const (
+	// rfc2136DefaultTTL is the TTL of the TXT records when the configuration doesn't set one.
+	rfc2136DefaultTTL = 120
+	// rfc2136Timeout is how long the provider waits for the nameserver to answer.
+	rfc2136Timeout = 10 * time.Second
+	// tsigFudge is the number of seconds the TSIG signature is valid for.
+	tsigFudge = 300
+)
+
+func init() {
+	RegisterDNSProvider("rfc2136", func(options map[string]string) (DNSProvider, error) {
+		return NewRFC2136Provider(options)
+	})
+}
+
+// RFC2136Provider manages TXT records with
+// dynamic DNS updates, as described in RFC 2136.
+// The updates are signed with TSIG when a key is configured.
+type RFC2136Provider struct {
+	nameserver    string
+	zone          string
+	ttl           uint32
+	tsigKey       string
+	tsigSecret    string
+	tsigAlgorithm string
+}
+
+// Present adds the TXT record to the zone.
+func (p *RFC2136Provider) Present(domain, fqdn, value string) error {
+	err := p.update(fqdn, value, func(m *dns.Msg, rr []dns.RR) {
+		m.Insert(rr)
+	})
+	return errors.Wrapf(err, "error creating DNS record for domain challenge: %s", domain)
+}
+
+// Cleanup removes the TXT record from the zone.
+// Other records with the same name are kept,
+// so challenges for the same name don't interfere.
+func (p *RFC2136Provider) Cleanup(domain, fqdn, value string) error {
+	err := p.update(fqdn, value, func(m *dns.Msg, rr []dns.RR) {
+		m.Remove(rr)
+	})
+	return errors.Wrapf(err, "error removing DNS record for domain challenge: %s", domain)
+}
+
+func (p *RFC2136Provider) update(fqdn, value string, op func(*dns.Msg, []dns.RR)) error {
+	fqdn = dns.Fqdn(fqdn)
+
+	zone, err := p.findZone(fqdn)
+	if err != nil {
+		return err
+	}
+
+	rr := &dns.TXT{
+		Hdr: dns.RR_Header{Name: fqdn, Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: p.ttl},
+		Txt: []string{value},
+	}
+
+	m := new(dns.Msg)
+	m.SetUpdate(zone)
+	op(m, []dns.RR{rr})
+
+	r, err := p.exchange(m)
+	if err != nil {
+		return err
+	}
+
+	if r.Rcode != dns.RcodeSuccess {
+		return errors.Errorf("the nameserver rejected the update for zone %s: %s", zone, dns.RcodeToString[r.Rcode])
+	}
+	return nil
+}
+
+// findZone returns the configured zone, or asks
+// the nameserver for the closest zone that contains the record.
+func (p *RFC2136Provider) findZone(fqdn string) (string, error) {
+	if p.zone != "" {
+		return p.zone, nil
+	}
+
+	labels := dns.SplitDomainName(fqdn)
+	for i := range labels {
+		name := dns.Fqdn(strings.Join(labels[i:], "."))
+
+		m := new(dns.Msg)
+		m.SetQuestion(name, dns.TypeSOA)
+
+		r, err := p.exchange(m)
+		if err != nil {
+			return "", errors.Wrapf(err, "error finding the zone for record: %s", fqdn)
+		}
+
+		for _, a := range r.Answer {
+			if soa, ok := a.(*dns.SOA); ok && soa.Hdr.Name == name {
+				return name, nil
+			}
+		}
+	}
+
+	return "", errors.Errorf("unable to find the zone for record: %s", fqdn)
+}
+
+func (p *RFC2136Provider) exchange(m *dns.Msg) (*dns.Msg, error) {
+	c := &dns.Client{
+		Net:     "udp",
+		Timeout: rfc2136Timeout,
+	}
+
+	if p.tsigKey != "" {
+		c.TsigSecret = map[string]string{p.tsigKey: p.tsigSecret}
+		m.SetTsig(p.tsigKey, p.tsigAlgorithm, tsigFudge, time.Now().Unix())
+	}
+
+	r, _, err := c.Exchange(m, p.nameserver)
+	return r, err
+}
+
+// NewRFC2136Provider initializes a DNS provider that sends
+// dynamic updates to a nameserver. These are the options it accepts:
+//
+//	nameserver: address of the nameserver, the port defaults to 53.
+//	zone: zone to update, it's discovered from the nameserver when it's empty.
+//	ttl: TTL of the TXT records, in seconds.
+//	tsigKey, tsigSecret: name and base64 secret of the TSIG key.
+//	tsigAlgorithm: TSIG algorithm, it defaults to hmac-sha256.
+func NewRFC2136Provider(options map[string]string) (*RFC2136Provider, error) {
+	ns := options["nameserver"]
+	if ns == "" {
+		return nil, errors.New("the RFC 2136 nameserver is missing")
+	}
+	if _, _, err := net.SplitHostPort(ns); err != nil {
+		ns = net.JoinHostPort(ns, "53")
+	}
+
+	p := &RFC2136Provider{
+		nameserver:    ns,
+		ttl:           rfc2136DefaultTTL,
+		tsigAlgorithm: dns.HmacSHA256,
+	}
+
+	if z := options["zone"]; z != "" {
+		p.zone = dns.Fqdn(z)
+	}
+
+	if t := options["ttl"]; t != "" {
+		ttl, err := strconv.ParseUint(t, 10, 32)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid RFC 2136 ttl: %s", t)
+		}
+		p.ttl = uint32(ttl)
+	}
+
+	if k := options["tsigKey"]; k != "" {
+		if options["tsigSecret"] == "" {
+			return nil, errors.Errorf("the RFC 2136 secret for TSIG key %s is missing", k)
+		}
+		p.tsigKey = dns.Fqdn(k)
+		p.tsigSecret = options["tsigSecret"]
+	}
+
+	if a := options["tsigAlgorithm"]; a != "" {
+		p.tsigAlgorithm = dns.Fqdn(a)
+	}
+
+	return p, nil
+}
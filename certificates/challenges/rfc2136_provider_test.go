@@ -0,0 +1,184 @@
+package challenges
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lost-mountain/isard/domain"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testTSIGKey    = "isard."
+	testTSIGSecret = "aXNhcmQtdHNpZy1zZWNyZXQ="
+)
+
+// fakeNameserver stores the TXT records
+// sent in dynamic updates for a zone.
+type fakeNameserver struct {
+	sync.Mutex
+	zone    string
+	records map[string][]string
+	signed  bool
+}
+
+func (n *fakeNameserver) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+	n.Lock()
+	defer n.Unlock()
+
+	m := new(dns.Msg)
+	m.SetReply(r)
+
+	if r.IsTsig() != nil {
+		if w.TsigStatus() != nil {
+			m.Rcode = dns.RcodeNotAuth
+		} else {
+			n.signed = true
+		}
+		m.SetTsig(testTSIGKey, dns.HmacSHA256, tsigFudge, time.Now().Unix())
+	}
+
+	switch {
+	case m.Rcode != dns.RcodeSuccess:
+	case r.Opcode == dns.OpcodeUpdate:
+		if r.Question[0].Name != n.zone {
+			m.Rcode = dns.RcodeNotZone
+			break
+		}
+
+		for _, rr := range r.Ns {
+			txt := rr.(*dns.TXT)
+			switch rr.Header().Class {
+			case dns.ClassINET:
+				n.records[txt.Hdr.Name] = append(n.records[txt.Hdr.Name], txt.Txt...)
+			case dns.ClassNONE:
+				n.records[txt.Hdr.Name] = remove(n.records[txt.Hdr.Name], txt.Txt[0])
+			}
+		}
+	case r.Question[0].Qtype == dns.TypeSOA && r.Question[0].Name == n.zone:
+		m.Answer = append(m.Answer, &dns.SOA{
+			Hdr:     dns.RR_Header{Name: n.zone, Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: 60},
+			Ns:      "ns." + n.zone,
+			Mbox:    "hostmaster." + n.zone,
+			Serial:  1,
+			Refresh: 3600,
+			Retry:   600,
+			Expire:  86400,
+			Minttl:  60,
+		})
+	}
+
+	w.WriteMsg(m)
+}
+
+// txt returns the TXT records stored for a name,
+// and whether the updates were signed.
+func (n *fakeNameserver) txt(name string) ([]string, bool) {
+	n.Lock()
+	defer n.Unlock()
+	return append([]string(nil), n.records[name]...), n.signed
+}
+
+func remove(values []string, v string) []string {
+	var r []string
+	for _, o := range values {
+		if o != v {
+			r = append(r, o)
+		}
+	}
+	return r
+}
+
+func startNameserver(t *testing.T, zone string) (*fakeNameserver, string) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	n := &fakeNameserver{
+		zone:    zone,
+		records: make(map[string][]string),
+	}
+
+	started := make(chan struct{})
+	s := &dns.Server{
+		PacketConn:        pc,
+		Handler:           n,
+		TsigSecret:        map[string]string{testTSIGKey: testTSIGSecret},
+		NotifyStartedFunc: func() { close(started) },
+	}
+
+	go s.ActivateAndServe()
+	<-started
+	t.Cleanup(func() { s.Shutdown() })
+
+	return n, pc.LocalAddr().String()
+}
+
+func TestRFC2136PresentAndCleanup(t *testing.T) {
+	n, addr := startNameserver(t, "example.com.")
+
+	p, err := NewDNSProvider("rfc2136", map[string]string{
+		"nameserver": addr,
+		"tsigKey":    "isard",
+		"tsigSecret": testTSIGSecret,
+	})
+	require.NoError(t, err)
+
+	fqdn := domain.ChallengeRecordName("www.example.com")
+	require.NoError(t, p.Present("www.example.com", fqdn, "token-1"))
+	require.NoError(t, p.Present("*.www.example.com", fqdn, "token-2"))
+	records, signed := n.txt(fqdn)
+	require.Equal(t, []string{"token-1", "token-2"}, records)
+	require.True(t, signed)
+
+	require.NoError(t, p.Cleanup("www.example.com", fqdn, "token-1"))
+	records, _ = n.txt(fqdn)
+	require.Equal(t, []string{"token-2"}, records)
+}
+
+func TestRFC2136InvalidTSIG(t *testing.T) {
+	_, addr := startNameserver(t, "example.com.")
+
+	p, err := NewRFC2136Provider(map[string]string{
+		"nameserver": addr,
+		"zone":       "example.com",
+		"tsigKey":    "isard",
+		"tsigSecret": "d3Jvbmctc2VjcmV0",
+	})
+	require.NoError(t, err)
+
+	err = p.Present("www.example.com", domain.ChallengeRecordName("www.example.com"), "token")
+	require.Error(t, err)
+}
+
+func TestRFC2136UnknownZone(t *testing.T) {
+	_, addr := startNameserver(t, "example.com.")
+
+	p, err := NewRFC2136Provider(map[string]string{"nameserver": addr})
+	require.NoError(t, err)
+
+	err = p.Present("www.example.org", domain.ChallengeRecordName("www.example.org"), "token")
+	require.Error(t, err)
+}
+
+func TestNewRFC2136Provider(t *testing.T) {
+	_, err := NewDNSProvider("rfc2136", map[string]string{})
+	require.Error(t, err)
+
+	_, err = NewRFC2136Provider(map[string]string{"nameserver": "127.0.0.1", "tsigKey": "isard"})
+	require.Error(t, err)
+
+	_, err = NewRFC2136Provider(map[string]string{"nameserver": "127.0.0.1", "ttl": "soon"})
+	require.Error(t, err)
+
+	p, err := NewRFC2136Provider(map[string]string{"nameserver": "127.0.0.1", "zone": "example.com"})
+	require.NoError(t, err)
+	require.Equal(t, "127.0.0.1:53", p.nameserver)
+	require.Equal(t, "example.com.", p.zone)
+	require.Equal(t, uint32(rfc2136DefaultTTL), p.ttl)
+
+	_, err = NewDNSProvider("unknown", nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,335 @@
+package challenges
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/pkg/errors"
+)
+
+const (
+	// route53BaseURL is the address of the Route 53 api.
+	route53BaseURL = "https://route53.amazonaws.com"
+	// route53APIVersion is the version of the Route 53 api used in the requests.
+	route53APIVersion = "2013-04-01"
+	// route53Namespace is the XML namespace of the Route 53 requests.
+	route53Namespace = "https://route53.amazonaws.com/doc/2013-04-01/"
+	// route53DefaultRegion is the region used to sign the requests.
+	route53DefaultRegion = "us-east-1"
+	// route53TTL is the TTL of the TXT records.
+	route53TTL = 120
+)
+
+func init() {
+	RegisterDNSProvider("route53", func(options map[string]string) (DNSProvider, error) {
+		return NewRoute53Provider(options)
+	})
+}
+
+// Route53Provider manages TXT records with the AWS Route 53 API.
+// The requests are signed with AWS Signature Version 4.
+type Route53Provider struct {
+	baseURL         string // Route 53's api, the tests replace it with a fake server
+	region          string
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+	client          *http.Client
+	now             func() time.Time
+}
+
+type route53HostedZone struct {
+	ID          string `xml:"Id"`
+	Name        string `xml:"Name"`
+	PrivateZone bool   `xml:"Config>PrivateZone"`
+}
+
+type route53ResourceRecordSet struct {
+	Name   string   `xml:"Name"`
+	Type   string   `xml:"Type"`
+	TTL    int      `xml:"TTL"`
+	Values []string `xml:"ResourceRecords>ResourceRecord>Value"`
+}
+
+type route53Change struct {
+	Action string                    `xml:"Action"`
+	Set    *route53ResourceRecordSet `xml:"ResourceRecordSet"`
+}
+
+type route53ChangeRequest struct {
+	XMLName xml.Name         `xml:"ChangeResourceRecordSetsRequest"`
+	Xmlns   string           `xml:"xmlns,attr"`
+	Changes []*route53Change `xml:"ChangeBatch>Changes>Change"`
+}
+
+type route53Error struct {
+	Code    string `xml:"Error>Code"`
+	Message string `xml:"Error>Message"`
+}
+
+// Present adds the challenge value to the TXT record set.
+// Values from other challenges for the same name are kept.
+func (p *Route53Provider) Present(domain, fqdn, value string) error {
+	err := p.change(domain, fqdn, func(values []string) []string {
+		for _, v := range values {
+			if v == quoteTXT(value) {
+				return values
+			}
+		}
+		return append(values, quoteTXT(value))
+	})
+	return errors.Wrapf(err, "error creating DNS record for domain challenge: %s", domain)
+}
+
+// Cleanup removes the challenge value from the TXT record set,
+// and deletes the record set when there are no more values.
+func (p *Route53Provider) Cleanup(domain, fqdn, value string) error {
+	err := p.change(domain, fqdn, func(values []string) []string {
+		var r []string
+		for _, v := range values {
+			if v != quoteTXT(value) {
+				r = append(r, v)
+			}
+		}
+		return r
+	})
+	return errors.Wrapf(err, "error removing DNS record for domain challenge: %s", domain)
+}
+
+// change updates the values of the TXT record set with the update function.
+func (p *Route53Provider) change(domain, fqdn string, update func([]string) []string) error {
+	zoneID, err := p.findZone(domain)
+	if err != nil {
+		return err
+	}
+
+	fqdn = dns.Fqdn(fqdn)
+	current, err := p.recordSet(zoneID, fqdn)
+	if err != nil {
+		return err
+	}
+
+	var values []string
+	if current != nil {
+		values = current.Values
+	}
+	values = update(values)
+
+	var c *route53Change
+	switch {
+	case len(values) > 0:
+		c = &route53Change{
+			Action: "UPSERT",
+			Set:    &route53ResourceRecordSet{Name: fqdn, Type: "TXT", TTL: route53TTL, Values: values},
+		}
+	case current != nil:
+		c = &route53Change{Action: "DELETE", Set: current}
+	default:
+		return nil
+	}
+
+	body, err := xml.Marshal(&route53ChangeRequest{
+		Xmlns:   route53Namespace,
+		Changes: []*route53Change{c},
+	})
+	if err != nil {
+		return err
+	}
+
+	return p.do("POST", fmt.Sprintf("/%s/hostedzone/%s/rrset/", route53APIVersion, zoneID), nil, body, nil)
+}
+
+// recordSet returns the TXT record set for a name,
+// or nil if the zone doesn't have it.
+func (p *Route53Provider) recordSet(zoneID, fqdn string) (*route53ResourceRecordSet, error) {
+	q := url.Values{}
+	q.Set("name", fqdn)
+	q.Set("type", "TXT")
+	q.Set("maxitems", "1")
+
+	var res struct {
+		Sets []*route53ResourceRecordSet `xml:"ResourceRecordSets>ResourceRecordSet"`
+	}
+	if err := p.do("GET", fmt.Sprintf("/%s/hostedzone/%s/rrset", route53APIVersion, zoneID), q, nil, &res); err != nil {
+		return nil, err
+	}
+
+	for _, s := range res.Sets {
+		if s.Name == fqdn && s.Type == "TXT" {
+			return s, nil
+		}
+	}
+	return nil, nil
+}
+
+// findZone walks up from the domain name until
+// it finds a public hosted zone in the AWS account.
+func (p *Route53Provider) findZone(domain string) (string, error) {
+	labels := dns.SplitDomainName(domain)
+	for i := range labels {
+		name := dns.Fqdn(strings.Join(labels[i:], "."))
+
+		q := url.Values{}
+		q.Set("dnsname", name)
+		q.Set("maxitems", "1")
+
+		var res struct {
+			Zones []*route53HostedZone `xml:"HostedZones>HostedZone"`
+		}
+		if err := p.do("GET", fmt.Sprintf("/%s/hostedzonesbyname", route53APIVersion), q, nil, &res); err != nil {
+			return "", errors.Wrapf(err, "error getting the hosted zone for domain: %s", domain)
+		}
+
+		for _, z := range res.Zones {
+			if z.Name == name && !z.PrivateZone {
+				return strings.TrimPrefix(z.ID, "/hostedzone/"), nil
+			}
+		}
+	}
+
+	return "", errors.Errorf("unable to find a Route 53 hosted zone for domain: %s", domain)
+}
+
+func (p *Route53Provider) do(method, path string, query url.Values, body []byte, result interface{}) error {
+	u := p.baseURL + path
+	if len(query) > 0 {
+		u += "?" + canonicalQuery(query)
+	}
+
+	req, err := http.NewRequest(method, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/xml")
+	}
+	p.sign(req, body)
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode >= 300 {
+		var e route53Error
+		if err := xml.Unmarshal(b, &e); err != nil || e.Code == "" {
+			return errors.Errorf("Route 53 request failed: %s", res.Status)
+		}
+		return errors.Errorf("Route 53 request failed: %s - %s: %s", res.Status, e.Code, e.Message)
+	}
+
+	if result != nil {
+		return xml.Unmarshal(b, result)
+	}
+	return nil
+}
+
+// sign adds the AWS Signature Version 4 headers to a request.
+func (p *Route53Provider) sign(req *http.Request, body []byte) {
+	now := p.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if p.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", p.sessionToken)
+	}
+
+	headers := []string{"host", "x-amz-date"}
+	canonicalHeaders := "host:" + req.URL.Host + "\n" + "x-amz-date:" + amzDate + "\n"
+	if p.sessionToken != "" {
+		headers = append(headers, "x-amz-security-token")
+		canonicalHeaders += "x-amz-security-token:" + p.sessionToken + "\n"
+	}
+	signedHeaders := strings.Join(headers, ";")
+
+	payloadHash := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders,
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := strings.Join([]string{date, p.region, "route53", "aws4_request"}, "/")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(requestHash[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+p.secretAccessKey), date)
+	key = hmacSHA256(key, p.region)
+	key = hmacSHA256(key, "route53")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		p.accessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// canonicalQuery encodes the query parameters sorted
+// by name, with spaces escaped as AWS expects them.
+func canonicalQuery(q url.Values) string {
+	return strings.Replace(q.Encode(), "+", "%20", -1)
+}
+
+// quoteTXT quotes a TXT record value
+// the way Route 53 stores it.
+func quoteTXT(value string) string {
+	return `"` + value + `"`
+}
+
+// NewRoute53Provider initializes a DNS provider
+// that uses the AWS Route 53 api. These are the options it accepts:
+//
+//	accessKeyID, secretAccessKey: credentials of an IAM user that can change the zone's records.
+//	sessionToken: session token, when the credentials are temporary.
+//	region: region used to sign the requests, it defaults to us-east-1.
+func NewRoute53Provider(options map[string]string) (*Route53Provider, error) {
+	p := &Route53Provider{
+		baseURL:         route53BaseURL,
+		region:          options["region"],
+		accessKeyID:     options["accessKeyID"],
+		secretAccessKey: options["secretAccessKey"],
+		sessionToken:    options["sessionToken"],
+		client:          &http.Client{Timeout: time.Second * 10},
+		now:             time.Now,
+	}
+
+	if p.accessKeyID == "" || p.secretAccessKey == "" {
+		return nil, errors.New("the Route 53 access key id or secret access key are missing")
+	}
+
+	if p.region == "" {
+		p.region = route53DefaultRegion
+	}
+	return p, nil
+}
@@ -0,0 +1,188 @@
+package challenges
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lost-mountain/isard/domain"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRoute53 is a stand-in for the hosted zones
+// and record sets endpoints of the Route 53 api.
+type fakeRoute53 struct {
+	sync.Mutex
+	zones   []*route53HostedZone
+	records map[string]*route53ResourceRecordSet // record sets by zone id and name
+	changes []*route53Change
+}
+
+func (f *fakeRoute53) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKIDTEST/20171201/us-east-1/route53/aws4_request, SignedHeaders=host;x-amz-date, Signature=") ||
+		r.Header.Get("X-Amz-Date") != "20171201T120000Z" {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`<ErrorResponse><Error><Type>Sender</Type><Code>SignatureDoesNotMatch</Code><Message>invalid signature</Message></Error></ErrorResponse>`))
+		return
+	}
+
+	q := r.URL.Query()
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	switch {
+	case r.Method == "GET" && strings.HasSuffix(r.URL.Path, "/hostedzonesbyname"):
+		var res struct {
+			XMLName xml.Name             `xml:"ListHostedZonesByNameResponse"`
+			Zones   []*route53HostedZone `xml:"HostedZones>HostedZone"`
+		}
+		for _, z := range f.zones {
+			if z.Name == q.Get("dnsname") {
+				res.Zones = append(res.Zones, z)
+				break
+			}
+		}
+		xml.NewEncoder(w).Encode(&res)
+	case r.Method == "GET" && parts[len(parts)-1] == "rrset":
+		var res struct {
+			XMLName xml.Name                    `xml:"ListResourceRecordSetsResponse"`
+			Sets    []*route53ResourceRecordSet `xml:"ResourceRecordSets>ResourceRecordSet"`
+		}
+		if s, ok := f.records[parts[2]+q.Get("name")]; ok {
+			res.Sets = append(res.Sets, s)
+		}
+		xml.NewEncoder(w).Encode(&res)
+	case r.Method == "POST" && parts[len(parts)-1] == "rrset":
+		b, _ := ioutil.ReadAll(r.Body)
+		var req route53ChangeRequest
+		xml.Unmarshal(b, &req)
+
+		for _, c := range req.Changes {
+			f.changes = append(f.changes, c)
+			switch c.Action {
+			case "UPSERT":
+				f.records[parts[2]+c.Set.Name] = c.Set
+			case "DELETE":
+				delete(f.records, parts[2]+c.Set.Name)
+			}
+		}
+		w.Write([]byte(`<ChangeResourceRecordSetsResponse><ChangeInfo><Status>PENDING</Status></ChangeInfo></ChangeResourceRecordSetsResponse>`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newRoute53Provider(t *testing.T, options map[string]string) *Route53Provider {
+	p, err := NewRoute53Provider(options)
+	require.NoError(t, err)
+	p.now = func() time.Time {
+		return time.Date(2017, 12, 1, 12, 0, 0, 0, time.UTC)
+	}
+	return p
+}
+
+func TestRoute53PresentAndCleanup(t *testing.T) {
+	f := &fakeRoute53{
+		zones: []*route53HostedZone{
+			{ID: "/hostedzone/ZPRIVATE", Name: "internal.example.com.", PrivateZone: true},
+			{ID: "/hostedzone/Z1", Name: "example.com."},
+		},
+		records: make(map[string]*route53ResourceRecordSet),
+	}
+	ts := httptest.NewServer(f)
+	defer ts.Close()
+
+	p := newRoute53Provider(t, map[string]string{
+		"accessKeyID":     "AKIDTEST",
+		"secretAccessKey": "secret",
+	})
+	p.baseURL = ts.URL
+
+	fqdn := domain.ChallengeRecordName("www.example.com")
+	require.NoError(t, p.Present("www.example.com", fqdn, "token-1"))
+	require.NoError(t, p.Present("*.www.example.com", fqdn, "token-2"))
+
+	s := f.records["Z1"+fqdn]
+	require.NotNil(t, s)
+	require.Equal(t, []string{`"token-1"`, `"token-2"`}, s.Values)
+	require.Equal(t, route53TTL, s.TTL)
+
+	require.NoError(t, p.Cleanup("www.example.com", fqdn, "token-1"))
+	require.Equal(t, []string{`"token-2"`}, f.records["Z1"+fqdn].Values)
+
+	require.NoError(t, p.Cleanup("*.www.example.com", fqdn, "token-2"))
+	require.Empty(t, f.records)
+	require.Equal(t, "DELETE", f.changes[len(f.changes)-1].Action)
+}
+
+func TestRoute53Errors(t *testing.T) {
+	f := &fakeRoute53{
+		zones: []*route53HostedZone{
+			{ID: "/hostedzone/ZPRIVATE", Name: "internal.example.com.", PrivateZone: true},
+		},
+		records: make(map[string]*route53ResourceRecordSet),
+	}
+	ts := httptest.NewServer(f)
+	defer ts.Close()
+
+	p := newRoute53Provider(t, map[string]string{
+		"accessKeyID":     "AKIDTEST",
+		"secretAccessKey": "secret",
+	})
+	p.baseURL = ts.URL
+
+	err := p.Present("www.internal.example.com", domain.ChallengeRecordName("www.internal.example.com"), "token")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unable to find a Route 53 hosted zone")
+
+	p = newRoute53Provider(t, map[string]string{
+		"accessKeyID":     "AKIDOTHER",
+		"secretAccessKey": "secret",
+	})
+	p.baseURL = ts.URL
+
+	err = p.Present("www.example.com", domain.ChallengeRecordName("www.example.com"), "token")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "SignatureDoesNotMatch")
+
+	_, err = NewDNSProvider("route53", map[string]string{"accessKeyID": "AKIDTEST"})
+	require.Error(t, err)
+
+	// The api address can't be changed with the account options.
+	p = newRoute53Provider(t, map[string]string{
+		"accessKeyID":     "AKIDTEST",
+		"secretAccessKey": "secret",
+		"baseURL":         "http://169.254.169.254",
+	})
+	require.Equal(t, route53BaseURL, p.baseURL)
+}
+
+func TestRoute53Sign(t *testing.T) {
+	p := newRoute53Provider(t, map[string]string{
+		"accessKeyID":     "AKIDTEST",
+		"secretAccessKey": "secret",
+		"sessionToken":    "session",
+	})
+
+	req, err := http.NewRequest("GET", "https://route53.amazonaws.com/2013-04-01/hostedzonesbyname?dnsname=example.com.&maxitems=1", nil)
+	require.NoError(t, err)
+	p.sign(req, nil)
+
+	auth := req.Header.Get("Authorization")
+	require.True(t, strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKIDTEST/20171201/us-east-1/route53/aws4_request, SignedHeaders=host;x-amz-date;x-amz-security-token, Signature="))
+	require.Equal(t, "session", req.Header.Get("X-Amz-Security-Token"))
+
+	// the signature changes with the secret
+	other := newRoute53Provider(t, map[string]string{"accessKeyID": "AKIDTEST", "secretAccessKey": "other", "sessionToken": "session"})
+	req2, _ := http.NewRequest("GET", req.URL.String(), nil)
+	other.sign(req2, nil)
+	require.NotEqual(t, auth, req2.Header.Get("Authorization"))
+}
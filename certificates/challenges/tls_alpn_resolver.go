@@ -0,0 +1,62 @@
+package challenges
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/pem"
+
+	"github.com/lost-mountain/isard/cryptopolis"
+	"github.com/lost-mountain/isard/domain"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/acme"
+)
+
+// TLSALPNResolver validates tls-alpn-01 challenges.
+type TLSALPNResolver struct {
+	acmeClient *acme.Client
+}
+
+// Cleanup removes the challenge certificate from the authorization,
+// so it's not served after the CA validates it.
+func (r *TLSALPNResolver) Cleanup(a *domain.Authorization) error {
+	a.TLSALPN01ChallengeCert = nil
+	a.TLSALPN01ChallengeKey = nil
+	return nil
+}
+
+// Resolve generates the acme-tls/1 certificate for the challenge
+// and stores it in the authorization. So the ALPN responder
+// can serve it when the ACME verification is triggered.
+func (r *TLSALPNResolver) Resolve(a *domain.Authorization, challenge *acme.Challenge) error {
+	cert, err := r.acmeClient.TLSALPN01ChallengeCert(challenge.Token, a.Name)
+	if err != nil {
+		return errors.Wrapf(err, "error generating certificate for tls-alpn-01 challenge: %s", a.Name)
+	}
+
+	key, ok := cert.PrivateKey.(crypto.Signer)
+	if !ok {
+		return errors.Errorf("invalid key for tls-alpn-01 challenge: %s", a.Name)
+	}
+
+	var c, k bytes.Buffer
+	for _, b := range cert.Certificate {
+		if err := pem.Encode(&c, &pem.Block{Type: "CERTIFICATE", Bytes: b}); err != nil {
+			return errors.Wrapf(err, "error encoding certificate for tls-alpn-01 challenge: %s", a.Name)
+		}
+	}
+
+	if err := cryptopolis.EncodeKeyPEM(&k, key); err != nil {
+		return errors.Wrapf(err, "error encoding key for tls-alpn-01 challenge: %s", a.Name)
+	}
+
+	a.TLSALPN01ChallengeCert = c.Bytes()
+	a.TLSALPN01ChallengeKey = k.Bytes()
+	return nil
+}
+
+// NewTLSALPNResolver initializes a new tls-alpn challenge resolver.
+func NewTLSALPNResolver(ac *acme.Client) *TLSALPNResolver {
+	return &TLSALPNResolver{
+		acmeClient: ac,
+	}
+}
@@ -0,0 +1,37 @@
+package challenges
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"testing"
+
+	"github.com/lost-mountain/isard/account"
+	"github.com/lost-mountain/isard/domain"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/acme"
+)
+
+func TestTLSALPNResolveAndCleanup(t *testing.T) {
+	acc, err := account.NewAccount("david.calavera@gmail.com")
+	require.NoError(t, err)
+
+	pk, err := acc.PrivateKey()
+	require.NoError(t, err)
+
+	r := NewTLSALPNResolver(&acme.Client{Key: pk})
+	a := &domain.Authorization{Name: "www.example.com"}
+
+	err = r.Resolve(a, &acme.Challenge{Type: "tls-alpn-01", Token: "token"})
+	require.NoError(t, err)
+
+	cert, err := tls.X509KeyPair(a.TLSALPN01ChallengeCert, a.TLSALPN01ChallengeKey)
+	require.NoError(t, err)
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	require.Equal(t, []string{"www.example.com"}, leaf.DNSNames)
+
+	require.NoError(t, r.Cleanup(a))
+	require.Nil(t, a.TLSALPN01ChallengeCert)
+	require.Nil(t, a.TLSALPN01ChallengeKey)
+}
@@ -0,0 +1,132 @@
+package certificates
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lost-mountain/isard/cryptopolis"
+	"github.com/pkg/errors"
+)
+
+// ErrRenewalInfoUnsupported is an error returned when
+// the CA doesn't expose a renewal information endpoint.
+var ErrRenewalInfoUnsupported = errors.New("the ACME directory doesn't support renewal information")
+
+// RenewalInfo is the CA's suggestion about when to
+// renew a certificate, as described in ACME Renewal Information (ARI).
+type RenewalInfo struct {
+	SuggestedWindow struct {
+		Start time.Time `json:"start"`
+		End   time.Time `json:"end"`
+	} `json:"suggestedWindow"`
+	ExplanationURL string `json:"explanationURL,omitempty"`
+	// RetryAfter is how long the CA asked to wait
+	// before requesting this information again.
+	RetryAfter time.Duration `json:"-"`
+}
+
+// RenewalTime selects a random time inside the suggested window,
+// so certificates with the same window are not renewed at once.
+func (r *RenewalInfo) RenewalTime() time.Time {
+	w := r.SuggestedWindow.End.Sub(r.SuggestedWindow.Start)
+	if w <= 0 {
+		return r.SuggestedWindow.Start
+	}
+	return r.SuggestedWindow.Start.Add(time.Duration(rand.Int63n(int64(w))))
+}
+
+// GetRenewalInfo asks the CA when a certificate should be renewed.
+// It returns ErrRenewalInfoUnsupported if the CA doesn't support it.
+func (c *Client) GetRenewalInfo(cert *cryptopolis.Certificate) (*RenewalInfo, error) {
+	if cert.RenewalInfoID == "" {
+		return nil, errors.New("the certificate doesn't have a renewal information identifier")
+	}
+
+	u, err := c.renewalInfoURL()
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := c.get(u + "/" + cert.RenewalInfoID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error requesting renewal information")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("error requesting renewal information: %s", res.Status)
+	}
+
+	var info RenewalInfo
+	if err := json.NewDecoder(res.Body).Decode(&info); err != nil {
+		return nil, errors.Wrap(err, "error decoding renewal information")
+	}
+	info.RetryAfter = parseRetryAfter(res.Header.Get("Retry-After"), time.Now())
+
+	return &info, nil
+}
+
+// renewalInfoURL discovers the renewal information endpoint
+// in the ACME directory. The x/crypto client doesn't expose it.
+func (c *Client) renewalInfoURL() (string, error) {
+	if c.renewalInfo != "" {
+		return c.renewalInfo, nil
+	}
+
+	res, err := c.get(c.client.DirectoryURL)
+	if err != nil {
+		return "", errors.Wrap(err, "error requesting ACME directory")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", errors.Errorf("error requesting ACME directory: %s", res.Status)
+	}
+
+	var dir struct {
+		RenewalInfo string `json:"renewalInfo"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&dir); err != nil {
+		return "", errors.Wrap(err, "error decoding ACME directory")
+	}
+
+	if dir.RenewalInfo == "" {
+		return "", ErrRenewalInfoUnsupported
+	}
+
+	c.renewalInfo = strings.TrimSuffix(dir.RenewalInfo, "/")
+	return c.renewalInfo, nil
+}
+
+func (c *Client) get(url string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.client.HTTPClient.Do(req.WithContext(context.Background()))
+}
+
+// renewalInfoID builds the identifier of a certificate in the
+// renewal information endpoint. It's the certificate's authority
+// key identifier and its serial number, encoded in base64url.
+// It returns an empty string for certificates without an authority key identifier.
+func renewalInfoID(cert *x509.Certificate) string {
+	if len(cert.AuthorityKeyId) == 0 || cert.SerialNumber == nil {
+		return ""
+	}
+
+	serial := cert.SerialNumber.Bytes()
+	// Serial numbers are DER integers, a leading zero keeps them positive.
+	if len(serial) == 0 || serial[0]&0x80 != 0 {
+		serial = append([]byte{0}, serial...)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(cert.AuthorityKeyId) + "." +
+		base64.RawURLEncoding.EncodeToString(serial)
+}
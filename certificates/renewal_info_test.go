@@ -0,0 +1,111 @@
+package certificates
+
+import (
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lost-mountain/isard/account"
+	"github.com/lost-mountain/isard/cryptopolis"
+	"github.com/stretchr/testify/require"
+)
+
+// newRenewalInfoDirectory starts a fake ACME directory
+// that exposes a renewal information endpoint.
+func newRenewalInfoDirectory(t *testing.T, windows map[string]string) *httptest.Server {
+	mux := http.NewServeMux()
+	ts := httptest.NewServer(mux)
+
+	mux.HandleFunc("/directory", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"newNonce": "%[1]s/new-nonce", "renewalInfo": "%[1]s/renewal-info/"}`, ts.URL)
+	})
+	mux.HandleFunc("/renewal-info/", func(w http.ResponseWriter, r *http.Request) {
+		id := r.URL.Path[len("/renewal-info/"):]
+		window, ok := windows[id]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Retry-After", "21600")
+		fmt.Fprint(w, window)
+	})
+
+	return ts
+}
+
+func newRenewalInfoClient(t *testing.T, directoryURL string) *Client {
+	a, err := account.NewAccount("david.calavera@gmail.com")
+	require.NoError(t, err)
+	a.DirectoryURL = directoryURL
+
+	c, err := NewClient(a)
+	require.NoError(t, err)
+	return c
+}
+
+func TestGetRenewalInfo(t *testing.T) {
+	ts := newRenewalInfoDirectory(t, map[string]string{
+		"aYhba4dGQEHhs3uEe6CuLN4ByNQ.AIdlQyE": `{
+			"suggestedWindow": {
+				"start": "2025-01-02T04:00:00Z",
+				"end": "2025-01-03T04:00:00Z"
+			},
+			"explanationURL": "https://acme.example.com/docs/ari"
+		}`,
+	})
+	defer ts.Close()
+
+	c := newRenewalInfoClient(t, ts.URL+"/directory")
+	info, err := c.GetRenewalInfo(&cryptopolis.Certificate{
+		RenewalInfoID: "aYhba4dGQEHhs3uEe6CuLN4ByNQ.AIdlQyE",
+	})
+	require.NoError(t, err)
+
+	start := time.Date(2025, 1, 2, 4, 0, 0, 0, time.UTC)
+	end := time.Date(2025, 1, 3, 4, 0, 0, 0, time.UTC)
+	require.True(t, start.Equal(info.SuggestedWindow.Start))
+	require.True(t, end.Equal(info.SuggestedWindow.End))
+	require.Equal(t, "https://acme.example.com/docs/ari", info.ExplanationURL)
+	require.Equal(t, 6*time.Hour, info.RetryAfter)
+
+	for i := 0; i < 10; i++ {
+		r := info.RenewalTime()
+		require.False(t, r.Before(start))
+		require.True(t, r.Before(end))
+	}
+
+	_, err = c.GetRenewalInfo(&cryptopolis.Certificate{RenewalInfoID: "unknown.AQ"})
+	require.EqualError(t, err, "error requesting renewal information: 404 Not Found")
+}
+
+func TestGetRenewalInfoUnsupported(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"newNonce": "https://acme.example.com/new-nonce"}`)
+	}))
+	defer ts.Close()
+
+	c := newRenewalInfoClient(t, ts.URL)
+	_, err := c.GetRenewalInfo(&cryptopolis.Certificate{RenewalInfoID: "aYhba4dGQEHhs3uEe6CuLN4ByNQ.AIdlQyE"})
+	require.Equal(t, ErrRenewalInfoUnsupported, err)
+}
+
+func TestRenewalInfoID(t *testing.T) {
+	// Example from the ACME Renewal Information specification.
+	aki, err := hex.DecodeString("69885B6B87464041E1B37B847BA0AE2CDE01C8D4")
+	require.NoError(t, err)
+
+	cert := &x509.Certificate{
+		AuthorityKeyId: aki,
+		SerialNumber:   big.NewInt(0x87654321),
+	}
+	require.Equal(t, "aYhba4dGQEHhs3uEe6CuLN4ByNQ.AIdlQyE", renewalInfoID(cert))
+
+	cert.AuthorityKeyId = nil
+	require.Empty(t, renewalInfoID(cert))
+}
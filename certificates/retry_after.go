@@ -0,0 +1,78 @@
+package certificates
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// retryAfterTransport records the Retry-After header that the CA
+// sends for each URL, so callers know when to poll a resource again.
+type retryAfterTransport struct {
+	rt     http.RoundTripper
+	mu     sync.Mutex
+	delays map[string]time.Duration
+}
+
+// RoundTrip sends the request with the underlying transport
+// and records the Retry-After header in the response.
+func (t *retryAfterTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.rt.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	d := parseRetryAfter(res.Header.Get("Retry-After"), time.Now())
+
+	t.mu.Lock()
+	if d > 0 {
+		t.delays[req.URL.String()] = d
+	} else {
+		delete(t.delays, req.URL.String())
+	}
+	t.mu.Unlock()
+
+	return res, nil
+}
+
+// retryAfter returns the delay requested by the CA in
+// the last response for a URL, or zero if there was none.
+func (t *retryAfterTransport) retryAfter(url string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.delays[url]
+}
+
+func newRetryAfterTransport() *retryAfterTransport {
+	return &retryAfterTransport{
+		rt:     http.DefaultTransport,
+		delays: map[string]time.Duration{},
+	}
+}
+
+// parseRetryAfter parses the value of a Retry-After header,
+// which can be a number of seconds or an HTTP date.
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	if v == "" {
+		return 0
+	}
+
+	if s, err := strconv.Atoi(v); err == nil {
+		if s < 0 {
+			return 0
+		}
+		return time.Duration(s) * time.Second
+	}
+
+	t, err := http.ParseTime(v)
+	if err != nil {
+		return 0
+	}
+
+	d := t.Sub(now)
+	if d < 0 {
+		return 0
+	}
+	return d
+}
@@ -0,0 +1,52 @@
+package certificates
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2017, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		v string
+		d time.Duration
+	}{
+		{"", 0},
+		{"120", 2 * time.Minute},
+		{"-1", 0},
+		{"Thu, 01 Jun 2017 10:00:30 GMT", 30 * time.Second},
+		{"Thu, 01 Jun 2017 09:00:00 GMT", 0},
+		{"soon", 0},
+	}
+
+	for _, c := range cases {
+		require.Equal(t, c.d, parseRetryAfter(c.v, now), "invalid delay for: %q", c.v)
+	}
+}
+
+func TestRetryAfterTransport(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/pending" {
+			w.Header().Set("Retry-After", "10")
+		}
+	}))
+	defer ts.Close()
+
+	tr := newRetryAfterTransport()
+	client := &http.Client{Transport: tr}
+
+	res, err := client.Get(ts.URL + "/pending")
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, 10*time.Second, tr.retryAfter(ts.URL+"/pending"))
+
+	res, err = client.Get(ts.URL + "/valid")
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, time.Duration(0), tr.retryAfter(ts.URL+"/valid"))
+}
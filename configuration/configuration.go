@@ -3,6 +3,7 @@ package configuration
 import (
 	"encoding/json"
 	"os"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -34,25 +35,113 @@ type Configuration struct {
 		KeyFile  string
 	}
 
+	Bolt struct {
+		Directory string
+	}
+
 	GC *struct {
 		Project        string
 		AccountKeyFile string
 	}
 
 	Domains *DomainsConfiguration
+
+	HTTPResponder    *HTTPResponderConfiguration
+	TLSALPNResponder *ResponderConfiguration
+
+	Retries map[string]*RetryConfiguration
+
+	Renewal *RenewalConfiguration
 }
 
 // DomainsConfiguration holds setup
 // information to request certificates
 // and validate domains.
+// The DNS provider resolves dns-01 challenges,
+// NS1 is used when only its api key is set.
+// dns-01 challenges are accepted once their record has propagated
+// to all the zone's nameservers, or fail after the propagation timeout.
+// http-01 challenges are accepted once Isard can request them itself,
+// or fail after the self-check timeout.
 type DomainsConfiguration struct {
-	Ns1APIKey       string
-	HeaderValidator struct {
+	Ns1APIKey          string
+	DNSProvider        *DNSProviderConfiguration
+	PropagationTimeout Duration
+	SelfCheckTimeout   Duration
+	DisableSelfCheck   bool // accept http-01 challenges without requesting them first
+	HeaderValidator    struct {
 		Name  string
 		Value string
 	}
 }
 
+// DNSProviderConfiguration holds the name
+// of a registered DNS provider and its options.
+type DNSProviderConfiguration struct {
+	Name    string
+	Options map[string]string
+}
+
+// ResponderConfiguration holds setup
+// information for the listeners that answer
+// the CA's challenge requests.
+type ResponderConfiguration struct {
+	Address string // i.e: ":443"
+}
+
+// HTTPResponderConfiguration holds setup
+// information for the listener that answers
+// http-01 challenges.
+type HTTPResponderConfiguration struct {
+	Address            string // i.e: ":80"
+	RedirectHTTPS      bool   // redirect the requests that are not challenges to HTTPS
+	TrustForwardedHost bool   // find the domain in the X-Forwarded-Host header set by proxies
+}
+
+// RetryConfiguration holds the retry policy
+// for the messages of a broker topic.
+type RetryConfiguration struct {
+	MaxAttempts    int
+	InitialBackoff Duration
+	MaxBackoff     Duration
+	Multiplier     float64
+	Jitter         float64
+}
+
+// RenewalConfiguration holds setup
+// information to renew certificates
+// before they expire.
+// When the CA supports ACME Renewal Information (ARI),
+// its suggested window takes precedence.
+type RenewalConfiguration struct {
+	Interval           Duration // how often certificates are checked
+	Window             float64  // fraction of the certificate lifetime, before it expires, to renew it
+	Jitter             Duration // maximum delay added to each renewal to spread the load
+	DisableRenewalInfo bool     // ignore the renewal information from the CA
+}
+
+// Duration wraps time.Duration to parse
+// values like "1m30s" from the configuration file.
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalJSON parses a duration string.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return errors.Wrapf(err, "invalid duration: %s", b)
+	}
+
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return errors.Wrapf(err, "invalid duration: %s", s)
+	}
+
+	d.Duration = v
+	return nil
+}
+
 // Load parses a file to generate
 // a configuration structure.
 func Load(p string) (*Configuration, error) {
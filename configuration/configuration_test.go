@@ -2,6 +2,7 @@ package configuration
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -16,3 +17,45 @@ func TestLoad(t *testing.T) {
 	require.Equal(t, productionDirectory, c.ACME.DefaultProductionDirectory)
 	require.Equal(t, stagingDirectory, c.ACME.DefaultStagingDirectory)
 }
+
+func TestLoadRetries(t *testing.T) {
+	c, err := Load("testdata/retries.json")
+	require.NoError(t, err)
+
+	r, ok := c.Retries["authorization"]
+	require.True(t, ok)
+	require.Equal(t, 5, r.MaxAttempts)
+	require.Equal(t, 30*time.Second, r.InitialBackoff.Duration)
+	require.Equal(t, 5*time.Minute, r.MaxBackoff.Duration)
+	require.Zero(t, r.Jitter)
+}
+
+func TestLoadRenewal(t *testing.T) {
+	c, err := Load("testdata/renewal.json")
+	require.NoError(t, err)
+
+	require.Equal(t, 30*time.Minute, c.Renewal.Interval.Duration)
+	require.Equal(t, 0.25, c.Renewal.Window)
+	require.Equal(t, 10*time.Minute, c.Renewal.Jitter.Duration)
+}
+
+func TestLoadDNSProvider(t *testing.T) {
+	c, err := Load("testdata/dns_provider.json")
+	require.NoError(t, err)
+
+	p := c.Domains.DNSProvider
+	require.Equal(t, "rfc2136", p.Name)
+	require.Equal(t, "127.0.0.1:53", p.Options["nameserver"])
+	require.Equal(t, "isard.", p.Options["tsigKey"])
+	require.Equal(t, 2*time.Minute, c.Domains.PropagationTimeout.Duration)
+}
+
+func TestLoadResponders(t *testing.T) {
+	c, err := Load("testdata/responders.json")
+	require.NoError(t, err)
+
+	require.Equal(t, ":80", c.HTTPResponder.Address)
+	require.True(t, c.HTTPResponder.RedirectHTTPS)
+	require.False(t, c.HTTPResponder.TrustForwardedHost)
+	require.Equal(t, ":443", c.TLSALPNResponder.Address)
+}
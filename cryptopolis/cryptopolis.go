@@ -11,6 +11,7 @@ import (
 	"crypto/x509"
 	"encoding/pem"
 	"io"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -18,18 +19,39 @@ import (
 const (
 	certBlockType  = "CERTIFICATE"
 	ecdsaBlockType = "EC PRIVATE KEY"
-	rsaBlockType   = "RSA PRIVATE TYPE"
+	rsaBlockType   = "RSA PRIVATE KEY"
+	// legacyRSABlockType is the type that RSA keys
+	// were encoded with before, they are still decoded.
+	legacyRSABlockType = "RSA PRIVATE TYPE"
 )
 
 var rander = rand.Reader
 
 // Certificate is a structure that holds
 // a certificate information in PEM format
-// to be serialized.
+// to be serialized, and the metadata
+// extracted from the leaf certificate.
 type Certificate struct {
-	Cert []byte
-	Key  []byte
-	CA   []byte
+	Cert          []byte // leaf certificate
+	Key           []byte // leaf certificate's private key
+	CA            []byte // intermediate certificates, from the leaf's issuer up
+	NotBefore     time.Time
+	NotAfter      time.Time
+	RenewalInfoID string // identifies the certificate in the CA's renewal information endpoint
+
+	SerialNumber string   // serial number in hexadecimal
+	Issuer       string   // issuer's distinguished name
+	DNSNames     []string // names included in the certificate
+	Fingerprint  string   // SHA-256 fingerprint of the leaf certificate in hexadecimal
+	KeyType      KeyType
+
+	RevokedAt        time.Time // zero until the certificate is revoked
+	RevocationReason int       // CRL reason code in RFC 5280
+}
+
+// Revoked returns true when the certificate has been revoked.
+func (c *Certificate) Revoked() bool {
+	return !c.RevokedAt.IsZero()
 }
 
 // EncodeCertificate encodes a certificate and its chain in PEM format.
@@ -112,7 +134,7 @@ func ExtractPEMSigner(data string) (crypto.Signer, error) {
 	switch block.Type {
 	case ecdsaBlockType:
 		return x509.ParseECPrivateKey(block.Bytes)
-	case rsaBlockType:
+	case rsaBlockType, legacyRSABlockType:
 		return x509.ParsePKCS1PrivateKey(block.Bytes)
 	default:
 		return nil, errors.Errorf("invalid private key type: %s", block.Type)
@@ -0,0 +1,107 @@
+package cryptopolis
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// KeyType is the algorithm and size
+// of a certificate's private key.
+type KeyType string
+
+const (
+	// EC256 is an ECDSA key with the P-256 curve.
+	EC256 KeyType = "ec256"
+	// EC384 is an ECDSA key with the P-384 curve.
+	EC384 KeyType = "ec384"
+	// RSA2048 is a 2048 bits RSA key.
+	RSA2048 KeyType = "rsa2048"
+	// RSA3072 is a 3072 bits RSA key.
+	RSA3072 KeyType = "rsa3072"
+	// RSA4096 is a 4096 bits RSA key.
+	RSA4096 KeyType = "rsa4096"
+
+	// DefaultKeyType is the key type used when
+	// neither the account nor the request set one.
+	DefaultKeyType = EC256
+)
+
+// ParseKeyType checks that a key type is supported.
+// An empty string is a valid key type, it means that
+// the key type is not set.
+func ParseKeyType(s string) (KeyType, error) {
+	switch t := KeyType(s); t {
+	case "", EC256, EC384, RSA2048, RSA3072, RSA4096:
+		return t, nil
+	default:
+		return "", errors.Errorf("unsupported key type: %s", s)
+	}
+}
+
+// KeyTypeOf returns the key type of a private key.
+func KeyTypeOf(key crypto.Signer) (KeyType, error) {
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return EC256, nil
+		case elliptic.P384():
+			return EC384, nil
+		}
+	case *rsa.PrivateKey:
+		switch k.N.BitLen() {
+		case 2048:
+			return RSA2048, nil
+		case 3072:
+			return RSA3072, nil
+		case 4096:
+			return RSA4096, nil
+		}
+	}
+	return "", errors.Errorf("unsupported private key type")
+}
+
+// GenerateKey generates a new private key of the given type.
+func GenerateKey(t KeyType) (crypto.Signer, error) {
+	var (
+		key crypto.Signer
+		err error
+	)
+
+	switch t {
+	case EC256:
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rander)
+	case EC384:
+		key, err = ecdsa.GenerateKey(elliptic.P384(), rander)
+	case RSA2048:
+		key, err = rsa.GenerateKey(rander, 2048)
+	case RSA3072:
+		key, err = rsa.GenerateKey(rander, 3072)
+	case RSA4096:
+		key, err = rsa.GenerateKey(rander, 4096)
+	default:
+		return nil, errors.Errorf("unsupported key type: %s", t)
+	}
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "error generating %s key", t)
+	}
+	return key, nil
+}
+
+// EncodeKeyPEM encodes an ECDSA or RSA private key into a PEM data blob.
+func EncodeKeyPEM(w io.Writer, key crypto.Signer) error {
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		return EncodeECDSAKeyPEM(w, k)
+	case *rsa.PrivateKey:
+		return EncodeRSAKeyPEM(w, k)
+	default:
+		return errors.Errorf("invalid private key type encoding key")
+	}
+}
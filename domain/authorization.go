@@ -0,0 +1,108 @@
+package domain
+
+import (
+	"path"
+	"time"
+
+	"golang.org/x/crypto/acme"
+)
+
+// Authorization stores the state of the CA's
+// authorization for one of the domain's SAN names,
+// and the challenge used to complete it.
+type Authorization struct {
+	Name                    string
+	URL                     string
+	Status                  string // status reported by the CA, i.e: pending, valid, invalid
+	ChallengeType           string
+	ChallengeURL            string
+	HTTP01ChallengePath     string
+	HTTP01ChallengeResponse string
+	DNS01ChallengeRecord    string
+	TLSALPN01ChallengeCert  []byte // PEM certificate served in the acme-tls/1 handshake
+	TLSALPN01ChallengeKey   []byte
+	PreparedAt              time.Time // when the resources to resolve the challenge were created
+	CleanedUp               bool      // the resources created to resolve the challenge have been removed
+	FailedChallengeTypes    []string  // challenge types that the CA failed to validate for the name
+}
+
+// Valid returns true when the CA has validated the authorization.
+func (a *Authorization) Valid() bool {
+	return a.Status == acme.StatusValid
+}
+
+// Prepared returns true when the challenge has been
+// prepared and its resources have not been removed yet.
+func (a *Authorization) Prepared() bool {
+	return a.ChallengeType != "" && !a.CleanedUp
+}
+
+// NeedsCleanup returns true when the CA has finished
+// with the authorization, but the resources created to
+// resolve its challenge have not been removed yet.
+func (a *Authorization) NeedsCleanup() bool {
+	return a.Prepared() && a.Status != acme.StatusPending
+}
+
+// ChallengeToken returns the token that the CA
+// requests to resolve the authorization's challenge.
+// Only http-01 challenges are requested with a token.
+func (a *Authorization) ChallengeToken() string {
+	if a.ChallengeType != "http-01" || a.HTTP01ChallengePath == "" {
+		return ""
+	}
+	return path.Base(a.HTTP01ChallengePath)
+}
+
+// ChallengeTypesFor returns the domain's challenge types
+// to authorize a name with, in order of preference.
+// The challenge types that already failed for the name are skipped,
+// and wildcard names are only authorized with dns-01 challenges.
+func (d *Domain) ChallengeTypesFor(a *Authorization) []string {
+	var types []string
+	for _, t := range d.ChallengeTypes {
+		if IsWildcard(a.Name) && t != wildcardChallengeType {
+			continue
+		}
+		if !containsName(a.FailedChallengeTypes, t) {
+			types = append(types, t)
+		}
+	}
+	return types
+}
+
+// Authorization returns the authorization for
+// one of the domain's names, or nil if the domain
+// doesn't have an authorization for it.
+func (d *Domain) Authorization(name string) *Authorization {
+	for _, a := range d.Authorizations {
+		if a.Name == name {
+			return a
+		}
+	}
+	return nil
+}
+
+// SetAuthorization adds an authorization to the domain,
+// replacing the previous authorization for the same name.
+func (d *Domain) SetAuthorization(a *Authorization) {
+	for i, o := range d.Authorizations {
+		if o.Name == a.Name {
+			d.Authorizations[i] = a
+			return
+		}
+	}
+	d.Authorizations = append(d.Authorizations, a)
+}
+
+// Authorized returns true when every SAN name
+// in the domain has a valid authorization.
+func (d *Domain) Authorized() bool {
+	for _, n := range d.SAN {
+		a := d.Authorization(n)
+		if a == nil || !a.Valid() {
+			return false
+		}
+	}
+	return len(d.SAN) > 0
+}
@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/acme"
+)
+
+func TestAuthorized(t *testing.T) {
+	d := &Domain{Name: "cabal.io", SAN: []string{"cabal.io", "www.cabal.io"}}
+	require.False(t, d.Authorized())
+
+	d.SetAuthorization(&Authorization{Name: "cabal.io", Status: acme.StatusValid})
+	d.SetAuthorization(&Authorization{Name: "www.cabal.io", Status: acme.StatusPending})
+	require.False(t, d.Authorized())
+
+	d.SetAuthorization(&Authorization{Name: "www.cabal.io", Status: acme.StatusValid})
+	require.Len(t, d.Authorizations, 2)
+	require.True(t, d.Authorized())
+
+	require.Nil(t, d.Authorization("blog.cabal.io"))
+	require.NoError(t, d.AddSANName("blog.cabal.io"))
+	require.False(t, d.Authorized())
+}
+
+func TestChallengeTypesFor(t *testing.T) {
+	d := &Domain{Name: "cabal.io", ChallengeTypes: []string{"http-01", "dns-01"}}
+
+	a := &Authorization{Name: "cabal.io"}
+	require.Equal(t, []string{"http-01", "dns-01"}, d.ChallengeTypesFor(a))
+
+	a.FailedChallengeTypes = []string{"http-01"}
+	require.Equal(t, []string{"dns-01"}, d.ChallengeTypesFor(a))
+
+	w := &Authorization{Name: "*.cabal.io"}
+	require.Equal(t, []string{"dns-01"}, d.ChallengeTypesFor(w))
+}
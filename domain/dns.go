@@ -10,7 +10,7 @@ func listARecords(domain string) ([]dns.RR, error) {
 }
 
 func listAnswers(domain string, questionType uint16) ([]dns.RR, error) {
-	config, err := dns.ClientConfigFromFile("/etc/resolv.conf")
+	server, err := defaultResolver()
 	if err != nil {
 		return nil, errors.Wrapf(err, "error querying DNS servers for domain: %s", domain)
 	}
@@ -19,7 +19,7 @@ func listAnswers(domain string, questionType uint16) ([]dns.RR, error) {
 	m := new(dns.Msg)
 	m.SetQuestion(dns.Fqdn(domain), questionType)
 
-	r, _, err := c.Exchange(m, config.Servers[0]+":"+config.Port)
+	r, _, err := c.Exchange(m, server)
 	if err != nil {
 		return nil, errors.Wrapf(err, "error querying DNS servers for domain: %s", domain)
 	}
@@ -30,3 +30,14 @@ func listAnswers(domain string, questionType uint16) ([]dns.RR, error) {
 
 	return r.Answer, nil
 }
+
+// defaultResolver returns the address of
+// the first DNS server configured in the system.
+func defaultResolver() (string, error) {
+	config, err := dns.ClientConfigFromFile("/etc/resolv.conf")
+	if err != nil {
+		return "", err
+	}
+
+	return config.Servers[0] + ":" + config.Port, nil
+}
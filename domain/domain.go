@@ -34,10 +34,16 @@ const (
 	Cancelling
 	// Cancelled is the state of a domain after the certificate has been cancelled.
 	Cancelled
+	// Revoked is the state of a domain after its certificate has been revoked.
+	Revoked
 
 	defaultChallengeType = "http-01"
 )
 
+// supportedChallengeTypes are the ACME challenges
+// that Isard can resolve.
+var supportedChallengeTypes = []string{"http-01", "dns-01", "tls-alpn-01"}
+
 // ErrDuplicatedSANName is an error returned when a name
 // already exists in the certificate's names list.
 var ErrDuplicatedSANName = errors.Errorf("domain already includes SAN name")
@@ -46,22 +52,25 @@ var ErrDuplicatedSANName = errors.Errorf("domain already includes SAN name")
 // about a registered domain
 // and its certificate authority.
 type Domain struct {
-	ID                      uuid.UUID
-	Name                    string
-	ChallengeType           string
-	AuthorizationURL        string
-	State                   State
-	AccountID               string
-	Account                 *account.Account
-	CreatedAt               time.Time
-	UpdatedAt               time.Time
-	HTTP01ChallengePath     string
-	HTTP01ChallengeResponse string
-
-	Certificate *cryptopolis.Certificate
-
-	initSAN map[string]struct{}
-	san     map[string]struct{}
+	ID             uuid.UUID
+	Name           string
+	ChallengeTypes []string // acceptable challenge types, in order of preference
+	OrderURL       string
+	PendingKey     []byte              // private key of the certificate requested in the current order, in PEM format
+	KeyType        cryptopolis.KeyType // key type for the certificate, it overrides the account's key type
+	ReuseKey       bool                // keep the certificate's private key when it's renewed
+	State          State
+	AccountID      string
+	Account        *account.Account
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+
+	Authorizations []*Authorization // authorizations in the current order, one per SAN name
+	Certificate    *cryptopolis.Certificate
+	History        []Transition // state transitions, from the oldest to the newest
+
+	SAN        []string // names included in the certificate
+	InitialSAN []string // names added when the domain was created, they cannot be removed
 }
 
 // AddSANName appends a name to the
@@ -69,14 +78,21 @@ type Domain struct {
 // It returns an error if the name is already
 // in the list. This prevents reaching out
 // limits with duplicated certificates.
+// Wildcard names are only accepted when the
+// domain can be authorized with dns-01 challenges.
 func (d *Domain) AddSANName(name string) error {
-	if d.san == nil {
-		d.san = map[string]struct{}{}
-	}
-	if _, exist := d.san[name]; exist {
+	if containsName(d.SAN, name) {
 		return ErrDuplicatedSANName
 	}
-	d.san[name] = struct{}{}
+
+	if err := checkWildcard(name); err != nil {
+		return err
+	}
+	if IsWildcard(name) && !containsName(d.ChallengeTypes, wildcardChallengeType) {
+		return errors.Errorf("wildcard names can only be authorized with %s challenges: %s", wildcardChallengeType, name)
+	}
+
+	d.SAN = append(d.SAN, name)
 	return nil
 }
 
@@ -85,55 +101,90 @@ func (d *Domain) AddSANName(name string) error {
 // It doesn't allow to remove the initial
 // name added when the domain was created.
 func (d *Domain) RemoveSANName(name string) {
-	if _, i := d.initSAN[name]; i {
+	if containsName(d.InitialSAN, name) {
 		return
 	}
 
-	if d.san != nil {
-		delete(d.san, name)
+	for i, n := range d.SAN {
+		if n == name {
+			d.SAN = append(d.SAN[:i], d.SAN[i+1:]...)
+			return
+		}
 	}
 }
 
 // SANNames returns the certificate's names list.
 func (d *Domain) SANNames() []string {
-	if d.san == nil {
-		return make([]string, 0)
+	names := make([]string, len(d.SAN))
+	copy(names, d.SAN)
+	return names
+}
+
+// CertificateKeyType returns the key type for the domain's
+// certificate. It uses the domain's key type when it's set,
+// then the account's key type, and the default key type otherwise.
+func (d *Domain) CertificateKeyType() cryptopolis.KeyType {
+	if d.KeyType != "" {
+		return d.KeyType
 	}
-	names := make([]string, 0, len(d.san))
-	for k := range d.san {
-		names = append(names, k)
+	if d.Account != nil && d.Account.KeyType != "" {
+		return d.Account.KeyType
 	}
-	return names
+	return cryptopolis.DefaultKeyType
 }
 
 // NewDomain initializes a new domain.
 func NewDomain(account *account.Account, name string) (*Domain, error) {
-	return NewDomainWithChallengeType(account, name, defaultChallengeType)
+	return NewDomainWithChallengeTypes(account, name, nil)
+}
+
+// NewDomainWithChallengeType initializes a new domain
+// that is authorized with a single challenge type.
+func NewDomainWithChallengeType(account *account.Account, name, challengeType string) (*Domain, error) {
+	return NewDomainWithChallengeTypes(account, name, []string{challengeType})
 }
 
-// NewDomainWithChallengeType initializes a new domain.
+// NewDomainWithChallengeTypes initializes a new domain.
 // It uses the Public Suffix list of domains to assing
 // the name to the domain. The given name is added to the
-// SAN names list.
-func NewDomainWithChallengeType(account *account.Account, name, challengeType string) (*Domain, error) {
+// SAN names list. The challenge types are tried in order,
+// it returns an error if any of them is not supported.
+// Wildcard names default to dns-01 challenges,
+// since CAs don't accept other challenges for them.
+func NewDomainWithChallengeTypes(account *account.Account, name string, challengeTypes []string) (*Domain, error) {
 	names, err := ExtractNames(name)
 	if err != nil {
 		return nil, err
 	}
 
-	if challengeType == "" {
-		challengeType = defaultChallengeType
+	var types []string
+	for _, t := range challengeTypes {
+		if t == "" || containsName(types, t) {
+			continue
+		}
+		if !containsName(supportedChallengeTypes, t) {
+			return nil, errors.Errorf("unsupported ACME challenge: %s", t)
+		}
+		types = append(types, t)
+	}
+
+	if len(types) == 0 {
+		types = []string{defaultChallengeType}
+		if IsWildcard(names.CN) {
+			types = []string{wildcardChallengeType}
+		}
 	}
 
 	d := &Domain{
-		AccountID:     account.ID.String(),
-		Account:       account,
-		Name:          names.CN,
-		State:         Pending,
-		ChallengeType: challengeType,
-		CreatedAt:     time.Now(),
-		UpdatedAt:     time.Now(),
-		initSAN:       map[string]struct{}{names.CN: struct{}{}},
+		ID:             uuid.New(),
+		AccountID:      account.ID.String(),
+		Account:        account,
+		Name:           names.CN,
+		State:          Pending,
+		ChallengeTypes: types,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+		InitialSAN:     []string{names.CN},
 	}
 
 	for _, s := range names.SAN {
@@ -143,3 +194,12 @@ func NewDomainWithChallengeType(account *account.Account, name, challengeType st
 	}
 	return d, nil
 }
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/lost-mountain/isard/account"
+	"github.com/lost-mountain/isard/cryptopolis"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 )
@@ -21,7 +22,7 @@ func (s *testSuite) TestNewDomain() {
 	require.NotEmpty(s.T(), d.UpdatedAt)
 	require.Equal(s.T(), "test.cabal.io", d.Name)
 	require.Equal(s.T(), Pending, d.State)
-	require.Equal(s.T(), "http-01", d.ChallengeType)
+	require.Equal(s.T(), []string{"http-01"}, d.ChallengeTypes)
 
 	names := d.SANNames()
 	require.Len(s.T(), names, 1)
@@ -36,7 +37,7 @@ func (s *testSuite) TestNewDomainWithChallengeType() {
 	require.NotEmpty(s.T(), d.UpdatedAt)
 	require.Equal(s.T(), "test.cabal.io", d.Name)
 	require.Equal(s.T(), Pending, d.State)
-	require.Equal(s.T(), "dns-01", d.ChallengeType)
+	require.Equal(s.T(), []string{"dns-01"}, d.ChallengeTypes)
 
 	names := d.SANNames()
 	require.Len(s.T(), names, 1)
@@ -51,13 +52,22 @@ func (s *testSuite) TestNewDomainEmptyWithChallengeType() {
 	require.NotEmpty(s.T(), d.UpdatedAt)
 	require.Equal(s.T(), "test.cabal.io", d.Name)
 	require.Equal(s.T(), Pending, d.State)
-	require.Equal(s.T(), "http-01", d.ChallengeType)
+	require.Equal(s.T(), []string{"http-01"}, d.ChallengeTypes)
 
 	names := d.SANNames()
 	require.Len(s.T(), names, 1)
 	require.Equal(s.T(), []string{"test.cabal.io"}, names)
 }
 
+func (s *testSuite) TestNewDomainWithChallengeTypes() {
+	d, err := NewDomainWithChallengeTypes(s.account, "test.cabal.io", []string{"http-01", "", "dns-01", "http-01"})
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), []string{"http-01", "dns-01"}, d.ChallengeTypes)
+
+	_, err = NewDomainWithChallengeTypes(s.account, "test.cabal.io", []string{"http-01", "tls-sni-01"})
+	require.EqualError(s.T(), err, "unsupported ACME challenge: tls-sni-01")
+}
+
 func (s *testSuite) TestNewDomainWithExtension() {
 	d, err := NewDomainWithChallengeType(s.account, "cabal.io", "")
 	require.NoError(s.T(), err)
@@ -93,6 +103,39 @@ func (s *testSuite) TestAddSANName() {
 	require.EqualError(s.T(), err, ErrDuplicatedSANName.Error())
 }
 
+func (s *testSuite) TestNewDomainWildcard() {
+	d, err := NewDomain(s.account, "*.customer.cabal.io")
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "*.customer.cabal.io", d.Name)
+	require.Equal(s.T(), []string{"dns-01"}, d.ChallengeTypes)
+	require.Equal(s.T(), []string{"*.customer.cabal.io", "customer.cabal.io"}, d.SANNames())
+
+	d, err = NewDomainWithChallengeTypes(s.account, "*.customer.cabal.io", []string{"http-01", "dns-01"})
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), []string{"http-01", "dns-01"}, d.ChallengeTypes)
+
+	_, err = NewDomainWithChallengeType(s.account, "*.customer.cabal.io", "http-01")
+	require.EqualError(s.T(), err, "wildcard names can only be authorized with dns-01 challenges: *.customer.cabal.io")
+}
+
+func (s *testSuite) TestAddSANNameWildcard() {
+	d, err := NewDomain(s.account, "test.cabal.io")
+	require.NoError(s.T(), err)
+
+	err = d.AddSANName("*.test.cabal.io")
+	require.EqualError(s.T(), err, "wildcard names can only be authorized with dns-01 challenges: *.test.cabal.io")
+
+	d, err = NewDomainWithChallengeType(s.account, "test.cabal.io", "dns-01")
+	require.NoError(s.T(), err)
+
+	err = d.AddSANName("beta.*.cabal.io")
+	require.EqualError(s.T(), err, "invalid wildcard name: beta.*.cabal.io")
+
+	err = d.AddSANName("*.test.cabal.io")
+	require.NoError(s.T(), err)
+	require.Contains(s.T(), d.SANNames(), "*.test.cabal.io")
+}
+
 func (s *testSuite) TestRemoveSANName() {
 	d, err := NewDomain(s.account, "test.cabal.io")
 	require.NoError(s.T(), err)
@@ -106,6 +149,20 @@ func (s *testSuite) TestRemoveSANName() {
 	require.Contains(s.T(), names, "test.cabal.io")
 }
 
+func (s *testSuite) TestCertificateKeyType() {
+	d, err := NewDomain(s.account, "test.cabal.io")
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), cryptopolis.DefaultKeyType, d.CertificateKeyType())
+
+	a := *s.account
+	a.KeyType = cryptopolis.RSA2048
+	d.Account = &a
+	require.Equal(s.T(), cryptopolis.RSA2048, d.CertificateKeyType())
+
+	d.KeyType = cryptopolis.EC384
+	require.Equal(s.T(), cryptopolis.EC384, d.CertificateKeyType())
+}
+
 func TestDomain(t *testing.T) {
 	a, err := account.NewAccount("david.calavera@gmail.com")
 	require.NoError(t, err)
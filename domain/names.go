@@ -7,6 +7,14 @@ import (
 	"github.com/weppos/publicsuffix-go/publicsuffix"
 )
 
+// wildcardPrefix is the label that makes a
+// name match all the names one level below it.
+const wildcardPrefix = "*."
+
+// wildcardChallengeType is the only challenge
+// that CAs accept to authorize wildcard names.
+const wildcardChallengeType = "dns-01"
+
 // Names is a struct that holds
 // domain names information.
 type Names struct {
@@ -17,7 +25,16 @@ type Names struct {
 // ExtractNames normalizes a domain
 // name to extract the common name
 // and the SAN names.
+// Wildcard names include their base name,
+// so the certificate is also valid for it.
 func ExtractNames(name string) (*Names, error) {
+	if err := checkWildcard(name); err != nil {
+		return nil, err
+	}
+	if IsWildcard(name) {
+		return extractWildcardNames(name)
+	}
+
 	normal := strings.TrimLeft(name, "www.")
 
 	dn, err := publicsuffix.Domain(name)
@@ -37,3 +54,59 @@ func ExtractNames(name string) (*Names, error) {
 		SAN: []string{name},
 	}, nil
 }
+
+// extractWildcardNames checks that a wildcard name
+// doesn't cover a public suffix, i.e: *.co.uk.
+func extractWildcardNames(name string) (*Names, error) {
+	base := BaseName(name)
+	if _, err := publicsuffix.Domain(base); err != nil {
+		return nil, errors.Wrapf(err, "invalid wildcard name: %s", name)
+	}
+
+	return &Names{
+		CN:  name,
+		SAN: []string{name, base},
+	}, nil
+}
+
+// checkWildcard checks that the wildcard label
+// is only used as the leftmost label of a name.
+func checkWildcard(name string) error {
+	if !strings.Contains(name, "*") {
+		return nil
+	}
+
+	base := strings.TrimPrefix(name, wildcardPrefix)
+	if !IsWildcard(name) || base == "" || strings.Contains(base, "*") {
+		return errors.Errorf("invalid wildcard name: %s", name)
+	}
+	return nil
+}
+
+// IsWildcard returns true when the name
+// is a wildcard name, i.e: *.example.com.
+func IsWildcard(name string) bool {
+	return strings.HasPrefix(name, wildcardPrefix)
+}
+
+// BaseName returns the name without its wildcard label.
+// Names that are not wildcards are returned as they are.
+func BaseName(name string) string {
+	return strings.TrimPrefix(name, wildcardPrefix)
+}
+
+// WildcardName returns the wildcard name that matches
+// a hostname, i.e: *.example.com for www.example.com.
+// It returns an empty string when the hostname
+// doesn't have a parent name that a wildcard can cover.
+func WildcardName(hostname string) string {
+	if IsWildcard(hostname) {
+		return ""
+	}
+
+	i := strings.Index(hostname, ".")
+	if i <= 0 || !strings.Contains(hostname[i+1:], ".") {
+		return ""
+	}
+	return wildcardPrefix + hostname[i+1:]
+}
@@ -15,6 +15,8 @@ func TestExtractNames(t *testing.T) {
 		{"www.cabal.io", "cabal.io", []string{"cabal.io", "www.cabal.io"}},
 		{"cabal.io", "cabal.io", []string{"cabal.io", "www.cabal.io"}},
 		{"test.cabal.io", "test.cabal.io", []string{"test.cabal.io"}},
+		{"*.cabal.io", "*.cabal.io", []string{"*.cabal.io", "cabal.io"}},
+		{"*.customer.cabal.io", "*.customer.cabal.io", []string{"*.customer.cabal.io", "customer.cabal.io"}},
 	}
 
 	for _, c := range cases {
@@ -24,3 +26,26 @@ func TestExtractNames(t *testing.T) {
 		require.Equal(t, c.san, g.SAN)
 	}
 }
+
+func TestExtractNamesInvalidWildcard(t *testing.T) {
+	for _, n := range []string{"*.co.uk", "*.io", "*.*.cabal.io", "test.*.cabal.io", "*cabal.io"} {
+		_, err := ExtractNames(n)
+		require.Error(t, err, n)
+	}
+}
+
+func TestWildcardName(t *testing.T) {
+	cases := []struct {
+		n string
+		w string
+	}{
+		{"www.customer.cabal.io", "*.customer.cabal.io"},
+		{"customer.cabal.io", "*.cabal.io"},
+		{"cabal.io", ""},
+		{"*.cabal.io", ""},
+	}
+
+	for _, c := range cases {
+		require.Equal(t, c.w, WildcardName(c.n), c.n)
+	}
+}
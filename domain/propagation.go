@@ -0,0 +1,190 @@
+package domain
+
+import (
+	"net"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/pkg/errors"
+)
+
+// dnsQueryTimeout is how long the propagation check waits for each DNS server to answer.
+const dnsQueryTimeout = 5 * time.Second
+
+// maxCNAMEChain is how many aliases the propagation check follows for a record.
+const maxCNAMEChain = 8
+
+// ChallengeRecordName returns the fully qualified name
+// of the TXT record that resolves a dns-01 challenge for a name.
+// Wildcard names use the record of their base name.
+func ChallengeRecordName(name string) string {
+	return "_acme-challenge." + dns.Fqdn(BaseName(name))
+}
+
+// TXTRecordPropagated checks that all the authoritative
+// nameservers of a record's zone answer with the TXT value.
+// The nameservers are queried directly, so their answers
+// are not affected by the caches of recursive resolvers.
+// Records delegated with a CNAME are checked in the zone of their target.
+func TXTRecordPropagated(fqdn, value string) (bool, error) {
+	resolver, err := defaultResolver()
+	if err != nil {
+		return false, errors.Wrapf(err, "error checking DNS propagation for record: %s", fqdn)
+	}
+
+	return txtRecordPropagated(resolver, "53", fqdn, value)
+}
+
+// txtRecordPropagated uses the resolver to find the authoritative
+// nameservers of the record's zone, and queries each one in a port.
+func txtRecordPropagated(resolver, port, fqdn, value string) (bool, error) {
+	fqdn, err := resolveCNAME(resolver, fqdn)
+	if err != nil {
+		return false, err
+	}
+
+	nameservers, err := authoritativeNameservers(resolver, fqdn)
+	if err != nil {
+		return false, err
+	}
+
+	for _, ns := range nameservers {
+		addrs, err := nameserverAddresses(resolver, ns)
+		if err != nil {
+			return false, err
+		}
+
+		r, err := queryAddresses(addrs, port, fqdn)
+		if err != nil {
+			return false, err
+		}
+
+		if !hasTXTValue(r.Answer, value) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// resolveCNAME follows the aliases of a record name,
+// and returns the name that has the record.
+func resolveCNAME(resolver, fqdn string) (string, error) {
+	name := dns.Fqdn(fqdn)
+	for i := 0; i < maxCNAMEChain; i++ {
+		r, err := queryServer(resolver, name, dns.TypeCNAME, true)
+		if err != nil {
+			return "", err
+		}
+
+		var target string
+		for _, rr := range r.Answer {
+			if c, ok := rr.(*dns.CNAME); ok && strings.EqualFold(c.Hdr.Name, name) {
+				target = c.Target
+				break
+			}
+		}
+
+		if target == "" {
+			return name, nil
+		}
+		name = target
+	}
+
+	return "", errors.Errorf("too many CNAME records for record: %s", fqdn)
+}
+
+// nameserverAddresses returns the IPv4 and IPv6 addresses of a nameserver.
+// IPv4 addresses go first.
+func nameserverAddresses(resolver, ns string) ([]string, error) {
+	var addrs []string
+	var qerr error
+	for _, t := range []uint16{dns.TypeA, dns.TypeAAAA} {
+		r, err := queryServer(resolver, ns, t, true)
+		if err != nil {
+			qerr = err
+			continue
+		}
+
+		for _, rr := range r.Answer {
+			switch a := rr.(type) {
+			case *dns.A:
+				addrs = append(addrs, a.A.String())
+			case *dns.AAAA:
+				addrs = append(addrs, a.AAAA.String())
+			}
+		}
+	}
+
+	if len(addrs) > 0 {
+		return addrs, nil
+	}
+	if qerr != nil {
+		return nil, qerr
+	}
+	return nil, errors.Errorf("unable to find the address of nameserver: %s", ns)
+}
+
+// queryAddresses asks for the TXT records of a name to the
+// addresses of a nameserver, until one of them answers.
+func queryAddresses(addrs []string, port, fqdn string) (*dns.Msg, error) {
+	var qerr error
+	for _, addr := range addrs {
+		r, err := queryServer(net.JoinHostPort(addr, port), fqdn, dns.TypeTXT, false)
+		if err == nil {
+			return r, nil
+		}
+		qerr = err
+	}
+	return nil, qerr
+}
+
+// authoritativeNameservers walks up from the record
+// name until it finds the nameservers of its zone.
+func authoritativeNameservers(resolver, fqdn string) ([]string, error) {
+	labels := dns.SplitDomainName(fqdn)
+	for i := range labels {
+		zone := dns.Fqdn(strings.Join(labels[i:], "."))
+
+		r, err := queryServer(resolver, zone, dns.TypeNS, true)
+		if err != nil {
+			return nil, err
+		}
+
+		var nameservers []string
+		for _, rr := range r.Answer {
+			if ns, ok := rr.(*dns.NS); ok && ns.Hdr.Name == zone {
+				nameservers = append(nameservers, ns.Ns)
+			}
+		}
+
+		if len(nameservers) > 0 {
+			return nameservers, nil
+		}
+	}
+
+	return nil, errors.Errorf("unable to find the authoritative nameservers for record: %s", fqdn)
+}
+
+func queryServer(server, name string, questionType uint16, recursive bool) (*dns.Msg, error) {
+	c := &dns.Client{Timeout: dnsQueryTimeout}
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(name), questionType)
+	m.RecursionDesired = recursive
+
+	r, _, err := c.Exchange(m, server)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error querying DNS server %s for domain: %s", server, name)
+	}
+	return r, nil
+}
+
+func hasTXTValue(answers []dns.RR, value string) bool {
+	for _, rr := range answers {
+		if txt, ok := rr.(*dns.TXT); ok && strings.Join(txt.Txt, "") == value {
+			return true
+		}
+	}
+	return false
+}
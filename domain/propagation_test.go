@@ -0,0 +1,163 @@
+package domain
+
+import (
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeNameserver answers for the example.com zone, delegated
+// to ns1.example.com (127.0.0.1) and ns2.example.com (127.0.0.2).
+// The example.net zone is delegated to ns1.example.com, and
+// the example.io zone to ns.example.io, that only has an IPv6 address (::1).
+type fakeNameserver struct {
+	sync.Mutex
+	txt map[string]string
+}
+
+// setTXT stores the TXT value of a name.
+func (n *fakeNameserver) setTXT(name, value string) {
+	n.Lock()
+	defer n.Unlock()
+	n.txt[name] = value
+}
+
+func (n *fakeNameserver) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+	n.Lock()
+	defer n.Unlock()
+
+	m := new(dns.Msg)
+	m.SetReply(r)
+
+	q := r.Question[0]
+	hdr := dns.RR_Header{Name: q.Name, Rrtype: q.Qtype, Class: dns.ClassINET, Ttl: 60}
+
+	switch {
+	case q.Qtype == dns.TypeNS && q.Name == "example.com.":
+		m.Answer = append(m.Answer,
+			&dns.NS{Hdr: hdr, Ns: "ns1.example.com."},
+			&dns.NS{Hdr: hdr, Ns: "ns2.example.com."})
+	case q.Qtype == dns.TypeNS && q.Name == "example.net.":
+		m.Answer = append(m.Answer, &dns.NS{Hdr: hdr, Ns: "ns1.example.com."})
+	case q.Qtype == dns.TypeNS && q.Name == "example.io.":
+		m.Answer = append(m.Answer, &dns.NS{Hdr: hdr, Ns: "ns.example.io."})
+	case q.Qtype == dns.TypeCNAME && q.Name == "_acme-challenge.alias.example.com.":
+		m.Answer = append(m.Answer, &dns.CNAME{Hdr: hdr, Target: "_acme-challenge.example.net."})
+	case q.Qtype == dns.TypeAAAA && q.Name == "ns.example.io.":
+		m.Answer = append(m.Answer, &dns.AAAA{Hdr: hdr, AAAA: net.ParseIP("::1")})
+	case q.Qtype == dns.TypeA && q.Name == "ns1.example.com.":
+		m.Answer = append(m.Answer, &dns.A{Hdr: hdr, A: net.ParseIP("127.0.0.1")})
+	case q.Qtype == dns.TypeA && q.Name == "ns2.example.com.":
+		m.Answer = append(m.Answer, &dns.A{Hdr: hdr, A: net.ParseIP("127.0.0.2")})
+	case q.Qtype == dns.TypeTXT:
+		if v, ok := n.txt[q.Name]; ok {
+			m.Answer = append(m.Answer, &dns.TXT{Hdr: hdr, Txt: []string{v}})
+		}
+	}
+
+	w.WriteMsg(m)
+}
+
+func startNameserver(t *testing.T, addr string) (*fakeNameserver, string) {
+	pc, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		t.Skipf("unable to listen in %s: %v", addr, err)
+	}
+
+	n := &fakeNameserver{txt: make(map[string]string)}
+	started := make(chan struct{})
+	s := &dns.Server{
+		PacketConn:        pc,
+		Handler:           n,
+		NotifyStartedFunc: func() { close(started) },
+	}
+
+	go s.ActivateAndServe()
+	<-started
+	t.Cleanup(func() { s.Shutdown() })
+
+	return n, pc.LocalAddr().String()
+}
+
+func TestTXTRecordPropagated(t *testing.T) {
+	ns1, addr := startNameserver(t, "127.0.0.1:0")
+	_, port, err := net.SplitHostPort(addr)
+	require.NoError(t, err)
+	ns2, _ := startNameserver(t, net.JoinHostPort("127.0.0.2", port))
+
+	fqdn := ChallengeRecordName("www.example.com")
+	require.Equal(t, "_acme-challenge.www.example.com.", fqdn)
+	require.Equal(t, fqdn, ChallengeRecordName("*.www.example.com"))
+
+	ok, err := txtRecordPropagated(addr, port, fqdn, "token")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// Only one nameserver has the record.
+	ns1.setTXT(fqdn, "token")
+	ok, err = txtRecordPropagated(addr, port, fqdn, "token")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// The second nameserver has a stale value.
+	ns2.setTXT(fqdn, "old-token")
+	ok, err = txtRecordPropagated(addr, port, fqdn, "token")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	ns2.setTXT(fqdn, "token")
+	ok, err = txtRecordPropagated(addr, port, fqdn, "token")
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+func TestTXTRecordPropagatedCNAME(t *testing.T) {
+	ns1, addr := startNameserver(t, "127.0.0.1:0")
+	_, port, err := net.SplitHostPort(addr)
+	require.NoError(t, err)
+	startNameserver(t, net.JoinHostPort("127.0.0.2", port))
+
+	fqdn := ChallengeRecordName("alias.example.com")
+	ok, err := txtRecordPropagated(addr, port, fqdn, "token")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// The record is checked in the nameservers of the target's zone.
+	ns1.setTXT("_acme-challenge.example.net.", "token")
+	ok, err = txtRecordPropagated(addr, port, fqdn, "token")
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+func TestTXTRecordPropagatedIPv6(t *testing.T) {
+	_, addr := startNameserver(t, "127.0.0.1:0")
+	_, port, err := net.SplitHostPort(addr)
+	require.NoError(t, err)
+	ns, _ := startNameserver(t, net.JoinHostPort("::1", port))
+
+	fqdn := ChallengeRecordName("www.example.io")
+	ns.setTXT(fqdn, "token")
+	ok, err := txtRecordPropagated(addr, port, fqdn, "token")
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+func TestTXTRecordPropagatedUnknownZone(t *testing.T) {
+	_, addr := startNameserver(t, "127.0.0.1:0")
+	_, port, err := net.SplitHostPort(addr)
+	require.NoError(t, err)
+
+	_, err = txtRecordPropagated(addr, port, ChallengeRecordName("www.example.org"), "token")
+	require.EqualError(t, err, "unable to find the authoritative nameservers for record: _acme-challenge.www.example.org.")
+}
+
+func TestAuthoritativeNameservers(t *testing.T) {
+	_, addr := startNameserver(t, "127.0.0.1:0")
+
+	ns, err := authoritativeNameservers(addr, "_acme-challenge.a.b.example.com.")
+	require.NoError(t, err)
+	require.Equal(t, []string{"ns1.example.com.", "ns2.example.com."}, ns)
+}
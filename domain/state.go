@@ -0,0 +1,102 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+var stateNames = map[State]string{
+	Pending:      "pending",
+	Validating:   "validating",
+	Invalid:      "invalid",
+	Verified:     "verified",
+	Provisioning: "provisioning",
+	Authorized:   "authorized",
+	Requesting:   "requesting",
+	Issued:       "issued",
+	Cancelling:   "cancelling",
+	Cancelled:    "cancelled",
+	Revoked:      "revoked",
+}
+
+// transitions lists the states that a domain
+// can move to from each state.
+// A domain can always stay in the same state,
+// so failed jobs can be retried. Issued domains go through
+// provisioning to get a new certificate, so the CA
+// authorizes every name in the new order. They go back
+// to issued when the new order fails, so they keep
+// serving their current certificate.
+var transitions = map[State][]State{
+	Pending:      {Validating, Cancelling},
+	Validating:   {Verified, Invalid, Cancelling},
+	Invalid:      {Validating, Provisioning, Cancelling},
+	Verified:     {Provisioning, Cancelling},
+	Provisioning: {Authorized, Invalid, Issued, Cancelling},
+	Authorized:   {Requesting, Cancelling},
+	Requesting:   {Issued, Invalid, Cancelling},
+	Issued:       {Provisioning, Cancelling, Revoked},
+	Cancelling:   {Cancelled},
+	Revoked:      {Cancelling},
+}
+
+// String returns the name of the state.
+func (s State) String() string {
+	if n, ok := stateNames[s]; ok {
+		return n
+	}
+	return "unknown"
+}
+
+// CanTransitionTo checks if a domain in this
+// state is allowed to move to another state.
+func (s State) CanTransitionTo(to State) bool {
+	if s == to {
+		return true
+	}
+
+	for _, t := range transitions[s] {
+		if t == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Transition records a change in the state of a domain.
+type Transition struct {
+	From    State
+	To      State
+	Cause   string    // reason why the state changed
+	JobUUID uuid.UUID // identifier of the job that changed the state
+	Error   string    // error that caused the change, if any
+	Time    time.Time
+}
+
+// TransitionTo moves the domain to a new state
+// and records the transition in its history.
+// It returns an error if the domain cannot
+// move from its current state to the new one.
+func (d *Domain) TransitionTo(to State, cause string, jobID uuid.UUID, err error) error {
+	if !d.State.CanTransitionTo(to) {
+		return errors.Errorf("invalid state transition for domain %s: %s -> %s", d.Name, d.State, to)
+	}
+
+	t := Transition{
+		From:    d.State,
+		To:      to,
+		Cause:   cause,
+		JobUUID: jobID,
+		Time:    time.Now().UTC(),
+	}
+	if err != nil {
+		t.Error = err.Error()
+	}
+
+	d.History = append(d.History, t)
+	d.State = to
+	d.UpdatedAt = t.Time
+	return nil
+}
@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateString(t *testing.T) {
+	require.Equal(t, "pending", Pending.String())
+	require.Equal(t, "cancelled", Cancelled.String())
+	require.Equal(t, "revoked", Revoked.String())
+	require.Equal(t, "unknown", State(100).String())
+}
+
+func TestCanTransitionTo(t *testing.T) {
+	require.True(t, Pending.CanTransitionTo(Validating))
+	require.True(t, Validating.CanTransitionTo(Validating))
+	require.True(t, Invalid.CanTransitionTo(Provisioning))
+	require.True(t, Issued.CanTransitionTo(Cancelling))
+	require.False(t, Pending.CanTransitionTo(Issued))
+	require.False(t, Verified.CanTransitionTo(Requesting))
+	require.False(t, Cancelled.CanTransitionTo(Pending))
+	require.True(t, Issued.CanTransitionTo(Revoked))
+	require.False(t, Revoked.CanTransitionTo(Issued))
+}
+
+func TestIssuedTransitions(t *testing.T) {
+	require.True(t, Issued.CanTransitionTo(Provisioning))
+	require.True(t, Issued.CanTransitionTo(Cancelling))
+	require.True(t, Issued.CanTransitionTo(Revoked))
+	require.False(t, Issued.CanTransitionTo(Validating))
+	require.False(t, Issued.CanTransitionTo(Authorized))
+	require.False(t, Issued.CanTransitionTo(Requesting))
+
+	d := &Domain{Name: "test.cabal.io", State: Issued}
+	err := d.TransitionTo(Authorized, "authorization valid", uuid.New(), nil)
+	require.EqualError(t, err, "invalid state transition for domain test.cabal.io: issued -> authorized")
+	require.Equal(t, Issued, d.State)
+}
+
+func TestTransitionTo(t *testing.T) {
+	d := &Domain{Name: "test.cabal.io"}
+	job := uuid.New()
+
+	err := d.TransitionTo(Validating, "validation started", job, nil)
+	require.NoError(t, err)
+	err = d.TransitionTo(Invalid, "validation failed", job, errors.New("invalid headers"))
+	require.NoError(t, err)
+	require.Equal(t, Invalid, d.State)
+
+	require.Len(t, d.History, 2)
+	require.Equal(t, Pending, d.History[0].From)
+	require.Equal(t, Validating, d.History[0].To)
+	require.Equal(t, "validation started", d.History[0].Cause)
+	require.Equal(t, job, d.History[0].JobUUID)
+	require.Empty(t, d.History[0].Error)
+	require.NotEmpty(t, d.History[0].Time)
+	require.Equal(t, "invalid headers", d.History[1].Error)
+
+	err = d.TransitionTo(Issued, "certificate issued", job, nil)
+	require.EqualError(t, err, "invalid state transition for domain test.cabal.io: invalid -> issued")
+	require.Equal(t, Invalid, d.State)
+	require.Len(t, d.History, 2)
+}
@@ -0,0 +1,84 @@
+package validator
+
+import (
+	"crypto/tls"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// challengeTimeout is how long the self-check waits for the challenge response.
+	challengeTimeout = 10 * time.Second
+	// maxChallengeRedirects is the number of redirects the CA follows.
+	maxChallengeRedirects = 10
+	// maxChallengeResponse is the longest challenge response read.
+	maxChallengeResponse = 1024
+)
+
+// CheckHTTPChallenge requests the http-01 challenge for a hostname
+// the way the CA does, and checks that the response body
+// matches the expected key authorization.
+// It returns an error that describes why the CA would fail to validate it.
+func CheckHTTPChallenge(hostname, path, expected string) error {
+	return checkHTTPChallenge(hostname, hostname, path, expected)
+}
+
+func checkHTTPChallenge(address, hostname, path, expected string) error {
+	req, err := newRequest("GET", address, hostname, path)
+	if err != nil {
+		return err
+	}
+
+	resp, err := challengeClient.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "error requesting http-01 challenge: %s", hostname)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("invalid response requesting http-01 challenge: %s - %s", hostname, resp.Status)
+	}
+
+	b, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxChallengeResponse))
+	if err != nil {
+		return errors.Wrapf(err, "error reading http-01 challenge: %s", hostname)
+	}
+
+	if body := strings.TrimSpace(string(b)); body != expected {
+		return errors.Errorf("invalid http-01 challenge response: %s - expected %q, got %q", hostname, expected, body)
+	}
+	return nil
+}
+
+// challengeClient follows redirects the way the CA does.
+// It only follows redirects to HTTP and HTTPS in their
+// standard ports, and it doesn't verify HTTPS certificates,
+// because the domain doesn't have a valid certificate yet.
+var challengeClient = &http.Client{
+	Timeout: challengeTimeout,
+	Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxChallengeRedirects {
+			return errors.Errorf("too many redirects requesting http-01 challenge: %d", len(via))
+		}
+
+		if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+			return errors.Errorf("invalid redirect scheme requesting http-01 challenge: %s", req.URL)
+		}
+
+		// The first request is always sent to port 80,
+		// unless the address is set to check the challenge locally.
+		port := req.URL.Port()
+		if port != "" && port != "80" && port != "443" && port != via[0].URL.Port() {
+			return errors.Errorf("invalid redirect port requesting http-01 challenge: %s", req.URL)
+		}
+		return nil
+	},
+}
@@ -0,0 +1,58 @@
+package validator
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckHTTPChallenge(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/acme-challenge/token", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "test.cabal.io", r.Host)
+		w.Write([]byte("token.thumbprint\n"))
+	})
+	mux.HandleFunc("/.well-known/acme-challenge/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/.well-known/acme-challenge/token", http.StatusFound)
+	})
+	mux.HandleFunc("/.well-known/acme-challenge/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, r.URL.Path, http.StatusFound)
+	})
+	mux.HandleFunc("/.well-known/acme-challenge/ftp", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "ftp://test.cabal.io/token", http.StatusFound)
+	})
+	mux.HandleFunc("/.well-known/acme-challenge/port", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://test.cabal.io:8080/token", http.StatusFound)
+	})
+
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+	address := strings.TrimPrefix(ts.URL, "http://")
+
+	err := checkHTTPChallenge(address, "test.cabal.io", "/.well-known/acme-challenge/token", "token.thumbprint")
+	require.NoError(t, err)
+
+	err = checkHTTPChallenge(address, "test.cabal.io", "/.well-known/acme-challenge/redirect", "token.thumbprint")
+	require.NoError(t, err)
+
+	err = checkHTTPChallenge(address, "test.cabal.io", "/.well-known/acme-challenge/token", "other.thumbprint")
+	require.EqualError(t, err, `invalid http-01 challenge response: test.cabal.io - expected "other.thumbprint", got "token.thumbprint"`)
+
+	err = checkHTTPChallenge(address, "test.cabal.io", "/.well-known/acme-challenge/missing", "token.thumbprint")
+	require.EqualError(t, err, "invalid response requesting http-01 challenge: test.cabal.io - 404 Not Found")
+
+	err = checkHTTPChallenge(address, "test.cabal.io", "/.well-known/acme-challenge/loop", "token.thumbprint")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "too many redirects")
+
+	err = checkHTTPChallenge(address, "test.cabal.io", "/.well-known/acme-challenge/ftp", "token.thumbprint")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid redirect scheme")
+
+	err = checkHTTPChallenge(address, "test.cabal.io", "/.well-known/acme-challenge/port", "token.thumbprint")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid redirect port")
+}
@@ -24,15 +24,10 @@ func ValidHeader(hostname, header, expected string) bool {
 // It returns an error if the server doesn't reply with a 2xx or 3xx
 // status code or the header is not present.
 func readHeader(address, hostname, header string) (string, error) {
-	u := &url.URL{
-		Scheme: "http",
-		Host:   address,
-	}
-	req, err := http.NewRequest("HEAD", u.String(), nil)
+	req, err := newRequest("HEAD", address, hostname, "")
 	if err != nil {
 		return "", err
 	}
-	req.Host = hostname
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
@@ -50,3 +45,20 @@ func readHeader(address, hostname, header string) (string, error) {
 
 	return v, nil
 }
+
+// newRequest builds an HTTP request for a path in the given address,
+// asking for the hostname information.
+func newRequest(method, address, hostname, path string) (*http.Request, error) {
+	u := &url.URL{
+		Scheme: "http",
+		Host:   address,
+		Path:   path,
+	}
+	req, err := http.NewRequest(method, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Host = hostname
+
+	return req, nil
+}
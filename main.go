@@ -3,15 +3,18 @@ package main
 import (
 	"flag"
 	"fmt"
-	"io/ioutil"
 	"net"
+	"net/http"
 	"os"
+	"path/filepath"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 
 	"github.com/lost-mountain/isard/broker"
 	"github.com/lost-mountain/isard/configuration"
+	"github.com/lost-mountain/isard/renewal"
+	"github.com/lost-mountain/isard/responder"
 	"github.com/lost-mountain/isard/rpc"
 	"github.com/lost-mountain/isard/rpc/api"
 	"github.com/lost-mountain/isard/storage"
@@ -52,15 +55,22 @@ func main() {
 		}
 		bucket = s
 	} else {
-		queue = broker.NewChannelBroker()
+		// The queue and the storage must survive restarts,
+		// so they are never kept in a temporary directory.
+		dir := config.Bolt.Directory
+		if dir == "" {
+			fmt.Println("the Bolt directory is missing, set Bolt.Directory in the configuration file")
+			os.Exit(1)
+		}
 
-		f, err := ioutil.TempFile("", "isard-")
+		b, err := broker.NewBoltBroker(filepath.Join(dir, "queue.db"))
 		if err != nil {
 			fmt.Println(err)
 			os.Exit(1)
 		}
+		queue = b
 
-		s, err := storage.NewBoltBucket(f.Name())
+		s, err := storage.NewBoltBucket(filepath.Join(dir, "isard.db"))
 		if err != nil {
 			fmt.Println(err)
 			os.Exit(1)
@@ -68,6 +78,15 @@ func main() {
 		bucket = s
 	}
 
+	for topic, c := range config.Retries {
+		p, err := broker.NewRetryPolicy(c)
+		if err != nil {
+			fmt.Printf("invalid retry configuration for topic %s: %v\n", topic, err)
+			os.Exit(1)
+		}
+		queue.SetRetryPolicy(broker.TopicType(topic), p)
+	}
+
 	var server *grpc.Server
 	if config.TLS != nil {
 		creds, err := credentials.NewServerTLSFromFile(config.TLS.CertFile, config.TLS.KeyFile)
@@ -90,6 +109,29 @@ func main() {
 	proc := broker.NewDomainProcessor(bucket, queue, config.Domains)
 	queue.Subscribe(proc)
 
+	scheduler := renewal.NewScheduler(bucket, queue, config.Renewal)
+	scheduler.Start()
+
+	if config.HTTPResponder != nil {
+		l, err := net.Listen("tcp", config.HTTPResponder.Address)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		go http.Serve(l, responder.NewHTTPResponder(bucket, config.HTTPResponder))
+	}
+
+	if config.TLSALPNResponder != nil {
+		l, err := net.Listen("tcp", config.TLSALPNResponder.Address)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		go responder.NewTLSALPNResponder(bucket).Serve(l)
+	}
+
 	api := api.NewAPI(bucket, queue, config)
 	rpc.RegisterAPIServer(server, api)
 
@@ -0,0 +1,253 @@
+package renewal
+
+import (
+	"log"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lost-mountain/isard/account"
+	"github.com/lost-mountain/isard/broker"
+	"github.com/lost-mountain/isard/certificates"
+	"github.com/lost-mountain/isard/configuration"
+	"github.com/lost-mountain/isard/cryptopolis"
+	"github.com/lost-mountain/isard/domain"
+	"github.com/lost-mountain/isard/storage"
+	"github.com/pkg/errors"
+)
+
+const (
+	// defaultInterval is how often certificates are checked by default.
+	defaultInterval = time.Hour
+	// defaultWindow renews certificates in the last third of their lifetime.
+	defaultWindow = 1.0 / 3
+	// defaultRenewalInfoCheck is how long the renewal information is cached
+	// when the CA doesn't say when to request it again.
+	defaultRenewalInfoCheck = 6 * time.Hour
+)
+
+// renewalInfoClient defines the operation to ask the CA
+// when to renew a certificate. certificates.Client implements it.
+type renewalInfoClient interface {
+	GetRenewalInfo(cert *cryptopolis.Certificate) (*certificates.RenewalInfo, error)
+}
+
+// renewalSchedule is the renewal time selected
+// from the CA's renewal information.
+type renewalSchedule struct {
+	start, end     time.Time
+	renewAt        time.Time
+	explanationURL string
+	nextCheck      time.Time
+}
+
+// Scheduler checks issued certificates periodically and
+// sends the ones close to expire back to the broker to renew them.
+// It asks the CA when to renew each certificate, and it falls back
+// to the renewal window when the CA doesn't support it.
+type Scheduler struct {
+	bucket    storage.Bucket
+	broker    broker.Broker
+	interval  time.Duration
+	window    float64
+	jitter    time.Duration
+	newClient func(*account.Account) (renewalInfoClient, error)
+	schedules map[string]*renewalSchedule
+
+	exit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// Start checks the certificates every interval
+// in the background, until the scheduler is closed.
+func (s *Scheduler) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		t := time.NewTicker(s.interval)
+		defer t.Stop()
+
+		for {
+			// Certificates that fail to be scheduled
+			// are checked again in the next interval.
+			s.Scan()
+
+			select {
+			case <-t.C:
+			case <-s.exit:
+				return
+			}
+		}
+	}()
+}
+
+// Close stops checking certificates.
+func (s *Scheduler) Close() error {
+	close(s.exit)
+	s.wg.Wait()
+	return nil
+}
+
+// Scan publishes a renewal for every issued certificate
+// inside the renewal window. Each renewal is delayed
+// a random time, up to the jitter, to spread the load.
+// Renewals that fail to be published don't stop the scan,
+// they are checked again in the next one.
+func (s *Scheduler) Scan() error {
+	domains, err := s.bucket.ListDomains(domain.Issued)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	clients := map[uuid.UUID]renewalInfoClient{}
+	seen := map[string]bool{}
+	var failed []string
+	for _, d := range domains {
+		if d.Certificate == nil {
+			continue
+		}
+		seen[d.Certificate.RenewalInfoID] = true
+
+		renewAt, explanationURL := s.renewalTime(clients, d)
+		if now.Before(renewAt) {
+			continue
+		}
+
+		p := &broker.RenewDomainPayload{
+			AccountID:      d.Account.ID,
+			DomainName:     d.Name,
+			NotAfter:       d.Certificate.NotAfter,
+			ExplanationURL: explanationURL,
+		}
+
+		if err := s.broker.PublishAfter(broker.Renewal, p, s.delay()); err != nil {
+			log.Printf("error scheduling renewal for domain %s: %v", d.Name, err)
+			failed = append(failed, d.Name)
+			continue
+		}
+		delete(s.schedules, d.Certificate.RenewalInfoID)
+	}
+
+	// Forget the certificates that are no longer issued,
+	// like revoked, cancelled or replaced certificates.
+	for id := range s.schedules {
+		if !seen[id] {
+			delete(s.schedules, id)
+		}
+	}
+
+	if len(failed) > 0 {
+		return errors.Errorf("error scheduling renewal for domains: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// renewalTime decides when to renew a domain's certificate,
+// and the URL where the CA explains why, if any.
+// It uses the CA's renewal information when it's available,
+// otherwise it uses the renewal window.
+func (s *Scheduler) renewalTime(clients map[uuid.UUID]renewalInfoClient, d *domain.Domain) (time.Time, string) {
+	cert := d.Certificate
+	if s.newClient == nil || cert.RenewalInfoID == "" {
+		return windowTime(cert, s.window), ""
+	}
+
+	sch, ok := s.schedules[cert.RenewalInfoID]
+	if ok && time.Now().Before(sch.nextCheck) {
+		return sch.renewAt, sch.explanationURL
+	}
+
+	c, ok := clients[d.Account.ID]
+	if !ok {
+		var err error
+		c, err = s.newClient(d.Account)
+		if err != nil {
+			return windowTime(cert, s.window), ""
+		}
+		clients[d.Account.ID] = c
+	}
+
+	info, err := c.GetRenewalInfo(cert)
+	if err != nil {
+		if sch != nil {
+			return sch.renewAt, sch.explanationURL
+		}
+		return windowTime(cert, s.window), ""
+	}
+
+	wait := info.RetryAfter
+	if wait <= 0 {
+		wait = defaultRenewalInfoCheck
+	}
+
+	// Keep the time already selected while the window doesn't change.
+	start, end := info.SuggestedWindow.Start, info.SuggestedWindow.End
+	if sch == nil || !sch.start.Equal(start) || !sch.end.Equal(end) {
+		sch = &renewalSchedule{
+			start:   start,
+			end:     end,
+			renewAt: info.RenewalTime(),
+		}
+		s.schedules[cert.RenewalInfoID] = sch
+	}
+	sch.explanationURL = info.ExplanationURL
+	sch.nextCheck = time.Now().Add(wait)
+
+	return sch.renewAt, sch.explanationURL
+}
+
+func (s *Scheduler) delay() time.Duration {
+	if s.jitter <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(s.jitter)))
+}
+
+// windowTime returns when a certificate enters the renewal window,
+// the last fraction of its lifetime before it expires.
+func windowTime(c *cryptopolis.Certificate, window float64) time.Time {
+	lifetime := c.NotAfter.Sub(c.NotBefore)
+	return c.NotAfter.Add(-time.Duration(float64(lifetime) * window))
+}
+
+// NewScheduler initializes a renewal scheduler.
+// Values missing in the configuration take default values.
+// The jitter defaults to the interval, so the renewals found
+// in one check are spread until the next one.
+func NewScheduler(bucket storage.Bucket, broker broker.Broker, config *configuration.RenewalConfiguration) *Scheduler {
+	if config == nil {
+		config = &configuration.RenewalConfiguration{}
+	}
+
+	s := &Scheduler{
+		bucket:    bucket,
+		broker:    broker,
+		interval:  config.Interval.Duration,
+		window:    config.Window,
+		jitter:    config.Jitter.Duration,
+		schedules: map[string]*renewalSchedule{},
+		exit:      make(chan struct{}),
+	}
+
+	if !config.DisableRenewalInfo {
+		s.newClient = func(a *account.Account) (renewalInfoClient, error) {
+			return certificates.NewClient(a)
+		}
+	}
+
+	if s.interval <= 0 {
+		s.interval = defaultInterval
+	}
+	if s.window <= 0 || s.window > 1 {
+		s.window = defaultWindow
+	}
+	if s.jitter <= 0 {
+		s.jitter = s.interval
+	}
+
+	return s
+}
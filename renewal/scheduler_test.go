@@ -0,0 +1,256 @@
+package renewal
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lost-mountain/isard/account"
+	"github.com/lost-mountain/isard/broker"
+	"github.com/lost-mountain/isard/certificates"
+	"github.com/lost-mountain/isard/configuration"
+	"github.com/lost-mountain/isard/cryptopolis"
+	"github.com/lost-mountain/isard/domain"
+	"github.com/lost-mountain/isard/storage"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingBroker keeps the renewals published by the scheduler.
+// It fails to publish the renewals of the domains in failures.
+type recordingBroker struct {
+	broker.Broker
+	payloads []*broker.RenewDomainPayload
+	delays   []time.Duration
+	failures map[string]bool
+}
+
+func (b *recordingBroker) PublishAfter(topic broker.TopicType, payload interface{}, d time.Duration) error {
+	p := payload.(*broker.RenewDomainPayload)
+	if b.failures[p.DomainName] {
+		return errors.New("broker unavailable")
+	}
+
+	b.payloads = append(b.payloads, p)
+	b.delays = append(b.delays, d)
+	return nil
+}
+
+// fakeRenewalInfoClient returns the renewal information
+// stored for each certificate, and an error for the rest.
+type fakeRenewalInfoClient struct {
+	infos    map[string]*certificates.RenewalInfo
+	requests int
+}
+
+func (c *fakeRenewalInfoClient) GetRenewalInfo(cert *cryptopolis.Certificate) (*certificates.RenewalInfo, error) {
+	c.requests++
+	info, ok := c.infos[cert.RenewalInfoID]
+	if !ok {
+		return nil, certificates.ErrRenewalInfoUnsupported
+	}
+	return info, nil
+}
+
+func issuedDomain(t *testing.T, bucket storage.Bucket, a *account.Account, name, renewalInfoID string, notBefore, notAfter time.Time) {
+	d, err := domain.NewDomain(a, name)
+	require.NoError(t, err)
+
+	d.State = domain.Issued
+	d.Certificate = &cryptopolis.Certificate{
+		NotBefore:     notBefore,
+		NotAfter:      notAfter,
+		RenewalInfoID: renewalInfoID,
+	}
+	require.NoError(t, bucket.SaveDomain(d))
+}
+
+func newBucket(t *testing.T) (*storage.Bolt, *account.Account, func()) {
+	f, err := ioutil.TempFile("", "isard-")
+	require.NoError(t, err)
+
+	err = f.Close()
+	require.NoError(t, err)
+
+	bucket, err := storage.NewBoltBucket(f.Name())
+	require.NoError(t, err)
+
+	a, err := account.NewAccount("david.calavera@gmail.com")
+	require.NoError(t, err)
+
+	return bucket, a, func() {
+		bucket.Close()
+		os.Remove(f.Name())
+	}
+}
+
+func TestScan(t *testing.T) {
+	bucket, a, cleanup := newBucket(t)
+	defer cleanup()
+
+	day := 24 * time.Hour
+	now := time.Now()
+	expiring := now.Add(10 * day)
+	issuedDomain(t, bucket, a, "expiring.cabal.io", "", now.Add(-80*day), expiring)
+	issuedDomain(t, bucket, a, "fresh.cabal.io", "", now.Add(-10*day), now.Add(80*day))
+
+	b := &recordingBroker{}
+	s := NewScheduler(bucket, b, &configuration.RenewalConfiguration{
+		Jitter:             configuration.Duration{Duration: time.Minute},
+		DisableRenewalInfo: true,
+	})
+	require.NoError(t, s.Scan())
+
+	require.Len(t, b.payloads, 1)
+	require.Equal(t, a.ID, b.payloads[0].AccountID)
+	require.Equal(t, "expiring.cabal.io", b.payloads[0].DomainName)
+	require.True(t, expiring.Equal(b.payloads[0].NotAfter))
+	require.True(t, b.delays[0] >= 0 && b.delays[0] < time.Minute)
+}
+
+func TestScanAfterFailedRenewal(t *testing.T) {
+	bucket, a, cleanup := newBucket(t)
+	defer cleanup()
+
+	day := 24 * time.Hour
+	now := time.Now()
+	expiring := now.Add(10 * day)
+	issuedDomain(t, bucket, a, "expiring.cabal.io", "", now.Add(-80*day), expiring)
+
+	// The processor moves the domain back to issued when the renewal fails.
+	d, err := bucket.GetDomain(a.ID, "expiring.cabal.io")
+	require.NoError(t, err)
+	require.NoError(t, d.TransitionTo(domain.Provisioning, "renewal started", uuid.New(), nil))
+	require.NoError(t, d.TransitionTo(domain.Issued, "renewal failed, the current certificate is kept", uuid.New(), errors.New("authorization failed")))
+	require.NoError(t, bucket.SaveDomain(d))
+
+	b := &recordingBroker{}
+	s := NewScheduler(bucket, b, &configuration.RenewalConfiguration{DisableRenewalInfo: true})
+	require.NoError(t, s.Scan())
+
+	require.Len(t, b.payloads, 1)
+	require.Equal(t, "expiring.cabal.io", b.payloads[0].DomainName)
+	require.True(t, expiring.Equal(b.payloads[0].NotAfter))
+}
+
+func TestScanPublishErrors(t *testing.T) {
+	bucket, a, cleanup := newBucket(t)
+	defer cleanup()
+
+	day := 24 * time.Hour
+	now := time.Now()
+	issuedDomain(t, bucket, a, "a.cabal.io", "", now.Add(-80*day), now.Add(10*day))
+	issuedDomain(t, bucket, a, "b.cabal.io", "", now.Add(-80*day), now.Add(10*day))
+
+	b := &recordingBroker{failures: map[string]bool{"a.cabal.io": true}}
+	s := NewScheduler(bucket, b, &configuration.RenewalConfiguration{DisableRenewalInfo: true})
+
+	// The renewals after a failure are still published.
+	err := s.Scan()
+	require.EqualError(t, err, "error scheduling renewal for domains: a.cabal.io")
+	require.Len(t, b.payloads, 1)
+	require.Equal(t, "b.cabal.io", b.payloads[0].DomainName)
+}
+
+func TestScanForgetsRemovedCertificates(t *testing.T) {
+	bucket, a, cleanup := newBucket(t)
+	defer cleanup()
+
+	day := 24 * time.Hour
+	now := time.Now()
+	issuedDomain(t, bucket, a, "fresh.cabal.io", "fresh.AQ", now.Add(-10*day), now.Add(80*day))
+
+	info := &certificates.RenewalInfo{}
+	info.SuggestedWindow.Start = now.Add(50 * day)
+	info.SuggestedWindow.End = now.Add(51 * day)
+	ca := &fakeRenewalInfoClient{infos: map[string]*certificates.RenewalInfo{"fresh.AQ": info}}
+
+	s := NewScheduler(bucket, &recordingBroker{}, nil)
+	s.newClient = func(*account.Account) (renewalInfoClient, error) {
+		return ca, nil
+	}
+	require.NoError(t, s.Scan())
+	require.Contains(t, s.schedules, "fresh.AQ")
+
+	// The certificate is revoked, so it's no longer scheduled.
+	d, err := bucket.GetDomain(a.ID, "fresh.cabal.io")
+	require.NoError(t, err)
+	d.State = domain.Revoked
+	require.NoError(t, bucket.SaveDomain(d))
+
+	require.NoError(t, s.Scan())
+	require.Empty(t, s.schedules)
+}
+
+func TestScanRenewalInfo(t *testing.T) {
+	bucket, a, cleanup := newBucket(t)
+	defer cleanup()
+
+	day := 24 * time.Hour
+	now := time.Now()
+
+	// The CA asks to renew this certificate before its renewal window.
+	issuedDomain(t, bucket, a, "revoked.cabal.io", "revoked.AQ", now.Add(-10*day), now.Add(80*day))
+	// The CA asks to renew this certificate after its renewal window.
+	issuedDomain(t, bucket, a, "extended.cabal.io", "extended.AQ", now.Add(-80*day), now.Add(10*day))
+	// The CA doesn't know this certificate, it uses the renewal window.
+	issuedDomain(t, bucket, a, "unknown.cabal.io", "unknown.AQ", now.Add(-80*day), now.Add(10*day))
+
+	revoked := &certificates.RenewalInfo{ExplanationURL: "https://acme.example.com/incidents/1"}
+	revoked.SuggestedWindow.Start = now.Add(-2 * time.Hour)
+	revoked.SuggestedWindow.End = now.Add(-time.Hour)
+
+	extended := &certificates.RenewalInfo{}
+	extended.SuggestedWindow.Start = now.Add(5 * day)
+	extended.SuggestedWindow.End = now.Add(6 * day)
+
+	ca := &fakeRenewalInfoClient{
+		infos: map[string]*certificates.RenewalInfo{
+			"revoked.AQ":  revoked,
+			"extended.AQ": extended,
+		},
+	}
+
+	b := &recordingBroker{}
+	s := NewScheduler(bucket, b, nil)
+	s.newClient = func(*account.Account) (renewalInfoClient, error) {
+		return ca, nil
+	}
+	require.NoError(t, s.Scan())
+
+	renewals := map[string]string{}
+	for _, p := range b.payloads {
+		renewals[p.DomainName] = p.ExplanationURL
+	}
+	require.Equal(t, map[string]string{
+		"revoked.cabal.io": "https://acme.example.com/incidents/1",
+		"unknown.cabal.io": "",
+	}, renewals)
+
+	// The renewal information is cached until the CA asks to check it again.
+	requests := ca.requests
+	b.payloads = nil
+	require.NoError(t, s.Scan())
+	require.Equal(t, requests+2, ca.requests)
+	require.Len(t, b.payloads, 2)
+}
+
+func TestWindowTime(t *testing.T) {
+	now := time.Now()
+	c := &cryptopolis.Certificate{
+		NotBefore: now,
+		NotAfter:  now.Add(90 * time.Hour),
+	}
+
+	require.WithinDuration(t, now.Add(60*time.Hour), windowTime(c, defaultWindow), time.Millisecond)
+	require.WithinDuration(t, now.Add(45*time.Hour), windowTime(c, 0.5), time.Millisecond)
+}
+
+func TestNewSchedulerDefaults(t *testing.T) {
+	s := NewScheduler(nil, nil, nil)
+	require.Equal(t, defaultInterval, s.interval)
+	require.Equal(t, defaultWindow, s.window)
+	require.Equal(t, defaultInterval, s.jitter)
+}
@@ -0,0 +1,75 @@
+package responder
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/lost-mountain/isard/configuration"
+	"github.com/lost-mountain/isard/storage"
+)
+
+// challengePathPrefix is the path where the CA requests http-01 challenges.
+const challengePathPrefix = "/.well-known/acme-challenge/"
+
+// HTTPResponder answers http-01 challenge requests.
+// It serves the challenge response of the pending authorization
+// for the request host and token. Other requests are redirected
+// to HTTPS when the configuration enables it.
+type HTTPResponder struct {
+	bucket storage.Bucket
+	config *configuration.HTTPResponderConfiguration
+}
+
+// ServeHTTP implements http.Handler.
+func (r *HTTPResponder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	host := r.host(req)
+
+	if !strings.HasPrefix(req.URL.Path, challengePathPrefix) {
+		if r.config.RedirectHTTPS && (req.Method == "GET" || req.Method == "HEAD") {
+			http.Redirect(w, req, "https://"+host+req.URL.RequestURI(), http.StatusMovedPermanently)
+			return
+		}
+
+		http.NotFound(w, req)
+		return
+	}
+
+	token := strings.TrimPrefix(req.URL.Path, challengePathPrefix)
+	a, err := pendingAuthorization(r.bucket, host, "http-01", token)
+	if err != nil || a.HTTP01ChallengePath != req.URL.Path {
+		http.NotFound(w, req)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte(a.HTTP01ChallengeResponse))
+}
+
+// host returns the name of the domain in the request, without the port.
+// Proxies that forward challenge requests can set it in the
+// X-Forwarded-Host header when the configuration trusts them.
+func (r *HTTPResponder) host(req *http.Request) string {
+	host := req.Host
+	if f := req.Header.Get("X-Forwarded-Host"); f != "" && r.config.TrustForwardedHost {
+		host = strings.TrimSpace(strings.Split(f, ",")[0])
+	}
+
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
+
+// NewHTTPResponder initializes a responder that looks up
+// the pending challenges in the bucket.
+func NewHTTPResponder(bucket storage.Bucket, config *configuration.HTTPResponderConfiguration) *HTTPResponder {
+	if config == nil {
+		config = &configuration.HTTPResponderConfiguration{}
+	}
+
+	return &HTTPResponder{
+		bucket: bucket,
+		config: config,
+	}
+}
@@ -0,0 +1,101 @@
+package responder
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lost-mountain/isard/configuration"
+	"github.com/lost-mountain/isard/domain"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/acme"
+)
+
+func TestHTTPResponder(t *testing.T) {
+	bucket, acc := newBucket(t)
+	saveProvisioningDomain(t, bucket, acc, "test.cabal.io",
+		&domain.Authorization{
+			Name:                    "test.cabal.io",
+			Status:                  acme.StatusPending,
+			ChallengeType:           "http-01",
+			HTTP01ChallengePath:     "/.well-known/acme-challenge/token",
+			HTTP01ChallengeResponse: "token.thumbprint",
+		},
+		&domain.Authorization{
+			Name:          "www.test.cabal.io",
+			Status:        acme.StatusValid,
+			ChallengeType: "http-01",
+		})
+
+	h := NewHTTPResponder(bucket, nil)
+
+	tests := []struct {
+		host   string
+		path   string
+		status int
+		body   string
+	}{
+		{"test.cabal.io", "/.well-known/acme-challenge/token", http.StatusOK, "token.thumbprint"},
+		{"test.cabal.io:80", "/.well-known/acme-challenge/token", http.StatusOK, "token.thumbprint"},
+		{"test.cabal.io", "/.well-known/acme-challenge/other", http.StatusNotFound, ""},
+		{"www.test.cabal.io", "/.well-known/acme-challenge/token", http.StatusNotFound, ""},
+		{"unknown.cabal.io", "/.well-known/acme-challenge/token", http.StatusNotFound, ""},
+		{"test.cabal.io", "/index.html", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "http://"+tt.host+tt.path, nil)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		require.Equal(t, tt.status, w.Code, "%s%s", tt.host, tt.path)
+		if tt.body != "" {
+			require.Equal(t, tt.body, w.Body.String())
+		}
+	}
+}
+
+func TestHTTPResponderRedirectHTTPS(t *testing.T) {
+	bucket, _ := newBucket(t)
+	h := NewHTTPResponder(bucket, &configuration.HTTPResponderConfiguration{RedirectHTTPS: true})
+
+	req := httptest.NewRequest("GET", "http://test.cabal.io:80/index.html?q=1", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusMovedPermanently, w.Code)
+	require.Equal(t, "https://test.cabal.io/index.html?q=1", w.Header().Get("Location"))
+
+	// Challenges that are not pending are never redirected.
+	req = httptest.NewRequest("GET", "http://test.cabal.io/.well-known/acme-challenge/token", nil)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	require.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestHTTPResponderForwardedHost(t *testing.T) {
+	bucket, acc := newBucket(t)
+	saveProvisioningDomain(t, bucket, acc, "test.cabal.io",
+		&domain.Authorization{
+			Name:                    "test.cabal.io",
+			Status:                  acme.StatusPending,
+			ChallengeType:           "http-01",
+			HTTP01ChallengePath:     "/.well-known/acme-challenge/token",
+			HTTP01ChallengeResponse: "token.thumbprint",
+		})
+
+	newRequest := func() *http.Request {
+		req := httptest.NewRequest("GET", "http://isard.internal:8080/.well-known/acme-challenge/token", nil)
+		req.Header.Set("X-Forwarded-Host", "test.cabal.io, edge.cabal.io")
+		return req
+	}
+
+	w := httptest.NewRecorder()
+	NewHTTPResponder(bucket, nil).ServeHTTP(w, newRequest())
+	require.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	NewHTTPResponder(bucket, &configuration.HTTPResponderConfiguration{TrustForwardedHost: true}).ServeHTTP(w, newRequest())
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "token.thumbprint", w.Body.String())
+}
@@ -0,0 +1,25 @@
+// Package responder answers the CA's challenge requests
+// for the domains that are being authorized.
+package responder
+
+import (
+	"github.com/lost-mountain/isard/domain"
+	"github.com/lost-mountain/isard/storage"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/acme"
+)
+
+// pendingAuthorization finds the pending authorization for a name,
+// prepared to be resolved with a challenge type and token,
+// in the index of prepared challenges.
+func pendingAuthorization(bucket storage.Bucket, name, challengeType, token string) (*domain.Authorization, error) {
+	a, err := bucket.GetChallenge(challengeType, name, token)
+	if err != nil {
+		return nil, errors.Wrapf(err, "there is no pending %s challenge for domain: %s", challengeType, name)
+	}
+
+	if a.Status != acme.StatusPending || !a.Prepared() {
+		return nil, errors.Errorf("there is no pending %s challenge for domain: %s", challengeType, name)
+	}
+	return a, nil
+}
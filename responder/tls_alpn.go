@@ -0,0 +1,81 @@
+package responder
+
+import (
+	"crypto/tls"
+	"net"
+	"time"
+
+	"github.com/lost-mountain/isard/storage"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/acme"
+)
+
+// handshakeTimeout is how long the responder waits for a challenge handshake to complete.
+const handshakeTimeout = 10 * time.Second
+
+// TLSALPNResponder answers tls-alpn-01 challenge handshakes.
+// It serves the acme-tls/1 certificate of the pending authorization
+// for the server name, and closes the connection after the handshake.
+// Handshakes for other protocols are rejected.
+type TLSALPNResponder struct {
+	bucket storage.Bucket
+	config *tls.Config
+}
+
+// GetCertificate returns the challenge certificate
+// for the server name in the handshake.
+func (r *TLSALPNResponder) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	if !supportsALPN(hello.SupportedProtos) {
+		return nil, errors.Errorf("the handshake for %s is not an ACME challenge", hello.ServerName)
+	}
+
+	a, err := pendingAuthorization(r.bucket, hello.ServerName, "tls-alpn-01", "")
+	if err != nil {
+		return nil, err
+	}
+
+	cert, err := tls.X509KeyPair(a.TLSALPN01ChallengeCert, a.TLSALPN01ChallengeKey)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid tls-alpn-01 certificate for domain: %s", hello.ServerName)
+	}
+	return &cert, nil
+}
+
+// Serve accepts connections in the listener and
+// completes their handshake, until the listener is closed.
+func (r *TLSALPNResponder) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+
+		go func() {
+			conn.SetDeadline(time.Now().Add(handshakeTimeout))
+			c := tls.Server(conn, r.config)
+			c.Handshake()
+			c.Close()
+		}()
+	}
+}
+
+func supportsALPN(protos []string) bool {
+	for _, p := range protos {
+		if p == acme.ALPNProto {
+			return true
+		}
+	}
+	return false
+}
+
+// NewTLSALPNResponder initializes a responder that looks up
+// the pending challenges in the bucket.
+func NewTLSALPNResponder(bucket storage.Bucket) *TLSALPNResponder {
+	r := &TLSALPNResponder{bucket: bucket}
+	r.config = &tls.Config{
+		NextProtos:     []string{acme.ALPNProto},
+		GetCertificate: r.GetCertificate,
+		MinVersion:     tls.VersionTLS12,
+	}
+	return r
+}
@@ -0,0 +1,112 @@
+package responder
+
+import (
+	"crypto/tls"
+	"encoding/asn1"
+	"io/ioutil"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/lost-mountain/isard/account"
+	"github.com/lost-mountain/isard/certificates/challenges"
+	"github.com/lost-mountain/isard/domain"
+	"github.com/lost-mountain/isard/storage"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/acme"
+)
+
+// idPeAcmeIdentifier is the extension that carries the
+// key authorization in tls-alpn-01 certificates.
+var idPeAcmeIdentifier = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 1, 31}
+
+func newBucket(t *testing.T) (storage.Bucket, *account.Account) {
+	f, err := ioutil.TempFile("", "isard-")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	bucket, err := storage.NewBoltBucket(f.Name())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		bucket.Close()
+		os.Remove(f.Name())
+	})
+
+	a, err := account.NewAccount("david.calavera@gmail.com")
+	require.NoError(t, err)
+	require.NoError(t, bucket.SaveAccount(a))
+
+	return bucket, a
+}
+
+// saveProvisioningDomain stores a domain being authorized,
+// and indexes the challenges prepared for its authorizations.
+func saveProvisioningDomain(t *testing.T, bucket storage.Bucket, acc *account.Account, name string, auths ...*domain.Authorization) {
+	d, err := domain.NewDomain(acc, name)
+	require.NoError(t, err)
+	d.State = domain.Provisioning
+	d.Authorizations = auths
+	require.NoError(t, bucket.SaveDomain(d))
+
+	for _, a := range auths {
+		if a.Prepared() {
+			require.NoError(t, bucket.SaveChallenge(a))
+		}
+	}
+}
+
+func TestTLSALPNResponder(t *testing.T) {
+	bucket, acc := newBucket(t)
+
+	pk, err := acc.PrivateKey()
+	require.NoError(t, err)
+
+	a := &domain.Authorization{Name: "test.cabal.io", Status: acme.StatusPending, ChallengeType: "tls-alpn-01"}
+	err = challenges.NewTLSALPNResolver(&acme.Client{Key: pk}).Resolve(a, &acme.Challenge{Token: "token"})
+	require.NoError(t, err)
+	saveProvisioningDomain(t, bucket, acc, "test.cabal.io", a)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	go NewTLSALPNResponder(bucket).Serve(l)
+
+	conn, err := tls.Dial("tcp", l.Addr().String(), &tls.Config{
+		ServerName:         "test.cabal.io",
+		NextProtos:         []string{acme.ALPNProto},
+		InsecureSkipVerify: true,
+	})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	state := conn.ConnectionState()
+	require.Equal(t, acme.ALPNProto, state.NegotiatedProtocol)
+
+	leaf := state.PeerCertificates[0]
+	require.Equal(t, []string{"test.cabal.io"}, leaf.DNSNames)
+
+	var found bool
+	for _, e := range leaf.Extensions {
+		if e.Id.Equal(idPeAcmeIdentifier) {
+			found = e.Critical
+		}
+	}
+	require.True(t, found, "the certificate doesn't have a critical acmeIdentifier extension")
+}
+
+func TestTLSALPNResponderRejectsHandshakes(t *testing.T) {
+	bucket, acc := newBucket(t)
+	saveProvisioningDomain(t, bucket, acc, "test.cabal.io",
+		&domain.Authorization{Name: "test.cabal.io", Status: acme.StatusPending, ChallengeType: "http-01"})
+
+	r := NewTLSALPNResponder(bucket)
+
+	_, err := r.GetCertificate(&tls.ClientHelloInfo{ServerName: "test.cabal.io", SupportedProtos: []string{"h2"}})
+	require.EqualError(t, err, "the handshake for test.cabal.io is not an ACME challenge")
+
+	_, err = r.GetCertificate(&tls.ClientHelloInfo{ServerName: "test.cabal.io", SupportedProtos: []string{acme.ALPNProto}})
+	require.EqualError(t, err, "there is no pending tls-alpn-01 challenge for domain: test.cabal.io: error retrieving challenge for domain test.cabal.io: challenge not found")
+
+	_, err = r.GetCertificate(&tls.ClientHelloInfo{ServerName: "unknown.cabal.io", SupportedProtos: []string{acme.ALPNProto}})
+	require.Error(t, err)
+}
@@ -1,12 +1,16 @@
 package api
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
 
 	"github.com/lost-mountain/isard/account"
 	"github.com/lost-mountain/isard/broker"
+	"github.com/lost-mountain/isard/certificates/challenges"
 	"github.com/lost-mountain/isard/configuration"
+	"github.com/lost-mountain/isard/cryptopolis"
 	"github.com/lost-mountain/isard/domain"
 	"github.com/lost-mountain/isard/rpc"
 	"github.com/lost-mountain/isard/storage"
@@ -23,6 +27,8 @@ type API struct {
 }
 
 // CreateAccount creates a new domain account.
+// The account can use its own DNS provider credentials
+// to resolve dns-01 challenges.
 func (a *API) CreateAccount(ctx context.Context, req *rpc.CreateAccountRequest) (*rpc.CreateAccountResponse, error) {
 	var (
 		acc *account.Account
@@ -39,6 +45,24 @@ func (a *API) CreateAccount(ctx context.Context, req *rpc.CreateAccountRequest)
 		return nil, err
 	}
 
+	acc.KeyType, err = cryptopolis.ParseKeyType(req.KeyType)
+	if err != nil {
+		return nil, err
+	}
+
+	if p := req.DnsProvider; p != nil {
+		options := make(map[string]string, len(p.Options))
+		for _, o := range p.Options {
+			options[o.Name] = o.Value
+		}
+
+		if _, err := challenges.NewDNSProvider(p.Name, options); err != nil {
+			return nil, err
+		}
+		acc.DNSProvider = p.Name
+		acc.DNSProviderOptions = options
+	}
+
 	if req.Environment == rpc.AccountEnvironment_PRODUCTION {
 		acc.DirectoryURL = a.configuration.ACME.DefaultProductionDirectory
 	}
@@ -59,7 +83,9 @@ func (a *API) UpdateAccount(context.Context, *rpc.UpdateAccountRequest) (*rpc.Up
 }
 
 // CreateCertificate starts the process to request a domain certificate.
-// It creates a new domain and negotiates the challenge type.
+// It creates a new domain and negotiates the challenge type,
+// falling back through the ordered challenge types when validation fails.
+// The key type overrides the account's key type for this certificate.
 func (a *API) CreateCertificate(ctx context.Context, req *rpc.CreateCertificateRequest) (*rpc.CreateCertificateResponse, error) {
 	accID, err := uuid.Parse(req.AccountID)
 	if err != nil {
@@ -70,32 +96,212 @@ func (a *API) CreateCertificate(ctx context.Context, req *rpc.CreateCertificateR
 		return nil, errors.Wrap(err, "invalid account token format")
 	}
 
+	if _, err := cryptopolis.ParseKeyType(req.KeyType); err != nil {
+		return nil, err
+	}
+
 	c := &broker.CreateDomainPayload{
-		AccountID:     accID,
-		AccountToken:  accountToken,
-		DomainName:    req.Domain,
-		ChallengeType: req.ChallengeType,
+		AccountID:      accID,
+		AccountToken:   accountToken,
+		DomainName:     req.Domain,
+		ChallengeType:  req.ChallengeType,
+		ChallengeTypes: req.ChallengeTypes,
+		KeyType:        req.KeyType,
+		ReuseKey:       req.ReuseKey,
 	}
 
-	m := broker.NewMessage(c)
-	if err := a.broker.Publish(broker.Creation, m); err != nil {
+	if err := a.broker.Publish(broker.Creation, c); err != nil {
 		return nil, err
 	}
 
 	return &rpc.CreateCertificateResponse{}, nil
 }
 
+// ModifyCertificate starts the process to add and remove names
+// from a domain certificate. The CA only authorizes the names added,
+// and the current certificate is served until the new one is issued.
+// Only domains with an issued certificate can be modified.
+func (a *API) ModifyCertificate(ctx context.Context, req *rpc.ModifyCertificateRequest) (*rpc.ModifyCertificateResponse, error) {
+	accID, err := uuid.Parse(req.AccountID)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid account ID format")
+	}
+	accountToken, err := uuid.Parse(req.AccountToken)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid account token format")
+	}
+
+	acc, err := a.bucket.GetAccount(accID, accountToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(req.AddNames) == 0 && len(req.RemoveNames) == 0 {
+		return nil, errors.New("there are no names to modify")
+	}
+
+	d, err := a.bucket.GetDomain(acc.ID, req.Domain)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.State != domain.Issued {
+		return nil, errors.Errorf("unable to modify domain %s in state: %s", d.Name, d.State)
+	}
+
+	m := &broker.ModifyDomainPayload{
+		AccountID:   acc.ID,
+		DomainName:  req.Domain,
+		AddNames:    req.AddNames,
+		RemoveNames: req.RemoveNames,
+	}
+
+	if err := a.broker.Publish(broker.Modification, m); err != nil {
+		return nil, err
+	}
+
+	return &rpc.ModifyCertificateResponse{}, nil
+}
+
+// CancelCertificate aborts the process to request a domain certificate.
+// It deactivates the domain's pending authorization in the CA and
+// cleans up its challenge before marking the domain as cancelled.
+func (a *API) CancelCertificate(ctx context.Context, req *rpc.CancelCertificateRequest) (*rpc.CancelCertificateResponse, error) {
+	accID, err := uuid.Parse(req.AccountID)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid account ID format")
+	}
+	accountToken, err := uuid.Parse(req.AccountToken)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid account token format")
+	}
+
+	acc, err := a.bucket.GetAccount(accID, accountToken)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &broker.DomainPayload{
+		AccountID:  acc.ID,
+		DomainName: req.Domain,
+	}
+
+	if err := a.broker.Publish(broker.Cancellation, c); err != nil {
+		return nil, err
+	}
+
+	return &rpc.CancelCertificateResponse{}, nil
+}
+
+// RevokeCertificate starts the process to revoke a domain certificate.
+// The CA authenticates the revocation with the account key,
+// or with the certificate key when the request asks for it.
+// Only domains with an issued certificate can be revoked.
+func (a *API) RevokeCertificate(ctx context.Context, req *rpc.RevokeCertificateRequest) (*rpc.RevokeCertificateResponse, error) {
+	accID, err := uuid.Parse(req.AccountID)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid account ID format")
+	}
+	accountToken, err := uuid.Parse(req.AccountToken)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid account token format")
+	}
+
+	acc, err := a.bucket.GetAccount(accID, accountToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := rpc.RevocationReason_name[int32(req.Reason)]; !ok {
+		return nil, errors.Errorf("invalid revocation reason: %d", req.Reason)
+	}
+
+	d, err := a.bucket.GetDomain(acc.ID, req.Domain)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.State != domain.Issued || d.Certificate == nil {
+		return nil, errors.Errorf("unable to revoke domain %s in state: %s", d.Name, d.State)
+	}
+
+	r := &broker.RevokeDomainPayload{
+		AccountID:         acc.ID,
+		DomainName:        req.Domain,
+		Reason:            int(req.Reason),
+		UseCertificateKey: req.UseCertificateKey,
+	}
+
+	if err := a.broker.Publish(broker.Revocation, r); err != nil {
+		return nil, err
+	}
+
+	return &rpc.RevokeCertificateResponse{}, nil
+}
+
 // ResolveCertificateChallenge returns the information required to resolve a challenge.
 func (a *API) ResolveCertificateChallenge(context.Context, *rpc.ResolveChallengeRequest) (*rpc.ResolveChallengeResponse, error) {
 	return nil, nil
 }
 
-// CheckCertificateState returns the state of a certificate.
-func (a *API) CheckCertificateState(context.Context, *rpc.CertificateStateRequest) (*rpc.CertificateStateResponse, error) {
-	return nil, nil
+// CheckCertificateState returns the state of a certificate
+// and the timeline of transitions that led to it.
+// It also reports the challenge type used to authorize each name.
+func (a *API) CheckCertificateState(ctx context.Context, req *rpc.CertificateStateRequest) (*rpc.CertificateStateResponse, error) {
+	accID, err := uuid.Parse(req.AccountID)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid account ID format")
+	}
+	accountToken, err := uuid.Parse(req.AccountToken)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid account token format")
+	}
+
+	acc, err := a.bucket.GetAccount(accID, accountToken)
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := a.bucket.GetDomain(acc.ID, req.Domain)
+	if err != nil {
+		return nil, err
+	}
+
+	timeline := make([]*rpc.StateTransition, 0, len(d.History))
+	for _, t := range d.History {
+		timeline = append(timeline, &rpc.StateTransition{
+			From:  t.From.String(),
+			To:    t.To.String(),
+			Cause: t.Cause,
+			JobID: t.JobUUID.String(),
+			Error: t.Error,
+			Time:  t.Time.Format(time.RFC3339),
+		})
+	}
+
+	authorizations := make([]*rpc.AuthorizationState, 0, len(d.Authorizations))
+	for _, a := range d.Authorizations {
+		authorizations = append(authorizations, &rpc.AuthorizationState{
+			Name:                 a.Name,
+			Status:               a.Status,
+			ChallengeType:        a.ChallengeType,
+			FailedChallengeTypes: a.FailedChallengeTypes,
+		})
+	}
+
+	return &rpc.CertificateStateResponse{
+		Domain:         d.Name,
+		State:          d.State.String(),
+		Timeline:       timeline,
+		Certificate:    certificateMetadata(d.Certificate),
+		Authorizations: authorizations,
+	}, nil
 }
 
 // GetCertificate returns the domain certificate once it has been authorized by the CA.
+// Modified domains return their previous certificate until the new one is issued.
+// Hostnames without a certificate of their own use the wildcard certificate that covers them.
+// Revoked certificates are never returned.
 func (a *API) GetCertificate(ctx context.Context, req *rpc.GetCertificateRequest) (*rpc.GetCertificateResponse, error) {
 	accID, err := uuid.Parse(req.AccountID)
 	if err != nil {
@@ -112,21 +318,86 @@ func (a *API) GetCertificate(ctx context.Context, req *rpc.GetCertificateRequest
 	}
 
 	d, err := a.bucket.GetDomain(acc.ID, req.Domain)
+	if errors.Cause(err) == storage.ErrDomainNotFound {
+		d, err = a.wildcardDomain(acc.ID, req.Domain, err)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	if d.State != domain.Issued {
+	if d.Certificate == nil {
 		return nil, errors.New("the certificate has not been issued yet")
 	}
 
+	if d.Certificate.Revoked() {
+		return nil, errors.Errorf("the certificate for domain %s has been revoked", d.Name)
+	}
+
 	return &rpc.GetCertificateResponse{
 		Certificate: string(d.Certificate.Cert),
 		Key:         string(d.Certificate.Key),
 		Chain:       string(d.Certificate.CA),
+		Metadata:    certificateMetadata(d.Certificate),
 	}, nil
 }
 
+// wildcardDomain finds the wildcard domain whose certificate covers a hostname,
+// i.e: *.example.com for www.example.com, and for example.com when the
+// wildcard certificate includes its base name. It returns notFound
+// when there is no wildcard domain that covers the hostname.
+func (a *API) wildcardDomain(accountID uuid.UUID, hostname string, notFound error) (*domain.Domain, error) {
+	// The wildcard domains to look up, and the name their certificates must include.
+	var candidates []struct{ name, covers string }
+	if w := domain.WildcardName(hostname); w != "" {
+		candidates = append(candidates, struct{ name, covers string }{w, w})
+	}
+	if !domain.IsWildcard(hostname) {
+		candidates = append(candidates, struct{ name, covers string }{"*." + hostname, hostname})
+	}
+
+	for _, c := range candidates {
+		d, err := a.bucket.GetDomain(accountID, c.name)
+		if errors.Cause(err) == storage.ErrDomainNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for _, n := range d.SANNames() {
+			if n == c.covers {
+				return d, nil
+			}
+		}
+	}
+
+	return nil, notFound
+}
+
+// certificateMetadata returns the metadata of an issued certificate,
+// or nil if the certificate has not been issued yet.
+func certificateMetadata(c *cryptopolis.Certificate) *rpc.CertificateMetadata {
+	if c == nil {
+		return nil
+	}
+
+	m := &rpc.CertificateMetadata{
+		SerialNumber: c.SerialNumber,
+		Issuer:       c.Issuer,
+		NotBefore:    c.NotBefore.Format(time.RFC3339),
+		NotAfter:     c.NotAfter.Format(time.RFC3339),
+		Names:        c.DNSNames,
+		Fingerprint:  c.Fingerprint,
+		KeyType:      string(c.KeyType),
+	}
+
+	if c.Revoked() {
+		m.RevokedAt = c.RevokedAt.Format(time.RFC3339)
+		m.RevocationReason = rpc.RevocationReason(c.RevocationReason)
+	}
+	return m
+}
+
 // NewAPI initializes the API.
 func NewAPI(bucket storage.Bucket, broker broker.Broker, config *configuration.Configuration) *API {
 	return &API{
@@ -0,0 +1,146 @@
+package api
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lost-mountain/isard/account"
+	"github.com/lost-mountain/isard/cryptopolis"
+	"github.com/lost-mountain/isard/domain"
+	"github.com/lost-mountain/isard/rpc"
+	"github.com/lost-mountain/isard/storage"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+// failingBucket fails to retrieve some domains,
+// like a storage backend that is unavailable.
+type failingBucket struct {
+	storage.Bucket
+	failures map[string]bool
+}
+
+func (b *failingBucket) GetDomain(accountID uuid.UUID, name string) (*domain.Domain, error) {
+	if b.failures[name] {
+		return nil, errors.Errorf("storage unavailable: %s", name)
+	}
+	return b.Bucket.GetDomain(accountID, name)
+}
+
+func newWildcardAPI(t *testing.T) (*API, *failingBucket, *account.Account) {
+	f, err := ioutil.TempFile("", "isard-")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	bb, err := storage.NewBoltBucket(f.Name())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		bb.Close()
+		os.Remove(f.Name())
+	})
+
+	a, err := account.NewAccount("david.calavera@gmail.com")
+	require.NoError(t, err)
+	require.NoError(t, bb.SaveAccount(a))
+
+	d, err := domain.NewDomain(a, "*.example.com")
+	require.NoError(t, err)
+	d.State = domain.Issued
+	d.Certificate = &cryptopolis.Certificate{Cert: []byte("wildcard certificate")}
+	require.NoError(t, bb.SaveDomain(d))
+
+	b := &failingBucket{Bucket: bb, failures: map[string]bool{}}
+	return NewAPI(b, nil, nil), b, a
+}
+
+func TestGetWildcardCertificate(t *testing.T) {
+	api, _, a := newWildcardAPI(t)
+
+	for _, name := range []string{"*.example.com", "www.example.com", "example.com"} {
+		res, err := api.GetCertificate(context.Background(), &rpc.GetCertificateRequest{
+			AccountID:    a.ID.String(),
+			AccountToken: a.Token.String(),
+			Domain:       name,
+		})
+		require.NoError(t, err, name)
+		require.Equal(t, "wildcard certificate", res.Certificate, name)
+	}
+
+	for _, name := range []string{"example.org", "www.sub.example.com"} {
+		_, err := api.GetCertificate(context.Background(), &rpc.GetCertificateRequest{
+			AccountID:    a.ID.String(),
+			AccountToken: a.Token.String(),
+			Domain:       name,
+		})
+		require.Equal(t, storage.ErrDomainNotFound, errors.Cause(err), name)
+	}
+}
+
+func TestGetCertificateStorageError(t *testing.T) {
+	api, b, a := newWildcardAPI(t)
+	b.failures["www.example.com"] = true
+
+	// Storage errors are not hidden by the wildcard certificate.
+	_, err := api.GetCertificate(context.Background(), &rpc.GetCertificateRequest{
+		AccountID:    a.ID.String(),
+		AccountToken: a.Token.String(),
+		Domain:       "www.example.com",
+	})
+	require.EqualError(t, err, "storage unavailable: www.example.com")
+}
+
+func TestModifyCertificateNotIssued(t *testing.T) {
+	api, b, a := newWildcardAPI(t)
+
+	d, err := domain.NewDomain(a, "test.example.com")
+	require.NoError(t, err)
+	require.NoError(t, b.SaveDomain(d))
+
+	// The request is refused before it's published.
+	_, err = api.ModifyCertificate(context.Background(), &rpc.ModifyCertificateRequest{
+		AccountID:    a.ID.String(),
+		AccountToken: a.Token.String(),
+		Domain:       "test.example.com",
+		AddNames:     []string{"beta.example.com"},
+	})
+	require.EqualError(t, err, "unable to modify domain test.example.com in state: pending")
+}
+
+func TestRevokeCertificateNotIssued(t *testing.T) {
+	api, b, a := newWildcardAPI(t)
+
+	d, err := domain.NewDomain(a, "test.example.com")
+	require.NoError(t, err)
+	require.NoError(t, b.SaveDomain(d))
+
+	// The request is refused before it's published.
+	_, err = api.RevokeCertificate(context.Background(), &rpc.RevokeCertificateRequest{
+		AccountID:    a.ID.String(),
+		AccountToken: a.Token.String(),
+		Domain:       "test.example.com",
+	})
+	require.EqualError(t, err, "unable to revoke domain test.example.com in state: pending")
+}
+
+func TestGetRevokedCertificate(t *testing.T) {
+	api, b, a := newWildcardAPI(t)
+
+	d, err := b.GetDomain(a.ID, "*.example.com")
+	require.NoError(t, err)
+	d.State = domain.Revoked
+	d.Certificate.RevokedAt = time.Now()
+	require.NoError(t, b.SaveDomain(d))
+
+	for _, name := range []string{"*.example.com", "www.example.com"} {
+		_, err = api.GetCertificate(context.Background(), &rpc.GetCertificateRequest{
+			AccountID:    a.ID.String(),
+			AccountToken: a.Token.String(),
+			Domain:       name,
+		})
+		require.EqualError(t, err, "the certificate for domain *.example.com has been revoked", name)
+	}
+}
@@ -10,17 +10,28 @@ It is generated from these files:
 
 It has these top-level messages:
 	CreateAccountRequest
+	DNSProviderCredentials
+	DNSProviderOption
 	CreateAccountResponse
 	UpdateAccountRequest
 	UpdateAccountResponse
 	CreateCertificateRequest
 	CreateCertificateResponse
+	ModifyCertificateRequest
+	ModifyCertificateResponse
+	CancelCertificateRequest
+	CancelCertificateResponse
+	RevokeCertificateRequest
+	RevokeCertificateResponse
 	ResolveChallengeRequest
 	ResolveChallengeResponse
 	CertificateStateRequest
 	CertificateStateResponse
+	AuthorizationState
+	StateTransition
 	GetCertificateRequest
 	GetCertificateResponse
+	CertificateMetadata
 */
 package rpc
 
@@ -65,10 +76,39 @@ func (x AccountEnvironment) String() string {
 }
 func (AccountEnvironment) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
 
+type RevocationReason int32
+
+const (
+	RevocationReason_UNSPECIFIED            RevocationReason = 0
+	RevocationReason_KEY_COMPROMISE         RevocationReason = 1
+	RevocationReason_SUPERSEDED             RevocationReason = 4
+	RevocationReason_CESSATION_OF_OPERATION RevocationReason = 5
+)
+
+var RevocationReason_name = map[int32]string{
+	0: "UNSPECIFIED",
+	1: "KEY_COMPROMISE",
+	4: "SUPERSEDED",
+	5: "CESSATION_OF_OPERATION",
+}
+var RevocationReason_value = map[string]int32{
+	"UNSPECIFIED":            0,
+	"KEY_COMPROMISE":         1,
+	"SUPERSEDED":             4,
+	"CESSATION_OF_OPERATION": 5,
+}
+
+func (x RevocationReason) String() string {
+	return proto.EnumName(RevocationReason_name, int32(x))
+}
+func (RevocationReason) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
 type CreateAccountRequest struct {
-	Owner       string             `protobuf:"bytes,1,opt,name=owner" json:"owner,omitempty"`
-	Key         string             `protobuf:"bytes,2,opt,name=key" json:"key,omitempty"`
-	Environment AccountEnvironment `protobuf:"varint,3,opt,name=environment,enum=rpc.AccountEnvironment" json:"environment,omitempty"`
+	Owner       string                  `protobuf:"bytes,1,opt,name=owner" json:"owner,omitempty"`
+	Key         string                  `protobuf:"bytes,2,opt,name=key" json:"key,omitempty"`
+	Environment AccountEnvironment      `protobuf:"varint,3,opt,name=environment,enum=rpc.AccountEnvironment" json:"environment,omitempty"`
+	KeyType     string                  `protobuf:"bytes,4,opt,name=keyType" json:"keyType,omitempty"`
+	DnsProvider *DNSProviderCredentials `protobuf:"bytes,5,opt,name=dnsProvider" json:"dnsProvider,omitempty"`
 }
 
 func (m *CreateAccountRequest) Reset()                    { *m = CreateAccountRequest{} }
@@ -97,6 +137,68 @@ func (m *CreateAccountRequest) GetEnvironment() AccountEnvironment {
 	return AccountEnvironment_PRODUCTION
 }
 
+func (m *CreateAccountRequest) GetKeyType() string {
+	if m != nil {
+		return m.KeyType
+	}
+	return ""
+}
+
+func (m *CreateAccountRequest) GetDnsProvider() *DNSProviderCredentials {
+	if m != nil {
+		return m.DnsProvider
+	}
+	return nil
+}
+
+type DNSProviderCredentials struct {
+	Name    string               `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Options []*DNSProviderOption `protobuf:"bytes,2,rep,name=options" json:"options,omitempty"`
+}
+
+func (m *DNSProviderCredentials) Reset()                    { *m = DNSProviderCredentials{} }
+func (m *DNSProviderCredentials) String() string            { return proto.CompactTextString(m) }
+func (*DNSProviderCredentials) ProtoMessage()               {}
+func (*DNSProviderCredentials) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+func (m *DNSProviderCredentials) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *DNSProviderCredentials) GetOptions() []*DNSProviderOption {
+	if m != nil {
+		return m.Options
+	}
+	return nil
+}
+
+type DNSProviderOption struct {
+	Name  string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Value string `protobuf:"bytes,2,opt,name=value" json:"value,omitempty"`
+}
+
+func (m *DNSProviderOption) Reset()                    { *m = DNSProviderOption{} }
+func (m *DNSProviderOption) String() string            { return proto.CompactTextString(m) }
+func (*DNSProviderOption) ProtoMessage()               {}
+func (*DNSProviderOption) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
+func (m *DNSProviderOption) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *DNSProviderOption) GetValue() string {
+	if m != nil {
+		return m.Value
+	}
+	return ""
+}
+
 type CreateAccountResponse struct {
 	Id    string `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
 	Token string `protobuf:"bytes,2,opt,name=token" json:"token,omitempty"`
@@ -105,7 +207,7 @@ type CreateAccountResponse struct {
 func (m *CreateAccountResponse) Reset()                    { *m = CreateAccountResponse{} }
 func (m *CreateAccountResponse) String() string            { return proto.CompactTextString(m) }
 func (*CreateAccountResponse) ProtoMessage()               {}
-func (*CreateAccountResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+func (*CreateAccountResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
 
 func (m *CreateAccountResponse) GetId() string {
 	if m != nil {
@@ -129,7 +231,7 @@ type UpdateAccountRequest struct {
 func (m *UpdateAccountRequest) Reset()                    { *m = UpdateAccountRequest{} }
 func (m *UpdateAccountRequest) String() string            { return proto.CompactTextString(m) }
 func (*UpdateAccountRequest) ProtoMessage()               {}
-func (*UpdateAccountRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+func (*UpdateAccountRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
 
 func (m *UpdateAccountRequest) GetId() string {
 	if m != nil {
@@ -151,19 +253,22 @@ type UpdateAccountResponse struct {
 func (m *UpdateAccountResponse) Reset()                    { *m = UpdateAccountResponse{} }
 func (m *UpdateAccountResponse) String() string            { return proto.CompactTextString(m) }
 func (*UpdateAccountResponse) ProtoMessage()               {}
-func (*UpdateAccountResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+func (*UpdateAccountResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
 
 type CreateCertificateRequest struct {
-	AccountID     string `protobuf:"bytes,1,opt,name=accountID" json:"accountID,omitempty"`
-	AccountToken  string `protobuf:"bytes,2,opt,name=accountToken" json:"accountToken,omitempty"`
-	Domain        string `protobuf:"bytes,3,opt,name=domain" json:"domain,omitempty"`
-	ChallengeType string `protobuf:"bytes,4,opt,name=challengeType" json:"challengeType,omitempty"`
+	AccountID      string   `protobuf:"bytes,1,opt,name=accountID" json:"accountID,omitempty"`
+	AccountToken   string   `protobuf:"bytes,2,opt,name=accountToken" json:"accountToken,omitempty"`
+	Domain         string   `protobuf:"bytes,3,opt,name=domain" json:"domain,omitempty"`
+	ChallengeType  string   `protobuf:"bytes,4,opt,name=challengeType" json:"challengeType,omitempty"`
+	KeyType        string   `protobuf:"bytes,5,opt,name=keyType" json:"keyType,omitempty"`
+	ReuseKey       bool     `protobuf:"varint,6,opt,name=reuseKey" json:"reuseKey,omitempty"`
+	ChallengeTypes []string `protobuf:"bytes,7,rep,name=challengeTypes" json:"challengeTypes,omitempty"`
 }
 
 func (m *CreateCertificateRequest) Reset()                    { *m = CreateCertificateRequest{} }
 func (m *CreateCertificateRequest) String() string            { return proto.CompactTextString(m) }
 func (*CreateCertificateRequest) ProtoMessage()               {}
-func (*CreateCertificateRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+func (*CreateCertificateRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
 
 func (m *CreateCertificateRequest) GetAccountID() string {
 	if m != nil {
@@ -193,6 +298,27 @@ func (m *CreateCertificateRequest) GetChallengeType() string {
 	return ""
 }
 
+func (m *CreateCertificateRequest) GetKeyType() string {
+	if m != nil {
+		return m.KeyType
+	}
+	return ""
+}
+
+func (m *CreateCertificateRequest) GetReuseKey() bool {
+	if m != nil {
+		return m.ReuseKey
+	}
+	return false
+}
+
+func (m *CreateCertificateRequest) GetChallengeTypes() []string {
+	if m != nil {
+		return m.ChallengeTypes
+	}
+	return nil
+}
+
 type CreateCertificateResponse struct {
 	AccountID string `protobuf:"bytes,1,opt,name=accountID" json:"accountID,omitempty"`
 	DomainID  string `protobuf:"bytes,2,opt,name=domainID" json:"domainID,omitempty"`
@@ -202,7 +328,7 @@ type CreateCertificateResponse struct {
 func (m *CreateCertificateResponse) Reset()                    { *m = CreateCertificateResponse{} }
 func (m *CreateCertificateResponse) String() string            { return proto.CompactTextString(m) }
 func (*CreateCertificateResponse) ProtoMessage()               {}
-func (*CreateCertificateResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+func (*CreateCertificateResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
 
 func (m *CreateCertificateResponse) GetAccountID() string {
 	if m != nil {
@@ -225,6 +351,158 @@ func (m *CreateCertificateResponse) GetState() string {
 	return ""
 }
 
+type ModifyCertificateRequest struct {
+	AccountID    string   `protobuf:"bytes,1,opt,name=accountID" json:"accountID,omitempty"`
+	AccountToken string   `protobuf:"bytes,2,opt,name=accountToken" json:"accountToken,omitempty"`
+	Domain       string   `protobuf:"bytes,3,opt,name=domain" json:"domain,omitempty"`
+	AddNames     []string `protobuf:"bytes,4,rep,name=addNames" json:"addNames,omitempty"`
+	RemoveNames  []string `protobuf:"bytes,5,rep,name=removeNames" json:"removeNames,omitempty"`
+}
+
+func (m *ModifyCertificateRequest) Reset()                    { *m = ModifyCertificateRequest{} }
+func (m *ModifyCertificateRequest) String() string            { return proto.CompactTextString(m) }
+func (*ModifyCertificateRequest) ProtoMessage()               {}
+func (*ModifyCertificateRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
+
+func (m *ModifyCertificateRequest) GetAccountID() string {
+	if m != nil {
+		return m.AccountID
+	}
+	return ""
+}
+
+func (m *ModifyCertificateRequest) GetAccountToken() string {
+	if m != nil {
+		return m.AccountToken
+	}
+	return ""
+}
+
+func (m *ModifyCertificateRequest) GetDomain() string {
+	if m != nil {
+		return m.Domain
+	}
+	return ""
+}
+
+func (m *ModifyCertificateRequest) GetAddNames() []string {
+	if m != nil {
+		return m.AddNames
+	}
+	return nil
+}
+
+func (m *ModifyCertificateRequest) GetRemoveNames() []string {
+	if m != nil {
+		return m.RemoveNames
+	}
+	return nil
+}
+
+type ModifyCertificateResponse struct {
+}
+
+func (m *ModifyCertificateResponse) Reset()                    { *m = ModifyCertificateResponse{} }
+func (m *ModifyCertificateResponse) String() string            { return proto.CompactTextString(m) }
+func (*ModifyCertificateResponse) ProtoMessage()               {}
+func (*ModifyCertificateResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
+
+type CancelCertificateRequest struct {
+	AccountID    string `protobuf:"bytes,1,opt,name=accountID" json:"accountID,omitempty"`
+	AccountToken string `protobuf:"bytes,2,opt,name=accountToken" json:"accountToken,omitempty"`
+	Domain       string `protobuf:"bytes,3,opt,name=domain" json:"domain,omitempty"`
+}
+
+func (m *CancelCertificateRequest) Reset()                    { *m = CancelCertificateRequest{} }
+func (m *CancelCertificateRequest) String() string            { return proto.CompactTextString(m) }
+func (*CancelCertificateRequest) ProtoMessage()               {}
+func (*CancelCertificateRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
+
+func (m *CancelCertificateRequest) GetAccountID() string {
+	if m != nil {
+		return m.AccountID
+	}
+	return ""
+}
+
+func (m *CancelCertificateRequest) GetAccountToken() string {
+	if m != nil {
+		return m.AccountToken
+	}
+	return ""
+}
+
+func (m *CancelCertificateRequest) GetDomain() string {
+	if m != nil {
+		return m.Domain
+	}
+	return ""
+}
+
+type CancelCertificateResponse struct {
+}
+
+func (m *CancelCertificateResponse) Reset()                    { *m = CancelCertificateResponse{} }
+func (m *CancelCertificateResponse) String() string            { return proto.CompactTextString(m) }
+func (*CancelCertificateResponse) ProtoMessage()               {}
+func (*CancelCertificateResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
+
+type RevokeCertificateRequest struct {
+	AccountID         string           `protobuf:"bytes,1,opt,name=accountID" json:"accountID,omitempty"`
+	AccountToken      string           `protobuf:"bytes,2,opt,name=accountToken" json:"accountToken,omitempty"`
+	Domain            string           `protobuf:"bytes,3,opt,name=domain" json:"domain,omitempty"`
+	Reason            RevocationReason `protobuf:"varint,4,opt,name=reason,enum=rpc.RevocationReason" json:"reason,omitempty"`
+	UseCertificateKey bool             `protobuf:"varint,5,opt,name=useCertificateKey" json:"useCertificateKey,omitempty"`
+}
+
+func (m *RevokeCertificateRequest) Reset()                    { *m = RevokeCertificateRequest{} }
+func (m *RevokeCertificateRequest) String() string            { return proto.CompactTextString(m) }
+func (*RevokeCertificateRequest) ProtoMessage()               {}
+func (*RevokeCertificateRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{12} }
+
+func (m *RevokeCertificateRequest) GetAccountID() string {
+	if m != nil {
+		return m.AccountID
+	}
+	return ""
+}
+
+func (m *RevokeCertificateRequest) GetAccountToken() string {
+	if m != nil {
+		return m.AccountToken
+	}
+	return ""
+}
+
+func (m *RevokeCertificateRequest) GetDomain() string {
+	if m != nil {
+		return m.Domain
+	}
+	return ""
+}
+
+func (m *RevokeCertificateRequest) GetReason() RevocationReason {
+	if m != nil {
+		return m.Reason
+	}
+	return RevocationReason_UNSPECIFIED
+}
+
+func (m *RevokeCertificateRequest) GetUseCertificateKey() bool {
+	if m != nil {
+		return m.UseCertificateKey
+	}
+	return false
+}
+
+type RevokeCertificateResponse struct {
+}
+
+func (m *RevokeCertificateResponse) Reset()                    { *m = RevokeCertificateResponse{} }
+func (m *RevokeCertificateResponse) String() string            { return proto.CompactTextString(m) }
+func (*RevokeCertificateResponse) ProtoMessage()               {}
+func (*RevokeCertificateResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
+
 type ResolveChallengeRequest struct {
 	AccountID    string `protobuf:"bytes,1,opt,name=accountID" json:"accountID,omitempty"`
 	AccountToken string `protobuf:"bytes,2,opt,name=accountToken" json:"accountToken,omitempty"`
@@ -234,7 +512,7 @@ type ResolveChallengeRequest struct {
 func (m *ResolveChallengeRequest) Reset()                    { *m = ResolveChallengeRequest{} }
 func (m *ResolveChallengeRequest) String() string            { return proto.CompactTextString(m) }
 func (*ResolveChallengeRequest) ProtoMessage()               {}
-func (*ResolveChallengeRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+func (*ResolveChallengeRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{14} }
 
 func (m *ResolveChallengeRequest) GetAccountID() string {
 	if m != nil {
@@ -266,7 +544,7 @@ type ResolveChallengeResponse struct {
 func (m *ResolveChallengeResponse) Reset()                    { *m = ResolveChallengeResponse{} }
 func (m *ResolveChallengeResponse) String() string            { return proto.CompactTextString(m) }
 func (*ResolveChallengeResponse) ProtoMessage()               {}
-func (*ResolveChallengeResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+func (*ResolveChallengeResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{15} }
 
 func (m *ResolveChallengeResponse) GetCertificate() string {
 	if m != nil {
@@ -292,13 +570,13 @@ func (m *ResolveChallengeResponse) GetChain() string {
 type CertificateStateRequest struct {
 	AccountID    string `protobuf:"bytes,1,opt,name=accountID" json:"accountID,omitempty"`
 	AccountToken string `protobuf:"bytes,2,opt,name=accountToken" json:"accountToken,omitempty"`
-	DomainID     string `protobuf:"bytes,3,opt,name=domainID" json:"domainID,omitempty"`
+	Domain       string `protobuf:"bytes,3,opt,name=domain" json:"domain,omitempty"`
 }
 
 func (m *CertificateStateRequest) Reset()                    { *m = CertificateStateRequest{} }
 func (m *CertificateStateRequest) String() string            { return proto.CompactTextString(m) }
 func (*CertificateStateRequest) ProtoMessage()               {}
-func (*CertificateStateRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
+func (*CertificateStateRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{16} }
 
 func (m *CertificateStateRequest) GetAccountID() string {
 	if m != nil {
@@ -314,20 +592,156 @@ func (m *CertificateStateRequest) GetAccountToken() string {
 	return ""
 }
 
-func (m *CertificateStateRequest) GetDomainID() string {
+func (m *CertificateStateRequest) GetDomain() string {
 	if m != nil {
-		return m.DomainID
+		return m.Domain
 	}
 	return ""
 }
 
 type CertificateStateResponse struct {
+	Domain         string                `protobuf:"bytes,1,opt,name=domain" json:"domain,omitempty"`
+	State          string                `protobuf:"bytes,2,opt,name=state" json:"state,omitempty"`
+	Timeline       []*StateTransition    `protobuf:"bytes,3,rep,name=timeline" json:"timeline,omitempty"`
+	Certificate    *CertificateMetadata  `protobuf:"bytes,4,opt,name=certificate" json:"certificate,omitempty"`
+	Authorizations []*AuthorizationState `protobuf:"bytes,5,rep,name=authorizations" json:"authorizations,omitempty"`
 }
 
 func (m *CertificateStateResponse) Reset()                    { *m = CertificateStateResponse{} }
 func (m *CertificateStateResponse) String() string            { return proto.CompactTextString(m) }
 func (*CertificateStateResponse) ProtoMessage()               {}
-func (*CertificateStateResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
+func (*CertificateStateResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{17} }
+
+func (m *CertificateStateResponse) GetDomain() string {
+	if m != nil {
+		return m.Domain
+	}
+	return ""
+}
+
+func (m *CertificateStateResponse) GetState() string {
+	if m != nil {
+		return m.State
+	}
+	return ""
+}
+
+func (m *CertificateStateResponse) GetTimeline() []*StateTransition {
+	if m != nil {
+		return m.Timeline
+	}
+	return nil
+}
+
+func (m *CertificateStateResponse) GetCertificate() *CertificateMetadata {
+	if m != nil {
+		return m.Certificate
+	}
+	return nil
+}
+
+func (m *CertificateStateResponse) GetAuthorizations() []*AuthorizationState {
+	if m != nil {
+		return m.Authorizations
+	}
+	return nil
+}
+
+type AuthorizationState struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Status               string   `protobuf:"bytes,2,opt,name=status" json:"status,omitempty"`
+	ChallengeType        string   `protobuf:"bytes,3,opt,name=challengeType" json:"challengeType,omitempty"`
+	FailedChallengeTypes []string `protobuf:"bytes,4,rep,name=failedChallengeTypes" json:"failedChallengeTypes,omitempty"`
+}
+
+func (m *AuthorizationState) Reset()                    { *m = AuthorizationState{} }
+func (m *AuthorizationState) String() string            { return proto.CompactTextString(m) }
+func (*AuthorizationState) ProtoMessage()               {}
+func (*AuthorizationState) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{18} }
+
+func (m *AuthorizationState) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *AuthorizationState) GetStatus() string {
+	if m != nil {
+		return m.Status
+	}
+	return ""
+}
+
+func (m *AuthorizationState) GetChallengeType() string {
+	if m != nil {
+		return m.ChallengeType
+	}
+	return ""
+}
+
+func (m *AuthorizationState) GetFailedChallengeTypes() []string {
+	if m != nil {
+		return m.FailedChallengeTypes
+	}
+	return nil
+}
+
+type StateTransition struct {
+	From  string `protobuf:"bytes,1,opt,name=from" json:"from,omitempty"`
+	To    string `protobuf:"bytes,2,opt,name=to" json:"to,omitempty"`
+	Cause string `protobuf:"bytes,3,opt,name=cause" json:"cause,omitempty"`
+	JobID string `protobuf:"bytes,4,opt,name=jobID" json:"jobID,omitempty"`
+	Error string `protobuf:"bytes,5,opt,name=error" json:"error,omitempty"`
+	Time  string `protobuf:"bytes,6,opt,name=time" json:"time,omitempty"`
+}
+
+func (m *StateTransition) Reset()                    { *m = StateTransition{} }
+func (m *StateTransition) String() string            { return proto.CompactTextString(m) }
+func (*StateTransition) ProtoMessage()               {}
+func (*StateTransition) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{19} }
+
+func (m *StateTransition) GetFrom() string {
+	if m != nil {
+		return m.From
+	}
+	return ""
+}
+
+func (m *StateTransition) GetTo() string {
+	if m != nil {
+		return m.To
+	}
+	return ""
+}
+
+func (m *StateTransition) GetCause() string {
+	if m != nil {
+		return m.Cause
+	}
+	return ""
+}
+
+func (m *StateTransition) GetJobID() string {
+	if m != nil {
+		return m.JobID
+	}
+	return ""
+}
+
+func (m *StateTransition) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func (m *StateTransition) GetTime() string {
+	if m != nil {
+		return m.Time
+	}
+	return ""
+}
 
 type GetCertificateRequest struct {
 	AccountID    string `protobuf:"bytes,1,opt,name=accountID" json:"accountID,omitempty"`
@@ -338,7 +752,7 @@ type GetCertificateRequest struct {
 func (m *GetCertificateRequest) Reset()                    { *m = GetCertificateRequest{} }
 func (m *GetCertificateRequest) String() string            { return proto.CompactTextString(m) }
 func (*GetCertificateRequest) ProtoMessage()               {}
-func (*GetCertificateRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
+func (*GetCertificateRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{20} }
 
 func (m *GetCertificateRequest) GetAccountID() string {
 	if m != nil {
@@ -362,15 +776,16 @@ func (m *GetCertificateRequest) GetDomain() string {
 }
 
 type GetCertificateResponse struct {
-	Certificate string `protobuf:"bytes,1,opt,name=certificate" json:"certificate,omitempty"`
-	Key         string `protobuf:"bytes,2,opt,name=key" json:"key,omitempty"`
-	Chain       string `protobuf:"bytes,3,opt,name=chain" json:"chain,omitempty"`
+	Certificate string               `protobuf:"bytes,1,opt,name=certificate" json:"certificate,omitempty"`
+	Key         string               `protobuf:"bytes,2,opt,name=key" json:"key,omitempty"`
+	Chain       string               `protobuf:"bytes,3,opt,name=chain" json:"chain,omitempty"`
+	Metadata    *CertificateMetadata `protobuf:"bytes,4,opt,name=metadata" json:"metadata,omitempty"`
 }
 
 func (m *GetCertificateResponse) Reset()                    { *m = GetCertificateResponse{} }
 func (m *GetCertificateResponse) String() string            { return proto.CompactTextString(m) }
 func (*GetCertificateResponse) ProtoMessage()               {}
-func (*GetCertificateResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
+func (*GetCertificateResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{21} }
 
 func (m *GetCertificateResponse) GetCertificate() string {
 	if m != nil {
@@ -393,20 +808,119 @@ func (m *GetCertificateResponse) GetChain() string {
 	return ""
 }
 
+func (m *GetCertificateResponse) GetMetadata() *CertificateMetadata {
+	if m != nil {
+		return m.Metadata
+	}
+	return nil
+}
+
+type CertificateMetadata struct {
+	SerialNumber     string           `protobuf:"bytes,1,opt,name=serialNumber" json:"serialNumber,omitempty"`
+	Issuer           string           `protobuf:"bytes,2,opt,name=issuer" json:"issuer,omitempty"`
+	NotBefore        string           `protobuf:"bytes,3,opt,name=notBefore" json:"notBefore,omitempty"`
+	NotAfter         string           `protobuf:"bytes,4,opt,name=notAfter" json:"notAfter,omitempty"`
+	Names            []string         `protobuf:"bytes,5,rep,name=names" json:"names,omitempty"`
+	Fingerprint      string           `protobuf:"bytes,6,opt,name=fingerprint" json:"fingerprint,omitempty"`
+	KeyType          string           `protobuf:"bytes,7,opt,name=keyType" json:"keyType,omitempty"`
+	RevokedAt        string           `protobuf:"bytes,8,opt,name=revokedAt" json:"revokedAt,omitempty"`
+	RevocationReason RevocationReason `protobuf:"varint,9,opt,name=revocationReason,enum=rpc.RevocationReason" json:"revocationReason,omitempty"`
+}
+
+func (m *CertificateMetadata) Reset()                    { *m = CertificateMetadata{} }
+func (m *CertificateMetadata) String() string            { return proto.CompactTextString(m) }
+func (*CertificateMetadata) ProtoMessage()               {}
+func (*CertificateMetadata) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{22} }
+
+func (m *CertificateMetadata) GetSerialNumber() string {
+	if m != nil {
+		return m.SerialNumber
+	}
+	return ""
+}
+
+func (m *CertificateMetadata) GetIssuer() string {
+	if m != nil {
+		return m.Issuer
+	}
+	return ""
+}
+
+func (m *CertificateMetadata) GetNotBefore() string {
+	if m != nil {
+		return m.NotBefore
+	}
+	return ""
+}
+
+func (m *CertificateMetadata) GetNotAfter() string {
+	if m != nil {
+		return m.NotAfter
+	}
+	return ""
+}
+
+func (m *CertificateMetadata) GetNames() []string {
+	if m != nil {
+		return m.Names
+	}
+	return nil
+}
+
+func (m *CertificateMetadata) GetFingerprint() string {
+	if m != nil {
+		return m.Fingerprint
+	}
+	return ""
+}
+
+func (m *CertificateMetadata) GetKeyType() string {
+	if m != nil {
+		return m.KeyType
+	}
+	return ""
+}
+
+func (m *CertificateMetadata) GetRevokedAt() string {
+	if m != nil {
+		return m.RevokedAt
+	}
+	return ""
+}
+
+func (m *CertificateMetadata) GetRevocationReason() RevocationReason {
+	if m != nil {
+		return m.RevocationReason
+	}
+	return RevocationReason_UNSPECIFIED
+}
+
 func init() {
 	proto.RegisterType((*CreateAccountRequest)(nil), "rpc.CreateAccountRequest")
+	proto.RegisterType((*DNSProviderCredentials)(nil), "rpc.DNSProviderCredentials")
+	proto.RegisterType((*DNSProviderOption)(nil), "rpc.DNSProviderOption")
 	proto.RegisterType((*CreateAccountResponse)(nil), "rpc.CreateAccountResponse")
 	proto.RegisterType((*UpdateAccountRequest)(nil), "rpc.UpdateAccountRequest")
 	proto.RegisterType((*UpdateAccountResponse)(nil), "rpc.UpdateAccountResponse")
 	proto.RegisterType((*CreateCertificateRequest)(nil), "rpc.CreateCertificateRequest")
 	proto.RegisterType((*CreateCertificateResponse)(nil), "rpc.CreateCertificateResponse")
+	proto.RegisterType((*ModifyCertificateRequest)(nil), "rpc.ModifyCertificateRequest")
+	proto.RegisterType((*ModifyCertificateResponse)(nil), "rpc.ModifyCertificateResponse")
+	proto.RegisterType((*CancelCertificateRequest)(nil), "rpc.CancelCertificateRequest")
+	proto.RegisterType((*CancelCertificateResponse)(nil), "rpc.CancelCertificateResponse")
+	proto.RegisterType((*RevokeCertificateRequest)(nil), "rpc.RevokeCertificateRequest")
+	proto.RegisterType((*RevokeCertificateResponse)(nil), "rpc.RevokeCertificateResponse")
 	proto.RegisterType((*ResolveChallengeRequest)(nil), "rpc.ResolveChallengeRequest")
 	proto.RegisterType((*ResolveChallengeResponse)(nil), "rpc.ResolveChallengeResponse")
 	proto.RegisterType((*CertificateStateRequest)(nil), "rpc.CertificateStateRequest")
 	proto.RegisterType((*CertificateStateResponse)(nil), "rpc.CertificateStateResponse")
+	proto.RegisterType((*AuthorizationState)(nil), "rpc.AuthorizationState")
+	proto.RegisterType((*StateTransition)(nil), "rpc.StateTransition")
 	proto.RegisterType((*GetCertificateRequest)(nil), "rpc.GetCertificateRequest")
 	proto.RegisterType((*GetCertificateResponse)(nil), "rpc.GetCertificateResponse")
+	proto.RegisterType((*CertificateMetadata)(nil), "rpc.CertificateMetadata")
 	proto.RegisterEnum("rpc.AccountEnvironment", AccountEnvironment_name, AccountEnvironment_value)
+	proto.RegisterEnum("rpc.RevocationReason", RevocationReason_name, RevocationReason_value)
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -423,6 +937,9 @@ type APIClient interface {
 	CreateAccount(ctx context.Context, in *CreateAccountRequest, opts ...grpc.CallOption) (*CreateAccountResponse, error)
 	UpdateAccount(ctx context.Context, in *UpdateAccountRequest, opts ...grpc.CallOption) (*UpdateAccountResponse, error)
 	CreateCertificate(ctx context.Context, in *CreateCertificateRequest, opts ...grpc.CallOption) (*CreateCertificateResponse, error)
+	ModifyCertificate(ctx context.Context, in *ModifyCertificateRequest, opts ...grpc.CallOption) (*ModifyCertificateResponse, error)
+	CancelCertificate(ctx context.Context, in *CancelCertificateRequest, opts ...grpc.CallOption) (*CancelCertificateResponse, error)
+	RevokeCertificate(ctx context.Context, in *RevokeCertificateRequest, opts ...grpc.CallOption) (*RevokeCertificateResponse, error)
 	ResolveCertificateChallenge(ctx context.Context, in *ResolveChallengeRequest, opts ...grpc.CallOption) (*ResolveChallengeResponse, error)
 	CheckCertificateState(ctx context.Context, in *CertificateStateRequest, opts ...grpc.CallOption) (*CertificateStateResponse, error)
 	GetCertificate(ctx context.Context, in *GetCertificateRequest, opts ...grpc.CallOption) (*GetCertificateResponse, error)
@@ -463,6 +980,33 @@ func (c *aPIClient) CreateCertificate(ctx context.Context, in *CreateCertificate
 	return out, nil
 }
 
+func (c *aPIClient) ModifyCertificate(ctx context.Context, in *ModifyCertificateRequest, opts ...grpc.CallOption) (*ModifyCertificateResponse, error) {
+	out := new(ModifyCertificateResponse)
+	err := grpc.Invoke(ctx, "/rpc.API/ModifyCertificate", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aPIClient) CancelCertificate(ctx context.Context, in *CancelCertificateRequest, opts ...grpc.CallOption) (*CancelCertificateResponse, error) {
+	out := new(CancelCertificateResponse)
+	err := grpc.Invoke(ctx, "/rpc.API/CancelCertificate", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aPIClient) RevokeCertificate(ctx context.Context, in *RevokeCertificateRequest, opts ...grpc.CallOption) (*RevokeCertificateResponse, error) {
+	out := new(RevokeCertificateResponse)
+	err := grpc.Invoke(ctx, "/rpc.API/RevokeCertificate", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *aPIClient) ResolveCertificateChallenge(ctx context.Context, in *ResolveChallengeRequest, opts ...grpc.CallOption) (*ResolveChallengeResponse, error) {
 	out := new(ResolveChallengeResponse)
 	err := grpc.Invoke(ctx, "/rpc.API/ResolveCertificateChallenge", in, out, c.cc, opts...)
@@ -496,6 +1040,9 @@ type APIServer interface {
 	CreateAccount(context.Context, *CreateAccountRequest) (*CreateAccountResponse, error)
 	UpdateAccount(context.Context, *UpdateAccountRequest) (*UpdateAccountResponse, error)
 	CreateCertificate(context.Context, *CreateCertificateRequest) (*CreateCertificateResponse, error)
+	ModifyCertificate(context.Context, *ModifyCertificateRequest) (*ModifyCertificateResponse, error)
+	CancelCertificate(context.Context, *CancelCertificateRequest) (*CancelCertificateResponse, error)
+	RevokeCertificate(context.Context, *RevokeCertificateRequest) (*RevokeCertificateResponse, error)
 	ResolveCertificateChallenge(context.Context, *ResolveChallengeRequest) (*ResolveChallengeResponse, error)
 	CheckCertificateState(context.Context, *CertificateStateRequest) (*CertificateStateResponse, error)
 	GetCertificate(context.Context, *GetCertificateRequest) (*GetCertificateResponse, error)
@@ -559,6 +1106,60 @@ func _API_CreateCertificate_Handler(srv interface{}, ctx context.Context, dec fu
 	return interceptor(ctx, in, info, handler)
 }
 
+func _API_ModifyCertificate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ModifyCertificateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(APIServer).ModifyCertificate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpc.API/ModifyCertificate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(APIServer).ModifyCertificate(ctx, req.(*ModifyCertificateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _API_CancelCertificate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelCertificateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(APIServer).CancelCertificate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpc.API/CancelCertificate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(APIServer).CancelCertificate(ctx, req.(*CancelCertificateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _API_RevokeCertificate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevokeCertificateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(APIServer).RevokeCertificate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpc.API/RevokeCertificate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(APIServer).RevokeCertificate(ctx, req.(*RevokeCertificateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _API_ResolveCertificateChallenge_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(ResolveChallengeRequest)
 	if err := dec(in); err != nil {
@@ -629,6 +1230,18 @@ var _API_serviceDesc = grpc.ServiceDesc{
 			MethodName: "CreateCertificate",
 			Handler:    _API_CreateCertificate_Handler,
 		},
+		{
+			MethodName: "ModifyCertificate",
+			Handler:    _API_ModifyCertificate_Handler,
+		},
+		{
+			MethodName: "CancelCertificate",
+			Handler:    _API_CancelCertificate_Handler,
+		},
+		{
+			MethodName: "RevokeCertificate",
+			Handler:    _API_RevokeCertificate_Handler,
+		},
 		{
 			MethodName: "ResolveCertificateChallenge",
 			Handler:    _API_ResolveCertificateChallenge_Handler,
@@ -649,38 +1262,80 @@ var _API_serviceDesc = grpc.ServiceDesc{
 func init() { proto.RegisterFile("rpc.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 528 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xbc, 0x55, 0x4d, 0x6f, 0xd3, 0x40,
-	0x10, 0x25, 0x31, 0x2d, 0x64, 0x42, 0xa2, 0xb0, 0x4a, 0x1a, 0xd7, 0x6d, 0x51, 0xb4, 0xe2, 0x50,
-	0x71, 0xa8, 0x44, 0x39, 0x71, 0xe0, 0x10, 0x39, 0x34, 0xf2, 0xa5, 0xad, 0x5c, 0xf7, 0xc2, 0xcd,
-	0xb5, 0x07, 0x62, 0x25, 0xf5, 0xba, 0xf6, 0xb6, 0x55, 0xfe, 0x0a, 0x3f, 0x90, 0xdf, 0x81, 0xe2,
-	0x9d, 0xd4, 0xdf, 0x48, 0x48, 0x85, 0x5b, 0x66, 0xc6, 0x3b, 0xef, 0xed, 0xdb, 0x37, 0x13, 0xe8,
-	0xc4, 0x91, 0x77, 0x12, 0xc5, 0x42, 0x0a, 0xa6, 0xc5, 0x91, 0xc7, 0xd7, 0x30, 0x34, 0x63, 0x74,
-	0x25, 0x4e, 0x3d, 0x4f, 0xdc, 0x87, 0xd2, 0xc6, 0xbb, 0x7b, 0x4c, 0x24, 0x1b, 0xc2, 0x8e, 0x78,
-	0x0c, 0x31, 0xd6, 0x5b, 0x93, 0xd6, 0x71, 0xc7, 0x56, 0x01, 0x1b, 0x80, 0xb6, 0xc4, 0xb5, 0xde,
-	0x4e, 0x73, 0x9b, 0x9f, 0xec, 0x33, 0x74, 0x31, 0x7c, 0x08, 0x62, 0x11, 0xde, 0x62, 0x28, 0x75,
-	0x6d, 0xd2, 0x3a, 0xee, 0x9f, 0x8e, 0x4f, 0x36, 0x28, 0xd4, 0xf1, 0x6b, 0x56, 0xb6, 0xf3, 0xdf,
-	0xf2, 0x2f, 0x30, 0x2a, 0x41, 0x27, 0x91, 0x08, 0x13, 0x64, 0x7d, 0x68, 0x07, 0x3e, 0x01, 0xb7,
-	0x03, 0x7f, 0xc3, 0x45, 0x8a, 0x25, 0x86, 0x84, 0xab, 0x02, 0xee, 0xc2, 0xf0, 0x3a, 0xf2, 0xab,
-	0xcc, 0xcb, 0xa7, 0x4b, 0x0c, 0xdb, 0x7f, 0xc1, 0x70, 0x0c, 0xa3, 0x12, 0x84, 0x62, 0xc8, 0x7f,
-	0xb6, 0x40, 0x57, 0xdc, 0x4d, 0x8c, 0x65, 0xf0, 0x3d, 0xf0, 0x5c, 0x89, 0x5b, 0x02, 0x87, 0xd0,
-	0x71, 0xd5, 0xf7, 0xd6, 0x8c, 0x78, 0x64, 0x09, 0xc6, 0xe1, 0x0d, 0x05, 0x4e, 0xee, 0x4e, 0x85,
-	0x1c, 0xdb, 0x83, 0x5d, 0x5f, 0xdc, 0xba, 0x41, 0x98, 0xea, 0xd9, 0xb1, 0x29, 0x62, 0xef, 0xa1,
-	0xe7, 0x2d, 0xdc, 0xd5, 0x0a, 0xc3, 0x1f, 0xe8, 0xac, 0x23, 0xd4, 0x5f, 0xa6, 0xe5, 0x62, 0x92,
-	0x2f, 0x61, 0xbf, 0x86, 0x1b, 0x69, 0xfb, 0x67, 0x72, 0x06, 0xbc, 0x56, 0x50, 0xd6, 0x8c, 0x88,
-	0x3d, 0xc5, 0x9b, 0x57, 0x48, 0xa4, 0x2b, 0x91, 0x38, 0xa9, 0x80, 0x27, 0x30, 0xb6, 0x31, 0x11,
-	0xab, 0x07, 0x34, 0xb7, 0x24, 0xfe, 0xb9, 0x0e, 0xdc, 0x07, 0xbd, 0x0a, 0x4a, 0x17, 0x9c, 0x40,
-	0xd7, 0xcb, 0xee, 0x4d, 0xb8, 0xf9, 0x54, 0x8d, 0x89, 0x87, 0xb0, 0xe3, 0x2d, 0x32, 0x18, 0x15,
-	0xf0, 0x47, 0x18, 0xe7, 0x14, 0xbc, 0x92, 0xcf, 0xfa, 0xc4, 0x79, 0xa5, 0xb5, 0xa2, 0xd2, 0xdc,
-	0x00, 0xbd, 0x0a, 0x4c, 0xce, 0xbb, 0x83, 0xd1, 0x1c, 0xe5, 0xff, 0x74, 0x1d, 0xbf, 0x81, 0xbd,
-	0x32, 0xe4, 0x73, 0x6b, 0xfd, 0xe1, 0x23, 0xb0, 0xea, 0x30, 0xb2, 0x3e, 0xc0, 0xa5, 0x7d, 0x31,
-	0xbb, 0x36, 0x1d, 0xeb, 0xe2, 0x7c, 0xf0, 0x82, 0x75, 0xe1, 0xd5, 0x95, 0x33, 0x9d, 0x5b, 0xe7,
-	0xf3, 0x41, 0xeb, 0xf4, 0x97, 0x06, 0xda, 0xf4, 0xd2, 0x62, 0x67, 0xd0, 0x2b, 0xac, 0x11, 0xb6,
-	0x9f, 0xce, 0x76, 0xdd, 0x56, 0x33, 0x8c, 0xba, 0x12, 0x5d, 0xe6, 0x0c, 0x7a, 0x85, 0x61, 0xa7,
-	0x3e, 0x75, 0x3b, 0x86, 0xfa, 0xd4, 0xee, 0x06, 0x66, 0xc3, 0xdb, 0xca, 0xf8, 0xb1, 0xa3, 0x1c,
-	0x70, 0xf5, 0xf1, 0x8c, 0x77, 0x4d, 0x65, 0xea, 0xf9, 0x0d, 0x0e, 0xb6, 0x86, 0xcf, 0xaa, 0x4f,
-	0xde, 0x67, 0x87, 0xe9, 0xf1, 0x86, 0x39, 0x34, 0x8e, 0x1a, 0xaa, 0xd4, 0xdb, 0x81, 0x91, 0xb9,
-	0x40, 0x6f, 0x59, 0xb6, 0x1c, 0x75, 0x6d, 0x18, 0x01, 0xea, 0xda, 0xe4, 0x53, 0x66, 0x41, 0xbf,
-	0x68, 0x1a, 0xa6, 0x34, 0xab, 0x35, 0xaf, 0x71, 0x50, 0x5b, 0x53, 0xad, 0x6e, 0x76, 0xd3, 0xbf,
-	0xab, 0x4f, 0xbf, 0x03, 0x00, 0x00, 0xff, 0xff, 0xea, 0x1d, 0x8a, 0x6f, 0xbb, 0x06, 0x00, 0x00,
+	// 1200 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xbc, 0x57, 0xdd, 0x8e, 0x22, 0x45,
+	0x14, 0xde, 0x86, 0x61, 0x06, 0x0e, 0x2e, 0xcb, 0x96, 0x0c, 0xd3, 0xd3, 0xf3, 0x13, 0xd2, 0x31,
+	0x86, 0x6c, 0x74, 0xb3, 0xa2, 0x37, 0x9a, 0x4c, 0x0c, 0x02, 0x33, 0x21, 0x9b, 0x01, 0xd2, 0x30,
+	0x17, 0x7a, 0xe1, 0xa4, 0xa6, 0xbb, 0xd8, 0x69, 0x81, 0x2e, 0xb6, 0xba, 0xc0, 0x8c, 0xaf, 0xe0,
+	0x0b, 0x18, 0x1f, 0xc3, 0x07, 0xf0, 0x1d, 0xf4, 0x11, 0x7c, 0x09, 0xbd, 0x34, 0x55, 0x5d, 0x34,
+	0xfd, 0xc7, 0xea, 0x85, 0xce, 0x5d, 0x9f, 0x9f, 0x3a, 0x3f, 0x75, 0xbe, 0x3a, 0xe7, 0x34, 0x94,
+	0xd8, 0xd2, 0x7e, 0xb9, 0x64, 0x94, 0x53, 0x94, 0x67, 0x4b, 0xdb, 0xfc, 0x5d, 0x83, 0x5a, 0x87,
+	0x11, 0xcc, 0x49, 0xdb, 0xb6, 0xe9, 0xca, 0xe3, 0x16, 0x79, 0xbb, 0x22, 0x3e, 0x47, 0x35, 0x28,
+	0xd0, 0xef, 0x3d, 0xc2, 0x74, 0xad, 0xa1, 0x35, 0x4b, 0x56, 0x40, 0xa0, 0x2a, 0xe4, 0x67, 0xe4,
+	0x41, 0xcf, 0x49, 0x9e, 0xf8, 0x44, 0x9f, 0x43, 0x99, 0x78, 0x6b, 0x97, 0x51, 0x6f, 0x41, 0x3c,
+	0xae, 0xe7, 0x1b, 0x5a, 0xb3, 0xd2, 0x3a, 0x7a, 0x29, 0xdc, 0x28, 0x8b, 0xbd, 0xad, 0xd8, 0x8a,
+	0xea, 0x22, 0x1d, 0x0e, 0x66, 0xe4, 0x61, 0xf2, 0xb0, 0x24, 0xfa, 0x9e, 0x34, 0xb8, 0x21, 0xd1,
+	0x05, 0x94, 0x1d, 0xcf, 0x1f, 0x31, 0xba, 0x76, 0x1d, 0xc2, 0xf4, 0x42, 0x43, 0x6b, 0x96, 0x5b,
+	0x27, 0xd2, 0x68, 0x77, 0x30, 0xde, 0xf0, 0x3b, 0x8c, 0x38, 0xc4, 0xe3, 0x2e, 0x9e, 0xfb, 0x56,
+	0x54, 0xdf, 0xfc, 0x16, 0xea, 0xd9, 0x6a, 0x08, 0xc1, 0x9e, 0x87, 0x17, 0x44, 0x25, 0x25, 0xbf,
+	0xd1, 0x2b, 0x38, 0xa0, 0x4b, 0xee, 0x52, 0xcf, 0xd7, 0x73, 0x8d, 0x7c, 0xb3, 0xdc, 0xaa, 0x27,
+	0x1d, 0x0d, 0xa5, 0xd8, 0xda, 0xa8, 0x99, 0x17, 0xf0, 0x3c, 0x25, 0xcd, 0x34, 0x5d, 0x83, 0xc2,
+	0x1a, 0xcf, 0x57, 0x44, 0x5d, 0x58, 0x40, 0x98, 0x17, 0x70, 0x98, 0xb8, 0x72, 0x7f, 0x49, 0x3d,
+	0x9f, 0xa0, 0x0a, 0xe4, 0x5c, 0x47, 0x19, 0xc8, 0xb9, 0x8e, 0x38, 0xce, 0xe9, 0x8c, 0x78, 0x9b,
+	0xe3, 0x92, 0x30, 0x31, 0xd4, 0x6e, 0x96, 0x4e, 0xba, 0x62, 0xc9, 0xd3, 0x89, 0xca, 0xe4, 0xfe,
+	0x7d, 0x65, 0xcc, 0x23, 0x38, 0x4c, 0xb8, 0x08, 0x22, 0x34, 0xff, 0xd2, 0x40, 0x0f, 0x62, 0xef,
+	0x10, 0xc6, 0xdd, 0xa9, 0x6b, 0x63, 0x4e, 0x36, 0x01, 0x9c, 0x42, 0x09, 0x07, 0xfa, 0xfd, 0xae,
+	0x8a, 0x63, 0xcb, 0x40, 0x26, 0xbc, 0xa7, 0x88, 0x49, 0x24, 0xa7, 0x18, 0x0f, 0xd5, 0x61, 0xdf,
+	0xa1, 0x0b, 0xec, 0x7a, 0x12, 0x47, 0x25, 0x4b, 0x51, 0xe8, 0x03, 0x78, 0x6a, 0xdf, 0xe3, 0xf9,
+	0x9c, 0x78, 0x6f, 0x48, 0x04, 0x2f, 0x71, 0x66, 0x14, 0x4f, 0x85, 0x38, 0x9e, 0x0c, 0x28, 0x32,
+	0xb2, 0xf2, 0xc9, 0x6b, 0xf2, 0xa0, 0xef, 0x37, 0xb4, 0x66, 0xd1, 0x0a, 0x69, 0xf4, 0x21, 0x54,
+	0x62, 0x66, 0x7c, 0xfd, 0xa0, 0x91, 0x6f, 0x96, 0xac, 0x04, 0xd7, 0x9c, 0xc1, 0x71, 0x46, 0xe6,
+	0xaa, 0x72, 0xef, 0x4e, 0xdd, 0x80, 0x62, 0x90, 0x48, 0xbf, 0xab, 0xd2, 0x0e, 0x69, 0x51, 0x63,
+	0x9f, 0x63, 0x4e, 0x54, 0xc6, 0x01, 0x61, 0xfe, 0xa2, 0x81, 0x7e, 0x4d, 0x1d, 0x77, 0xfa, 0xf0,
+	0xa8, 0xf7, 0x6c, 0x40, 0x11, 0x3b, 0xce, 0x00, 0x2f, 0x88, 0xaf, 0xef, 0xc9, 0x5b, 0x08, 0x69,
+	0xd4, 0x80, 0x32, 0x23, 0x0b, 0xba, 0x26, 0x81, 0xb8, 0x20, 0xc5, 0x51, 0x96, 0x79, 0x02, 0xc7,
+	0x19, 0x31, 0x2b, 0xe4, 0x70, 0xd0, 0x3b, 0xd8, 0xb3, 0xc9, 0xfc, 0x31, 0x13, 0x12, 0x21, 0x65,
+	0x78, 0x55, 0x21, 0xfd, 0xa6, 0x81, 0x6e, 0x91, 0x35, 0x9d, 0x3d, 0x2e, 0x98, 0x3f, 0x86, 0x7d,
+	0x46, 0xb0, 0x4f, 0x3d, 0x89, 0xe2, 0x4a, 0xeb, 0x50, 0x3e, 0x49, 0x11, 0x88, 0x8d, 0x65, 0x9f,
+	0x91, 0x42, 0x4b, 0x29, 0xa1, 0x8f, 0xe0, 0xf9, 0xca, 0x8f, 0x46, 0x28, 0x40, 0x5c, 0x90, 0x20,
+	0x4e, 0x0b, 0x44, 0xc2, 0x19, 0x29, 0xa9, 0x84, 0x7d, 0x38, 0xb2, 0x88, 0x4f, 0xe7, 0x6b, 0xd2,
+	0xd9, 0x60, 0xfb, 0xff, 0x2f, 0x81, 0x03, 0x7a, 0xda, 0xa9, 0x7a, 0x36, 0x0d, 0x28, 0xdb, 0xdb,
+	0x38, 0x95, 0xdf, 0x28, 0x2b, 0x63, 0xe0, 0xd4, 0xa0, 0x60, 0xdf, 0x6f, 0xdd, 0x04, 0x84, 0x48,
+	0x2d, 0x92, 0xf1, 0x98, 0x3f, 0x0a, 0xba, 0xfe, 0x14, 0xdd, 0x30, 0xe5, 0x55, 0xe5, 0xb6, 0x3d,
+	0xa4, 0xc5, 0xca, 0x1f, 0x3e, 0xf8, 0x5c, 0xe4, 0xc1, 0xa3, 0x57, 0x50, 0xe4, 0xee, 0x82, 0xcc,
+	0x5d, 0x4f, 0x74, 0x02, 0x31, 0x85, 0x6a, 0x12, 0x16, 0xd2, 0xe6, 0x84, 0x61, 0xcf, 0x77, 0x25,
+	0x36, 0x42, 0x2d, 0xf4, 0x45, 0xfc, 0xee, 0xf6, 0xe4, 0x8c, 0xd4, 0xe5, 0xa1, 0x48, 0x4c, 0xd7,
+	0x84, 0x63, 0x07, 0x73, 0x1c, 0xbf, 0xd5, 0x2f, 0xa1, 0x82, 0x57, 0xfc, 0x9e, 0x32, 0xf7, 0x07,
+	0x1c, 0x4c, 0xbe, 0x82, 0xf4, 0xa9, 0xa6, 0x43, 0x54, 0x14, 0x24, 0x95, 0x50, 0x37, 0x7f, 0xd6,
+	0x00, 0xa5, 0xd5, 0x32, 0x67, 0x60, 0x1d, 0xf6, 0x45, 0x8a, 0x2b, 0x5f, 0x25, 0xac, 0xa8, 0x74,
+	0x4f, 0xcf, 0x67, 0xf5, 0xf4, 0x16, 0xd4, 0xa6, 0xd8, 0x9d, 0x13, 0xa7, 0x13, 0xef, 0xd1, 0x41,
+	0x77, 0xca, 0x94, 0x99, 0x3f, 0x6a, 0xf0, 0x2c, 0x71, 0x6f, 0x22, 0xb2, 0x29, 0xa3, 0x8b, 0x4d,
+	0x64, 0xe2, 0x5b, 0x0c, 0x4c, 0x4e, 0x55, 0x54, 0x39, 0x4e, 0x25, 0xb2, 0xf0, 0xca, 0x0f, 0x5b,
+	0xb1, 0x24, 0x04, 0xf7, 0x3b, 0x7a, 0xd7, 0xef, 0xaa, 0x99, 0x13, 0x10, 0x82, 0x4b, 0x18, 0xa3,
+	0x4c, 0x4d, 0x9a, 0x80, 0x10, 0x5e, 0x44, 0x7d, 0xe4, 0x8c, 0x29, 0x59, 0xf2, 0xdb, 0x7c, 0x0b,
+	0x87, 0x57, 0x84, 0x3f, 0x6a, 0xd7, 0xfb, 0x49, 0x83, 0x7a, 0xd2, 0xe7, 0x7f, 0xfd, 0xe2, 0xd0,
+	0x67, 0x50, 0x5c, 0x28, 0x70, 0xfd, 0x23, 0xf8, 0x42, 0x4d, 0xf3, 0xd7, 0x1c, 0xbc, 0x9f, 0xa1,
+	0x21, 0xd2, 0xf5, 0x09, 0x73, 0xf1, 0x7c, 0xb0, 0x5a, 0xdc, 0x85, 0x5b, 0x67, 0x8c, 0x27, 0xd2,
+	0x75, 0x7d, 0x7f, 0x45, 0xd8, 0x06, 0x49, 0x01, 0x25, 0x2e, 0xd2, 0xa3, 0xfc, 0x2b, 0x32, 0xa5,
+	0x6c, 0x53, 0xbb, 0x2d, 0x43, 0xcc, 0x34, 0x8f, 0xf2, 0xf6, 0x94, 0x13, 0xa6, 0x4a, 0x18, 0xd2,
+	0x22, 0x33, 0x2f, 0x32, 0xcd, 0x02, 0x42, 0xdc, 0xd1, 0xd4, 0xf5, 0xde, 0x10, 0xb6, 0x64, 0xae,
+	0xc7, 0x55, 0x31, 0xa3, 0xac, 0xe8, 0xa6, 0x71, 0x10, 0xdf, 0x34, 0x4e, 0xa1, 0xc4, 0x64, 0xff,
+	0x75, 0xda, 0x5c, 0x2f, 0x06, 0xb1, 0x84, 0x0c, 0xd4, 0x86, 0x2a, 0x4b, 0xf4, 0x79, 0xbd, 0xf4,
+	0xae, 0x21, 0x90, 0x52, 0x7f, 0xf1, 0x09, 0xa0, 0xf4, 0xf6, 0x86, 0x2a, 0x00, 0x23, 0x6b, 0xd8,
+	0xbd, 0xe9, 0x4c, 0xfa, 0xc3, 0x41, 0xf5, 0x09, 0x2a, 0xc3, 0xc1, 0x78, 0xd2, 0xbe, 0xea, 0x0f,
+	0xae, 0xaa, 0xda, 0x0b, 0x1b, 0xaa, 0x49, 0xc3, 0xe8, 0x19, 0x94, 0x6f, 0x06, 0xe3, 0x51, 0xaf,
+	0xd3, 0xbf, 0xec, 0xf7, 0xba, 0xd5, 0x27, 0x08, 0x41, 0xe5, 0x75, 0xef, 0xeb, 0xdb, 0xce, 0xf0,
+	0x7a, 0x64, 0x0d, 0xaf, 0xfb, 0xe3, 0x5e, 0x55, 0x13, 0x56, 0xc7, 0x37, 0xa3, 0x9e, 0x35, 0xee,
+	0x75, 0x7b, 0xdd, 0xea, 0x1e, 0x32, 0xa0, 0xde, 0xe9, 0x8d, 0xc7, 0x6d, 0xe1, 0xe4, 0x76, 0x78,
+	0x79, 0x3b, 0x1c, 0xf5, 0x2c, 0x49, 0x54, 0x0b, 0xad, 0x3f, 0x0a, 0x90, 0x6f, 0x8f, 0xfa, 0xe8,
+	0x12, 0x9e, 0xc6, 0x96, 0x5b, 0x74, 0x1c, 0xa0, 0x22, 0xe3, 0x1f, 0xc3, 0x30, 0xb2, 0x44, 0x0a,
+	0xa8, 0x97, 0xf0, 0x34, 0xb6, 0x82, 0x2a, 0x3b, 0x59, 0x9b, 0xaf, 0x61, 0x64, 0x89, 0x94, 0x1d,
+	0x0b, 0x9e, 0xa7, 0xd6, 0x36, 0x74, 0x16, 0x71, 0x9c, 0x7e, 0x99, 0xc6, 0xf9, 0x2e, 0xf1, 0xd6,
+	0x66, 0x6a, 0xd1, 0x51, 0x36, 0x77, 0x2d, 0x6d, 0xc6, 0xf9, 0x2e, 0x71, 0x24, 0xce, 0xe4, 0xa6,
+	0xb2, 0x89, 0x73, 0xc7, 0xde, 0x64, 0x9c, 0xef, 0x12, 0x6f, 0x6d, 0xa6, 0x96, 0x01, 0x65, 0x73,
+	0xd7, 0xde, 0x63, 0x9c, 0xef, 0x12, 0x2b, 0x9b, 0xdf, 0xc0, 0xc9, 0x66, 0x9c, 0x6f, 0xa5, 0x61,
+	0x03, 0x46, 0xa7, 0xea, 0x78, 0xe6, 0x96, 0x61, 0x9c, 0xed, 0x90, 0x2a, 0xdb, 0x13, 0x38, 0xec,
+	0xdc, 0x13, 0x7b, 0x96, 0x9c, 0xa9, 0xca, 0xea, 0x8e, 0x01, 0x6f, 0x9c, 0xed, 0x90, 0x2a, 0xab,
+	0x7d, 0xa8, 0xc4, 0x9b, 0x21, 0x0a, 0xf0, 0x92, 0xd9, 0x95, 0x8d, 0x93, 0x4c, 0x59, 0x60, 0xea,
+	0x6e, 0x5f, 0xfe, 0x39, 0x7f, 0xfa, 0xf7, 0x00, 0x26, 0x6a, 0x85, 0x48, 0x46, 0x0f, 0x00, 0x00,
 }
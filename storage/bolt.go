@@ -22,6 +22,16 @@ func (b *Bolt) Close() error {
 	return b.db.Close()
 }
 
+// DeleteChallenge removes a challenge from the index.
+func (b *Bolt) DeleteChallenge(a *domain.Authorization) error {
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("challenges"))
+		return b.Delete([]byte(challengeKey(a.ChallengeType, a.Name, a.ChallengeToken())))
+	})
+
+	return errors.Wrapf(err, "error deleting challenge for domain %s", a.Name)
+}
+
 // GetAccount searches for an account with a given ID and Token.
 func (b *Bolt) GetAccount(id, token uuid.UUID) (*account.Account, error) {
 	var account account.Account
@@ -43,6 +53,28 @@ func (b *Bolt) GetAccount(id, token uuid.UUID) (*account.Account, error) {
 	return &account, nil
 }
 
+// GetChallenge searches for a prepared challenge
+// with a given type, name and token.
+func (b *Bolt) GetChallenge(challengeType, name, token string) (*domain.Authorization, error) {
+	var a domain.Authorization
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("challenges"))
+		v := b.Get([]byte(challengeKey(challengeType, name, token)))
+		if v == nil {
+			return errors.New("challenge not found")
+		}
+
+		return json.Unmarshal(v, &a)
+	})
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "error retrieving challenge for domain %s", name)
+	}
+
+	return &a, nil
+}
+
 // GetDomain searches for a domain with a given name.
 func (b *Bolt) GetDomain(accountID uuid.UUID, name string) (*domain.Domain, error) {
 	var domain domain.Domain
@@ -52,6 +84,9 @@ func (b *Bolt) GetDomain(accountID uuid.UUID, name string) (*domain.Domain, erro
 
 		key := fmt.Sprintf("%s@@%s", accountID, name)
 		v := b.Get([]byte(key))
+		if v == nil {
+			return ErrDomainNotFound
+		}
 
 		return json.Unmarshal(v, &domain)
 	})
@@ -63,6 +98,33 @@ func (b *Bolt) GetDomain(accountID uuid.UUID, name string) (*domain.Domain, erro
 	return &domain, nil
 }
 
+// ListDomains returns all the domains in a given state.
+func (b *Bolt) ListDomains(state domain.State) ([]*domain.Domain, error) {
+	var domains []*domain.Domain
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("domains"))
+
+		return b.ForEach(func(k, v []byte) error {
+			var d domain.Domain
+			if err := json.Unmarshal(v, &d); err != nil {
+				return errors.Wrapf(err, "error decoding domain %s", k)
+			}
+
+			if d.State == state {
+				domains = append(domains, &d)
+			}
+			return nil
+		})
+	})
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "error listing domains in state %s", state)
+	}
+
+	return domains, nil
+}
+
 // SaveAccount saves an account in a bucket.
 func (b *Bolt) SaveAccount(a *account.Account) error {
 	j, err := json.Marshal(a)
@@ -78,6 +140,21 @@ func (b *Bolt) SaveAccount(a *account.Account) error {
 	return errors.Wrapf(err, "error saving account %s", a.ID)
 }
 
+// SaveChallenge adds a prepared challenge to the index.
+func (b *Bolt) SaveChallenge(a *domain.Authorization) error {
+	j, err := json.Marshal(a)
+	if err != nil {
+		return errors.Wrapf(err, "error saving challenge for domain %s", a.Name)
+	}
+
+	err = b.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("challenges"))
+		return b.Put([]byte(challengeKey(a.ChallengeType, a.Name, a.ChallengeToken())), j)
+	})
+
+	return errors.Wrapf(err, "error saving challenge for domain %s", a.Name)
+}
+
 // SaveDomain saves a domain in a bucket.
 func (b *Bolt) SaveDomain(d *domain.Domain) error {
 	j, err := json.Marshal(d)
@@ -125,6 +202,11 @@ func initializeBuckets(db *bolt.DB) error {
 		return err
 	}
 
+	_, err = tx.CreateBucketIfNotExists([]byte("challenges"))
+	if err != nil {
+		return err
+	}
+
 	if err := tx.Commit(); err != nil {
 		return err
 	}
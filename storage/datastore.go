@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"encoding/json"
 
 	"cloud.google.com/go/datastore"
 	"github.com/google/uuid"
@@ -16,20 +17,83 @@ type Datastore struct {
 	client *datastore.Client
 }
 
+// accountEntity stores an account encoded in JSON,
+// like the Bolt backend does, so nested fields like
+// its UUIDs don't depend on Datastore property types.
+// Accounts saved before with a property per field
+// are still loaded, see datastore_legacy.go.
+type accountEntity struct {
+	Data   []byte
+	legacy []datastore.Property
+}
+
+// domainEntity stores a domain encoded in JSON,
+// with the properties needed to query it.
+// Domains saved before with a property per field
+// are still loaded, see datastore_legacy.go.
+type domainEntity struct {
+	AccountID string
+	Name      string
+	State     int
+	Data      []byte
+	legacy    []datastore.Property
+}
+
+// challengeEntity stores a prepared challenge encoded in JSON.
+// Its key identifies the challenge in the index.
+type challengeEntity struct {
+	Data []byte `datastore:",noindex"`
+}
+
 // Close closes the connection with the Datastore server.
 func (d *Datastore) Close() error {
 	return d.client.Close()
 }
 
+// DeleteChallenge removes a challenge from the index.
+func (d *Datastore) DeleteChallenge(a *domain.Authorization) error {
+	key := datastore.NameKey("Challenge", challengeKey(a.ChallengeType, a.Name, a.ChallengeToken()), nil)
+	err := d.client.Delete(context.Background(), key)
+	return errors.Wrapf(err, "error deleting challenge for domain %s", a.Name)
+}
+
 // GetAccount searches for an account with a given ID and Token.
 func (d *Datastore) GetAccount(id, token uuid.UUID) (*account.Account, error) {
 	key := datastore.NameKey("Account", id.String(), nil)
-	var account account.Account
-	if err := d.client.Get(context.Background(), key, &account); err != nil {
+	var e accountEntity
+	if err := d.client.Get(context.Background(), key, &e); err != nil {
+		return nil, errors.Wrapf(err, "error retrieving account %s", id)
+	}
+
+	account, err := e.account()
+	if err != nil {
 		return nil, errors.Wrapf(err, "error retrieving account %s", id)
 	}
 
-	return &account, nil
+	// Accounts are only returned to the holders of their token,
+	// like the Bolt backend does.
+	if account.Token != token {
+		return nil, errors.Errorf("error retrieving account %s", id)
+	}
+
+	return account, nil
+}
+
+// GetChallenge searches for a prepared challenge
+// with a given type, name and token.
+func (d *Datastore) GetChallenge(challengeType, name, token string) (*domain.Authorization, error) {
+	key := datastore.NameKey("Challenge", challengeKey(challengeType, name, token), nil)
+	var e challengeEntity
+	if err := d.client.Get(context.Background(), key, &e); err != nil {
+		return nil, errors.Wrapf(err, "error retrieving challenge for domain %s", name)
+	}
+
+	var a domain.Authorization
+	if err := json.Unmarshal(e.Data, &a); err != nil {
+		return nil, errors.Wrapf(err, "error retrieving challenge for domain %s", name)
+	}
+
+	return &a, nil
 }
 
 // GetDomain searches for a domain with a given name.
@@ -39,7 +103,7 @@ func (d *Datastore) GetDomain(accountID uuid.UUID, name string) (*domain.Domain,
 		Filter("Name =", name).
 		Limit(1)
 
-	var res []*domain.Domain
+	var res []*domainEntity
 	_, err := d.client.GetAll(context.Background(), query, &res)
 
 	if err != nil {
@@ -47,24 +111,81 @@ func (d *Datastore) GetDomain(accountID uuid.UUID, name string) (*domain.Domain,
 	}
 
 	if len(res) == 0 {
+		return nil, errors.Wrapf(ErrDomainNotFound, "error retrieving domain %s", name)
+	}
+
+	dm, err := res[0].domain()
+	if err != nil {
 		return nil, errors.Wrapf(err, "error retrieving domain %s", name)
 	}
 
-	return res[0], nil
+	return dm, nil
+}
+
+// ListDomains returns all the domains in a given state.
+func (d *Datastore) ListDomains(state domain.State) ([]*domain.Domain, error) {
+	query := datastore.NewQuery("Domain").
+		Filter("State =", int(state))
+
+	var res []*domainEntity
+	if _, err := d.client.GetAll(context.Background(), query, &res); err != nil {
+		return nil, errors.Wrapf(err, "error listing domains in state %s", state)
+	}
+
+	domains := make([]*domain.Domain, 0, len(res))
+	for _, e := range res {
+		dm, err := e.domain()
+		if err != nil {
+			return nil, errors.Wrapf(err, "error listing domains in state %s", state)
+		}
+		domains = append(domains, dm)
+	}
+
+	return domains, nil
 }
 
 // SaveAccount saves an account in a bucket.
 func (d *Datastore) SaveAccount(a *account.Account) error {
+	j, err := json.Marshal(a)
+	if err != nil {
+		return errors.Wrapf(err, "error saving account %s", a.ID)
+	}
+
 	key := datastore.NameKey("Account", a.ID.String(), nil)
-	_, err := d.client.Put(context.Background(), key, a)
+	_, err = d.client.Put(context.Background(), key, &accountEntity{Data: j})
 
 	return errors.Wrapf(err, "error saving account %s", a.ID)
 }
 
+// SaveChallenge adds a prepared challenge to the index.
+func (d *Datastore) SaveChallenge(a *domain.Authorization) error {
+	j, err := json.Marshal(a)
+	if err != nil {
+		return errors.Wrapf(err, "error saving challenge for domain %s", a.Name)
+	}
+
+	key := datastore.NameKey("Challenge", challengeKey(a.ChallengeType, a.Name, a.ChallengeToken()), nil)
+	_, err = d.client.Put(context.Background(), key, &challengeEntity{Data: j})
+
+	return errors.Wrapf(err, "error saving challenge for domain %s", a.Name)
+}
+
 // SaveDomain saves a domain in a bucket.
 func (d *Datastore) SaveDomain(dm *domain.Domain) error {
+	j, err := json.Marshal(dm)
+	if err != nil {
+		return errors.Wrapf(err, "error saving domain %s", dm.ID)
+	}
+
+	e := &domainEntity{
+		AccountID: dm.Account.ID.String(),
+		Name:      dm.Name,
+		State:     int(dm.State),
+		Data:      j,
+	}
+
 	key := datastore.NameKey("Domain", dm.ID.String(), nil)
-	_, err := d.client.Put(context.Background(), key, dm)
+	_, err = d.client.Put(context.Background(), key, e)
 
 	return errors.Wrapf(err, "error saving domain %s", dm.ID)
 }
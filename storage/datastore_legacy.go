@@ -0,0 +1,238 @@
+package storage
+
+import (
+	"encoding/json"
+	"time"
+
+	"cloud.google.com/go/datastore"
+	"github.com/google/uuid"
+	"github.com/lost-mountain/isard/account"
+	"github.com/lost-mountain/isard/cryptopolis"
+	"github.com/lost-mountain/isard/domain"
+	"github.com/pkg/errors"
+)
+
+// Accounts and domains were saved in Datastore with a property
+// per field before they were encoded in JSON. Those entities don't
+// have a Data property, so they are decoded from their properties,
+// and saved encoded in JSON the next time they change.
+
+// Load implements datastore.PropertyLoadSaver.
+// It keeps the properties of legacy entities to decode them later.
+func (e *accountEntity) Load(ps []datastore.Property) error {
+	if data, ok := propertyBytes(ps, "Data"); ok {
+		e.Data = data
+		return nil
+	}
+
+	e.legacy = ps
+	return nil
+}
+
+// Save implements datastore.PropertyLoadSaver.
+func (e *accountEntity) Save() ([]datastore.Property, error) {
+	return []datastore.Property{
+		{Name: "Data", Value: e.Data, NoIndex: true},
+	}, nil
+}
+
+func (e *accountEntity) account() (*account.Account, error) {
+	if e.Data == nil {
+		return legacyAccount(e.legacy)
+	}
+
+	var a account.Account
+	if err := json.Unmarshal(e.Data, &a); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// Load implements datastore.PropertyLoadSaver.
+// It keeps the properties of legacy entities to decode them later.
+func (e *domainEntity) Load(ps []datastore.Property) error {
+	e.AccountID, _ = propertyString(ps, "AccountID")
+	e.Name, _ = propertyString(ps, "Name")
+	if state, ok := propertyInt(ps, "State"); ok {
+		e.State = int(state)
+	}
+
+	if data, ok := propertyBytes(ps, "Data"); ok {
+		e.Data = data
+		return nil
+	}
+
+	e.legacy = ps
+	return nil
+}
+
+// Save implements datastore.PropertyLoadSaver.
+// Only the properties used in queries are indexed.
+func (e *domainEntity) Save() ([]datastore.Property, error) {
+	return []datastore.Property{
+		{Name: "AccountID", Value: e.AccountID},
+		{Name: "Name", Value: e.Name},
+		{Name: "State", Value: int64(e.State)},
+		{Name: "Data", Value: e.Data, NoIndex: true},
+	}, nil
+}
+
+func (e *domainEntity) domain() (*domain.Domain, error) {
+	if e.Data == nil {
+		return legacyDomain(e.legacy)
+	}
+
+	var d domain.Domain
+	if err := json.Unmarshal(e.Data, &d); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// legacyAccount decodes an account saved with a property per field.
+func legacyAccount(ps []datastore.Property) (*account.Account, error) {
+	id, err := propertyUUID(ps, "ID")
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := propertyUUID(ps, "Token")
+	if err != nil {
+		return nil, err
+	}
+
+	a := &account.Account{
+		ID:     id,
+		Token:  token,
+		Owners: propertyStrings(ps, "Owners"),
+	}
+	a.Key, _ = propertyString(ps, "Key")
+	a.DirectoryURL, _ = propertyString(ps, "DirectoryURL")
+	a.CreatedAt, _ = propertyTime(ps, "CreatedAt")
+	a.UpdatedAt, _ = propertyTime(ps, "UpdatedAt")
+	return a, nil
+}
+
+// legacyDomain decodes a domain saved with a property per field.
+// Legacy domains didn't save their SAN names, so they are
+// extracted from the domain name like new domains do.
+func legacyDomain(ps []datastore.Property) (*domain.Domain, error) {
+	id, err := propertyUUID(ps, "ID")
+	if err != nil {
+		return nil, err
+	}
+
+	d := &domain.Domain{ID: id}
+	d.Name, _ = propertyString(ps, "Name")
+	d.AccountID, _ = propertyString(ps, "AccountID")
+	d.CreatedAt, _ = propertyTime(ps, "CreatedAt")
+	d.UpdatedAt, _ = propertyTime(ps, "UpdatedAt")
+	if state, ok := propertyInt(ps, "State"); ok {
+		d.State = domain.State(state)
+	}
+	if t, ok := propertyString(ps, "ChallengeType"); ok && t != "" {
+		d.ChallengeTypes = []string{t}
+	}
+
+	if e, ok := propertyEntity(ps, "Account"); ok {
+		a, err := legacyAccount(e.Properties)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error decoding the account of domain %s", d.Name)
+		}
+		d.Account = a
+	}
+
+	if e, ok := propertyEntity(ps, "Certificate"); ok {
+		c := &cryptopolis.Certificate{}
+		c.Cert, _ = propertyBytes(e.Properties, "Cert")
+		c.Key, _ = propertyBytes(e.Properties, "Key")
+		c.CA, _ = propertyBytes(e.Properties, "CA")
+		d.Certificate = c
+	}
+
+	names, err := domain.ExtractNames(d.Name)
+	if err != nil {
+		names = &domain.Names{CN: d.Name, SAN: []string{d.Name}}
+	}
+	d.SAN = names.SAN
+	d.InitialSAN = []string{names.CN}
+
+	return d, nil
+}
+
+func property(ps []datastore.Property, name string) (interface{}, bool) {
+	for _, p := range ps {
+		if p.Name == name {
+			return p.Value, true
+		}
+	}
+	return nil, false
+}
+
+func propertyString(ps []datastore.Property, name string) (string, bool) {
+	v, _ := property(ps, name)
+	s, ok := v.(string)
+	return s, ok
+}
+
+func propertyBytes(ps []datastore.Property, name string) ([]byte, bool) {
+	v, _ := property(ps, name)
+	b, ok := v.([]byte)
+	return b, ok
+}
+
+func propertyInt(ps []datastore.Property, name string) (int64, bool) {
+	v, _ := property(ps, name)
+	i, ok := v.(int64)
+	return i, ok
+}
+
+func propertyTime(ps []datastore.Property, name string) (time.Time, bool) {
+	v, _ := property(ps, name)
+	t, ok := v.(time.Time)
+	return t, ok
+}
+
+func propertyEntity(ps []datastore.Property, name string) (*datastore.Entity, bool) {
+	v, _ := property(ps, name)
+	e, ok := v.(*datastore.Entity)
+	return e, ok && e != nil
+}
+
+// propertyStrings returns the values of a list property.
+// Lists are loaded as a single property with a slice value,
+// or as a property per value with the same name.
+func propertyStrings(ps []datastore.Property, name string) []string {
+	var r []string
+	for _, p := range ps {
+		if p.Name != name {
+			continue
+		}
+
+		switch v := p.Value.(type) {
+		case string:
+			r = append(r, v)
+		case []interface{}:
+			for _, i := range v {
+				if s, ok := i.(string); ok {
+					r = append(r, s)
+				}
+			}
+		}
+	}
+	return r
+}
+
+// propertyUUID decodes a UUID saved as its string
+// representation or as its 16 bytes.
+func propertyUUID(ps []datastore.Property, name string) (uuid.UUID, error) {
+	v, _ := property(ps, name)
+	switch id := v.(type) {
+	case string:
+		return uuid.Parse(id)
+	case []byte:
+		return uuid.FromBytes(id)
+	default:
+		return uuid.Nil, errors.Errorf("invalid legacy property %s: %v", name, v)
+	}
+}
@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/datastore"
+	"github.com/google/uuid"
+	"github.com/lost-mountain/isard/account"
+	"github.com/lost-mountain/isard/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func legacyAccountProperties(id, token uuid.UUID) []datastore.Property {
+	return []datastore.Property{
+		{Name: "ID", Value: id[:]},
+		{Name: "Token", Value: token.String()},
+		{Name: "Key", Value: "key"},
+		{Name: "Owners", Value: []interface{}{"david.calavera@gmail.com"}},
+		{Name: "CreatedAt", Value: time.Unix(1500000000, 0)},
+	}
+}
+
+func TestLoadLegacyAccountEntity(t *testing.T) {
+	id, token := uuid.New(), uuid.New()
+
+	var e accountEntity
+	require.NoError(t, e.Load(legacyAccountProperties(id, token)))
+
+	a, err := e.account()
+	require.NoError(t, err)
+	require.Equal(t, id, a.ID)
+	require.Equal(t, token, a.Token)
+	require.Equal(t, "key", a.Key)
+	require.Equal(t, []string{"david.calavera@gmail.com"}, a.Owners)
+	require.Equal(t, time.Unix(1500000000, 0), a.CreatedAt)
+}
+
+func TestLoadLegacyDomainEntity(t *testing.T) {
+	id, accountID := uuid.New(), uuid.New()
+
+	var e domainEntity
+	err := e.Load([]datastore.Property{
+		{Name: "ID", Value: id.String()},
+		{Name: "Name", Value: "cabal.io"},
+		{Name: "ChallengeType", Value: "dns-01"},
+		{Name: "State", Value: int64(domain.Issued)},
+		{Name: "AccountID", Value: accountID.String()},
+		{Name: "Account", Value: &datastore.Entity{Properties: legacyAccountProperties(accountID, uuid.New())}},
+		{Name: "Certificate", Value: &datastore.Entity{Properties: []datastore.Property{
+			{Name: "Cert", Value: []byte("certificate")},
+		}}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "cabal.io", e.Name)
+	require.Equal(t, int(domain.Issued), e.State)
+
+	d, err := e.domain()
+	require.NoError(t, err)
+	require.Equal(t, id, d.ID)
+	require.Equal(t, domain.Issued, d.State)
+	require.Equal(t, []string{"dns-01"}, d.ChallengeTypes)
+	require.Equal(t, accountID, d.Account.ID)
+	require.Equal(t, []byte("certificate"), d.Certificate.Cert)
+	require.Equal(t, []string{"cabal.io", "www.cabal.io"}, d.SANNames())
+	require.Equal(t, []string{"cabal.io"}, d.InitialSAN)
+}
+
+func TestLoadDomainEntity(t *testing.T) {
+	a, err := account.NewAccount("david.calavera@gmail.com")
+	require.NoError(t, err)
+	d, err := domain.NewDomain(a, "test.cabal.io")
+	require.NoError(t, err)
+
+	data, err := json.Marshal(d)
+	require.NoError(t, err)
+
+	saved := &domainEntity{AccountID: a.ID.String(), Name: d.Name, Data: data}
+	ps, err := saved.Save()
+	require.NoError(t, err)
+
+	var e domainEntity
+	require.NoError(t, e.Load(ps))
+	require.Nil(t, e.legacy)
+
+	dm, err := e.domain()
+	require.NoError(t, err)
+	require.Equal(t, d.ID, dm.ID)
+	require.Equal(t, d.SANNames(), dm.SANNames())
+}
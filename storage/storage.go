@@ -1,17 +1,35 @@
 package storage
 
 import (
+	"fmt"
+
 	"github.com/google/uuid"
 	"github.com/lost-mountain/isard/account"
 	"github.com/lost-mountain/isard/domain"
+	"github.com/pkg/errors"
 )
 
+// ErrDomainNotFound is an error returned when
+// a bucket doesn't have a domain with a given name.
+var ErrDomainNotFound = errors.New("domain not found")
+
 // Bucket defines an interface to store information
 // in a database.
+// Prepared challenges are indexed by their type, name and token,
+// so the challenge responders can find them without listing domains.
 type Bucket interface {
 	Close() error
+	DeleteChallenge(a *domain.Authorization) error
 	GetAccount(id, token uuid.UUID) (*account.Account, error)
+	GetChallenge(challengeType, name, token string) (*domain.Authorization, error)
 	GetDomain(accountID uuid.UUID, name string) (*domain.Domain, error)
+	ListDomains(state domain.State) ([]*domain.Domain, error)
 	SaveAccount(account *account.Account) error
+	SaveChallenge(a *domain.Authorization) error
 	SaveDomain(domain *domain.Domain) error
 }
+
+// challengeKey returns the key of a challenge in the index.
+func challengeKey(challengeType, name, token string) string {
+	return fmt.Sprintf("%s@@%s@@%s", challengeType, name, token)
+}
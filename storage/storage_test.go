@@ -8,6 +8,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/lost-mountain/isard/account"
 	"github.com/lost-mountain/isard/domain"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
@@ -51,6 +52,7 @@ func (s *testSuite) TestGetDomain() {
 
 	_, err = s.bucket.GetDomain(a.ID, "foobar.com")
 	require.Error(s.T(), err, "unable to get domain with missing name")
+	require.Equal(s.T(), ErrDomainNotFound, errors.Cause(err))
 }
 
 func (s *testSuite) TestSaveAccount() {
@@ -75,6 +77,76 @@ func (s *testSuite) TestSaveDomain() {
 	require.NoError(s.T(), err)
 }
 
+func (s *testSuite) TestDomainSANNames() {
+	a, err := account.NewAccount("david.calavera@gmail.com")
+	require.NoError(s.T(), err)
+
+	d, err := domain.NewDomain(a, "cabal.io")
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), d.AddSANName("beta.cabal.io"))
+
+	err = s.bucket.SaveDomain(d)
+	require.NoError(s.T(), err)
+
+	dom, err := s.bucket.GetDomain(a.ID, "cabal.io")
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), []string{"cabal.io", "www.cabal.io", "beta.cabal.io"}, dom.SANNames())
+
+	// The initial name is still protected after loading the domain.
+	dom.RemoveSANName("cabal.io")
+	dom.RemoveSANName("beta.cabal.io")
+	require.Equal(s.T(), []string{"cabal.io", "www.cabal.io"}, dom.SANNames())
+}
+
+func (s *testSuite) TestListDomains() {
+	a, err := account.NewAccount("david.calavera@gmail.com")
+	require.NoError(s.T(), err)
+
+	d, err := domain.NewDomain(a, "issued.cabal.io")
+	require.NoError(s.T(), err)
+	d.State = domain.Issued
+	require.NoError(s.T(), s.bucket.SaveDomain(d))
+
+	p, err := domain.NewDomain(a, "pending.cabal.io")
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), s.bucket.SaveDomain(p))
+
+	domains, err := s.bucket.ListDomains(domain.Issued)
+	require.NoError(s.T(), err)
+
+	var names []string
+	for _, dm := range domains {
+		require.Equal(s.T(), domain.Issued, dm.State)
+		names = append(names, dm.Name)
+	}
+	require.Contains(s.T(), names, "issued.cabal.io")
+	require.NotContains(s.T(), names, "pending.cabal.io")
+}
+
+func (s *testSuite) TestChallenges() {
+	a := &domain.Authorization{
+		Name:                    "test.cabal.io",
+		Status:                  "pending",
+		ChallengeType:           "http-01",
+		HTTP01ChallengePath:     "/.well-known/acme-challenge/token",
+		HTTP01ChallengeResponse: "token.thumbprint",
+	}
+	require.NoError(s.T(), s.bucket.SaveChallenge(a))
+
+	c, err := s.bucket.GetChallenge("http-01", "test.cabal.io", "token")
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), a, c)
+
+	_, err = s.bucket.GetChallenge("http-01", "test.cabal.io", "other")
+	require.Error(s.T(), err)
+	_, err = s.bucket.GetChallenge("tls-alpn-01", "test.cabal.io", "")
+	require.Error(s.T(), err)
+
+	require.NoError(s.T(), s.bucket.DeleteChallenge(a))
+	_, err = s.bucket.GetChallenge("http-01", "test.cabal.io", "token")
+	require.Error(s.T(), err)
+}
+
 func TestBoltBucket(t *testing.T) {
 	f, err := ioutil.TempFile("", "isard-")
 	require.NoError(t, err)
@@ -88,3 +160,18 @@ func TestBoltBucket(t *testing.T) {
 
 	suite.Run(t, &testSuite{bucket: b})
 }
+
+func TestDatastoreBucket(t *testing.T) {
+	projectID := os.Getenv("ISARD_TEST_DATASTORE_PROJECT")
+	if projectID == "" {
+		t.Skip(`Datastore test suite skipped.
+Set ISARD_TEST_DATASTORE_PROJECT with the Datastore project ID to enable them.
+Set DATASTORE_EMULATOR_HOST to run them against the Datastore emulator`)
+	}
+
+	d, err := NewDatastore(projectID)
+	require.NoError(t, err)
+	defer d.Close()
+
+	suite.Run(t, &testSuite{bucket: d})
+}